@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupTestRestoreBackupName       string
+	backupTestRestoreScratchNamespace string
+	backupTestRestoreKeep             bool
+)
+
+func getVeleroCommand() string {
+	return fmt.Sprintf("KUBECONFIG=%s %s --namespace backup", _config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG), _config.GetFullLocalAssetFilename(utils.VELERO_BINARY))
+}
+
+func getLatestBackupName() (string, error) {
+	output, error := utils.RunCommandWithOutput(fmt.Sprintf("%s backup get --output name", getVeleroCommand()))
+	if error != nil {
+		return "", fmt.Errorf("could not list backups (%s)", error.Error())
+	}
+
+	names := strings.Fields(output)
+	if len(names) == 0 {
+		return "", fmt.Errorf("no backups found")
+	}
+
+	// velero lists backups oldest first, the last name is the most recent one
+	return names[len(names)-1], nil
+}
+
+func waitForRestore(restoreName string) (string, error) {
+	for i := 0; i < 60; i++ {
+		output, error := utils.RunCommandWithOutput(fmt.Sprintf("%s restore get %s --output jsonpath={.status.phase}", getVeleroCommand(), restoreName))
+		if error != nil {
+			return "", fmt.Errorf("could not get restore '%s' status (%s)", restoreName, error.Error())
+		}
+
+		phase := strings.TrimSpace(output)
+
+		if phase == "Completed" || phase == "Failed" || phase == "PartiallyFailed" {
+			return phase, nil
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+
+	return "", fmt.Errorf("restore '%s' did not finish in time", restoreName)
+}
+
+func backupTestRestore() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	backupName := backupTestRestoreBackupName
+
+	if len(backupName) == 0 {
+		var error error
+
+		backupName, error = getLatestBackupName()
+		if error != nil {
+			return error
+		}
+	}
+
+	restoreName := fmt.Sprintf("%s-test-restore", backupName)
+
+	log.WithFields(log.Fields{"backup": backupName, "restore": restoreName, "namespace": backupTestRestoreScratchNamespace}).Info("Restoring backup into scratch namespace")
+
+	if error := utils.RunCommand(fmt.Sprintf("%s restore create %s --from-backup %s --namespace-mappings *:%s", getVeleroCommand(), restoreName, backupName, backupTestRestoreScratchNamespace)); error != nil {
+		return fmt.Errorf("could not create restore '%s' (%s)", restoreName, error.Error())
+	}
+
+	phase, error := waitForRestore(restoreName)
+	if error != nil {
+		return error
+	}
+
+	log.WithFields(log.Fields{"backup": backupName, "restore": restoreName, "phase": phase}).Info("Test restore finished")
+
+	if !backupTestRestoreKeep {
+		log.WithFields(log.Fields{"restore": restoreName, "namespace": backupTestRestoreScratchNamespace}).Info("Cleaning up test restore")
+
+		if error := utils.RunCommand(fmt.Sprintf("%s restore delete %s --confirm", getVeleroCommand(), restoreName)); error != nil {
+			log.WithFields(log.Fields{"error": error}).Warn("Failed to delete test restore")
+		}
+
+		kubectlCommand := fmt.Sprintf("%s --kubeconfig %s", _config.GetFullLocalAssetFilename(utils.KUBECTL_BINARY), _config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG))
+
+		if error := utils.RunCommand(fmt.Sprintf("%s delete namespace %s", kubectlCommand, backupTestRestoreScratchNamespace)); error != nil {
+			log.WithFields(log.Fields{"error": error}).Warn("Failed to delete scratch namespace")
+		}
+	}
+
+	if phase != "Completed" {
+		return fmt.Errorf("test restore of backup '%s' ended with phase '%s'", backupName, phase)
+	}
+
+	return nil
+}
+
+var backupTestRestoreCmd = &cobra.Command{
+	Use:   "backup-test-restore",
+	Short: "Restores the latest (or a given) Velero backup into a scratch namespace to verify it",
+	Long:  "Restores the latest (or a given) Velero backup into a scratch namespace to verify the backup is actually restorable, then cleans the scratch namespace up unless told to keep it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := backupTestRestore(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Test restore failed")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	backupTestRestoreCmd.Flags().StringVarP(&backupTestRestoreBackupName, "backup", "b", "", "Name of the backup to test (default: the most recent backup)")
+	backupTestRestoreCmd.Flags().StringVarP(&backupTestRestoreScratchNamespace, "namespace", "n", "backup-test-restore", "Scratch namespace the backup gets restored into")
+	backupTestRestoreCmd.Flags().BoolVarP(&backupTestRestoreKeep, "keep", "k", false, "Keep the restore and the scratch namespace instead of cleaning them up")
+	RootCmd.AddCommand(backupTestRestoreCmd)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/darxkies/k8s-tew/pki"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var certificatesStatusThresholdDays uint
+var certificatesStatusJSON bool
+
+const certificatesStatusSchemaVersion = 1
+
+// CertificateStatus is the machine readable status of a single generated certificate, as reported by
+// certificates-status
+type CertificateStatus struct {
+	Filename      string    `json:"filename"`
+	CommonName    string    `json:"common-name"`
+	NotBefore     time.Time `json:"not-before"`
+	NotAfter      time.Time `json:"not-after"`
+	DaysRemaining int       `json:"days-remaining"`
+	DNSNames      []string  `json:"dns-names,omitempty"`
+	IPAddresses   []string  `json:"ip-addresses,omitempty"`
+	Expired       bool      `json:"expired"`
+	ExpiringSoon  bool      `json:"expiring-soon"`
+}
+
+// CertificatesStatusReport is the machine readable result of certificates-status
+type CertificatesStatusReport struct {
+	SchemaVersion int                 `json:"schema-version"`
+	ThresholdDays uint                `json:"threshold-days"`
+	Certificates  []CertificateStatus `json:"certificates"`
+}
+
+// collectCertificatesStatus parses every *.pem certificate (skipping the matching *-key.pem private keys) found
+// in the certificates asset directory and reports how close each one is to thresholdDays of remaining validity
+func collectCertificatesStatus(thresholdDays uint) ([]CertificateStatus, error) {
+	certificatesDirectory := _config.GetFullLocalAssetDirectory(utils.CERTIFICATES_DIRECTORY)
+
+	filenames, error := filepath.Glob(path.Join(certificatesDirectory, "*.pem"))
+	if error != nil {
+		return nil, error
+	}
+
+	result := []CertificateStatus{}
+
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "-key.pem") {
+			continue
+		}
+
+		certificate, error := pki.LoadCertificate(filename)
+		if error != nil {
+			return nil, fmt.Errorf("could not parse certificate '%s' (%s)", filename, error.Error())
+		}
+
+		daysRemaining := int(time.Until(certificate.NotAfter).Hours() / 24)
+
+		dnsNames := certificate.DNSNames
+
+		ipAddresses := []string{}
+
+		for _, ipAddress := range certificate.IPAddresses {
+			ipAddresses = append(ipAddresses, ipAddress.String())
+		}
+
+		result = append(result, CertificateStatus{
+			Filename:      path.Base(filename),
+			CommonName:    certificate.Subject.CommonName,
+			NotBefore:     certificate.NotBefore,
+			NotAfter:      certificate.NotAfter,
+			DaysRemaining: daysRemaining,
+			DNSNames:      dnsNames,
+			IPAddresses:   ipAddresses,
+			Expired:       daysRemaining < 0,
+			ExpiringSoon:  daysRemaining <= int(thresholdDays),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Filename < result[j].Filename
+	})
+
+	return result, nil
+}
+
+func certificatesStatus() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	utils.SetProgressSteps(1)
+
+	certificates, error := collectCertificatesStatus(certificatesStatusThresholdDays)
+	if error != nil {
+		return error
+	}
+
+	expiringSoon := 0
+
+	for _, certificate := range certificates {
+		if certificate.ExpiringSoon {
+			expiringSoon++
+		}
+	}
+
+	if certificatesStatusJSON {
+		report := CertificatesStatusReport{SchemaVersion: certificatesStatusSchemaVersion, ThresholdDays: certificatesStatusThresholdDays, Certificates: certificates}
+
+		content, error := json.MarshalIndent(report, "", "  ")
+		if error != nil {
+			return error
+		}
+
+		fmt.Println(string(content))
+	} else {
+		for _, certificate := range certificates {
+			fields := log.Fields{"filename": certificate.Filename, "common-name": certificate.CommonName, "not-before": certificate.NotBefore, "not-after": certificate.NotAfter, "days-remaining": certificate.DaysRemaining, "dns-names": certificate.DNSNames, "ip-addresses": certificate.IPAddresses}
+
+			if certificate.Expired {
+				log.WithFields(fields).Error("Certificate expired")
+			} else if certificate.ExpiringSoon {
+				log.WithFields(fields).Warn("Certificate expiring soon")
+			} else {
+				log.WithFields(fields).Info("Certificate")
+			}
+		}
+	}
+
+	if expiringSoon > 0 {
+		return fmt.Errorf("%d certificate(s) expire within %d day(s)", expiringSoon, certificatesStatusThresholdDays)
+	}
+
+	return nil
+}
+
+var certificatesStatusCmd = &cobra.Command{
+	Use:   "certificates-status",
+	Short: "Report certificate expiry dates",
+	Long:  "Parses every generated certificate and reports its validity window, days remaining and SANs. Exits with a nonzero status if any certificate expires within --threshold-days, so it can be used from a monitoring cron job",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := certificatesStatus(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to report certificate status")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	certificatesStatusCmd.Flags().UintVar(&certificatesStatusThresholdDays, "threshold-days", 30, "Exit with a nonzero status if any certificate expires within this many days")
+	certificatesStatusCmd.Flags().BoolVar(&certificatesStatusJSON, "json", false, "Output the report as JSON instead of logging each certificate")
+	RootCmd.AddCommand(certificatesStatusCmd)
+}
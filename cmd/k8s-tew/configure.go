@@ -49,6 +49,7 @@ var configureCmd = &cobra.Command{
 
 type stringSetter func(value string)
 type uint16Setter func(value uint16)
+type boolSetter func(value bool)
 
 var setterHandlers map[string]stringSetter
 
@@ -72,6 +73,16 @@ func addUint16Option(name string, value uint16, description string, handler uint
 	}
 }
 
+func addBoolOption(name string, value bool, description string, handler boolSetter) {
+	configureCmd.Flags().Bool(name, value, description)
+
+	setterHandlers[name] = func(value string) {
+		_value, _ := strconv.ParseBool(value)
+
+		handler(_value)
+	}
+}
+
 func init() {
 	setterHandlers = map[string]stringSetter{}
 
@@ -79,6 +90,14 @@ func init() {
 		_config.Config.RSASize = value
 	})
 
+	addStringOption("certificate-algorithm", utils.CERTIFICATE_ALGORITHM_RSA, fmt.Sprintf("Certificate Algorithm ('%s' or '%s')", utils.CERTIFICATE_ALGORITHM_RSA, utils.CERTIFICATE_ALGORITHM_ECDSA), func(value string) {
+		_config.Config.CertificateAlgorithm = value
+	})
+
+	addStringOption("certificate-ecdsa-curve", utils.ECDSA_CURVE_P256, fmt.Sprintf("ECDSA Curve used when certificate-algorithm is '%s' ('%s', '%s', '%s' or '%s')", utils.CERTIFICATE_ALGORITHM_ECDSA, utils.ECDSA_CURVE_P224, utils.ECDSA_CURVE_P256, utils.ECDSA_CURVE_P384, utils.ECDSA_CURVE_P521), func(value string) {
+		_config.Config.CertificateECDSACurve = value
+	})
+
 	addUint16Option("ca-certificate-validity-period", utils.CA_VALIDITY_PERIOD, "CA Certificate Validity Period", func(value uint16) {
 		_config.Config.CAValidityPeriod = uint(value)
 	})
@@ -107,20 +126,40 @@ func init() {
 		_config.Config.KubernetesDashboardPort = value
 	})
 
-	addStringOption("controller-virtual-ip", "", "Controller Virtual/Floating IP for the cluster", func(value string) {
-		_config.Config.ControllerVirtualIP = value
+	addBoolOption("firewall", false, "Enable firewall rules for the required ports instead of disabling the firewall", func(value bool) {
+		_config.Config.Firewall = value
+	})
+
+	addBoolOption("metrics-server-kubelet-insecure-tls", false, "Make metrics-server skip kubelet certificate verification", func(value bool) {
+		_config.Config.MetricsServerKubeletInsecureTLS = value
+	})
+
+	addStringOption("metrics-server-kubelet-preferred-address-types", "", "Comma separated list of node address types used by metrics-server to connect to kubelet, in order of preference", func(value string) {
+		_config.Config.MetricsServerKubeletPreferredAddressTypes = value
+	})
+
+	addStringOption("etcd-discovery-domain", "", "Domain with SRV records used by etcd to discover its peers instead of a static initial cluster list", func(value string) {
+		_config.Config.ETCDDiscoveryDomain = value
 	})
 
-	addStringOption("controller-virtual-ip-interface", "", "Controller Virtual/Floating IP interface for the cluster", func(value string) {
-		_config.Config.ControllerVirtualIPInterface = value
+	addUint16Option("audit-log-max-age", uint16(utils.AUDIT_LOG_MAX_AGE), "Days to retain rotated kube-apiserver audit log files", func(value uint16) {
+		_config.Config.Audit.MaxAge = uint(value)
 	})
 
-	addStringOption("worker-virtual-ip", "", "Worker Virtual/Floating IP for the cluster", func(value string) {
-		_config.Config.WorkerVirtualIP = value
+	addUint16Option("audit-log-max-backup", uint16(utils.AUDIT_LOG_MAX_BACKUP), "Number of rotated kube-apiserver audit log files to retain", func(value uint16) {
+		_config.Config.Audit.MaxBackup = uint(value)
 	})
 
-	addStringOption("worker-virtual-ip-interface", "", "Worker Virtual/Floating IP interface for the cluster", func(value string) {
-		_config.Config.WorkerVirtualIPInterface = value
+	addUint16Option("audit-log-max-size", uint16(utils.AUDIT_LOG_MAX_SIZE), "Megabytes a kube-apiserver audit log file is allowed to reach before being rotated", func(value uint16) {
+		_config.Config.Audit.MaxSize = uint(value)
+	})
+
+	addBoolOption("serialize-image-pulls", true, "Pull images one at a time on the kubelet instead of in parallel", func(value bool) {
+		_config.Config.SerializeImagePulls = value
+	})
+
+	addStringOption("image-pull-progress-deadline", utils.IMAGE_PULL_PROGRESS_DEADLINE, "Kubelet image pull progress deadline, raise it for nodes pulling large images", func(value string) {
+		_config.Config.ImagePullProgressDeadline = value
 	})
 
 	addStringOption("cluster-domain", utils.CLUSTER_DOMAIN, "Cluster domain", func(value string) {
@@ -139,10 +178,54 @@ func init() {
 		_config.Config.ClusterCIDR = value
 	})
 
+	addUint16Option("node-cidr-mask-size", uint16(utils.NODE_CIDR_MASK_SIZE), "Size of the per-node subnet mask carved out of cluster-cidr, must leave room for both max-pods per node and one subnet per worker node", func(value uint16) {
+		_config.Config.NodeCIDRMaskSize = uint(value)
+	})
+
+	addUint16Option("max-pods", uint16(utils.MAX_PODS), "Maximum number of pods per node, must fit within the node-cidr-mask-size subnet", func(value uint16) {
+		_config.Config.MaxPods = uint(value)
+	})
+
 	addStringOption("calico-typha-ip", utils.CALICO_TYPHA_IP, "Calico Typha IP", func(value string) {
 		_config.Config.CalicoTyphaIP = value
 	})
 
+	addStringOption("cni", utils.CNI_CALICO, fmt.Sprintf("CNI to deploy ('%s', '%s' or '%s')", utils.CNI_CALICO, utils.CNI_CILIUM, utils.CNI_WEAVE), func(value string) {
+		_config.Config.CNI = value
+	})
+
+	addStringOption("weave-encryption-password", "", fmt.Sprintf("Weave Net encryption password used when cni is '%s'. If left empty, a random password is generated and printed once when the weave setup is generated", utils.CNI_WEAVE), func(value string) {
+		_config.Config.WeaveEncryptionPassword = value
+	})
+
+	addStringOption("storage-backend", utils.STORAGE_BACKEND_CEPH, fmt.Sprintf("Storage backend to deploy ('%s', '%s' or '%s')", utils.STORAGE_BACKEND_CEPH, utils.STORAGE_BACKEND_NFS, utils.STORAGE_BACKEND_LOCAL_PATH), func(value string) {
+		_config.Config.StorageBackend = value
+	})
+
+	addStringOption("ingress-controller", utils.INGRESS_CONTROLLER_NGINX, fmt.Sprintf("Ingress controller to deploy ('%s' or '%s')", utils.INGRESS_CONTROLLER_NGINX, utils.INGRESS_CONTROLLER_TRAEFIK), func(value string) {
+		_config.Config.IngressController = value
+	})
+
+	addStringOption("logging-backend", utils.LOGGING_BACKEND_EFK, fmt.Sprintf("Logging backend to deploy ('%s' or '%s')", utils.LOGGING_BACKEND_EFK, utils.LOGGING_BACKEND_LOKI), func(value string) {
+		_config.Config.LoggingBackend = value
+	})
+
+	addStringOption("grafana-admin-username", utils.GRAFANA_ADMIN_USERNAME, "Grafana admin username", func(value string) {
+		_config.Config.GrafanaAdminUsername = value
+	})
+
+	addStringOption("grafana-admin-password", "", "Grafana admin password. If left empty, a random password is generated and printed once when the monitoring setup is generated", func(value string) {
+		_config.Config.GrafanaAdminPassword = value
+	})
+
+	addStringOption("nfs-server", "", fmt.Sprintf("NFS server used by the nfs-subdir-provisioner when storage-backend is '%s'", utils.STORAGE_BACKEND_NFS), func(value string) {
+		_config.Config.NFSServer = value
+	})
+
+	addStringOption("nfs-path", "", fmt.Sprintf("Exported path on nfs-server used by the nfs-subdir-provisioner when storage-backend is '%s'", utils.STORAGE_BACKEND_NFS), func(value string) {
+		_config.Config.NFSPath = value
+	})
+
 	addStringOption("resolv-conf", utils.RESOLV_CONF, "Custom resolv.conf", func(value string) {
 		_config.Config.ResolvConf = value
 	})
@@ -151,7 +234,23 @@ func init() {
 		_config.Config.PublicNetwork = value
 	})
 
-	addStringOption("cluster-name", utils.CLUSTER_NAME, "Cluster Name used for Kubernetes Dashboard", func(value string) {
+	addStringOption("registry-mirror", "", "Registry used to pull images instead of docker.io, quay.io, gcr.io and k8s.gcr.io, e.g. 'registry.internal'", func(value string) {
+		_config.Config.RegistryMirror = value
+	})
+
+	addStringOption("http-proxy", "", "HTTP proxy used for binary downloads and, via the containerd process environment, image pulls", func(value string) {
+		_config.Config.HTTPProxy = value
+	})
+
+	addStringOption("https-proxy", "", "HTTPS proxy used for binary downloads and, via the containerd process environment, image pulls", func(value string) {
+		_config.Config.HTTPSProxy = value
+	})
+
+	addStringOption("no-proxy", "", "Comma separated list of hosts/CIDRs to exclude from http-proxy/https-proxy, in addition to the cluster CIDR, the cluster IP range and every node IP which are added automatically", func(value string) {
+		_config.Config.NoProxy = value
+	})
+
+	addStringOption("cluster-name", utils.CLUSTER_NAME, "Cluster name, used in kubeconfig cluster/context names and for the Kubernetes Dashboard so multiple clusters don't collide", func(value string) {
 		_config.Config.ClusterName = value
 	})
 
@@ -195,8 +294,8 @@ func init() {
 		_config.Config.Versions.Gobetween = value
 	})
 
-	addStringOption("version-ark", utils.VERSION_ARK, "Ark version", func(value string) {
-		_config.Config.Versions.Ark = value
+	addStringOption("version-velero", utils.VERSION_VELERO, "Velero version", func(value string) {
+		_config.Config.Versions.Velero = value
 	})
 
 	addStringOption("version-minio-server", utils.VERSION_MINIO_SERVER, "Minio server version", func(value string) {
@@ -239,6 +338,14 @@ func init() {
 		_config.Config.Versions.FluentBit = value
 	})
 
+	addStringOption("version-loki", utils.VERSION_LOKI, "Loki version", func(value string) {
+		_config.Config.Versions.Loki = value
+	})
+
+	addStringOption("version-promtail", utils.VERSION_PROMTAIL, "Promtail version", func(value string) {
+		_config.Config.Versions.Promtail = value
+	})
+
 	addStringOption("version-calico-typha", utils.VERSION_CALICO_TYPHA, "Calico Typha version", func(value string) {
 		_config.Config.Versions.CalicoTypha = value
 	})
@@ -251,10 +358,26 @@ func init() {
 		_config.Config.Versions.CalicoCNI = value
 	})
 
+	addStringOption("version-cilium", utils.VERSION_CILIUM, "Cilium version", func(value string) {
+		_config.Config.Versions.Cilium = value
+	})
+
+	addStringOption("version-weave-net", utils.VERSION_WEAVE_NET, "Weave Net version", func(value string) {
+		_config.Config.Versions.WeaveNet = value
+	})
+
+	addStringOption("version-weave-npc", utils.VERSION_WEAVE_NPC, "Weave NPC version", func(value string) {
+		_config.Config.Versions.WeaveNPC = value
+	})
+
 	addStringOption("version-rbd-provisioner", utils.VERSION_RBD_PROVISIONER, "RBD-Provisioner version", func(value string) {
 		_config.Config.Versions.RBDProvisioner = value
 	})
 
+	addStringOption("version-nfs-subdir-provisioner", utils.VERSION_NFS_SUBDIR_PROVISIONER, "NFS-Subdir-Provisioner version", func(value string) {
+		_config.Config.Versions.NFSSubdirProvisioner = value
+	})
+
 	addStringOption("version-ceph", utils.VERSION_CEPH, "Ceph version", func(value string) {
 		_config.Config.Versions.Ceph = value
 	})
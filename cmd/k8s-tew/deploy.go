@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
 	"path"
+	"syscall"
 
 	"github.com/darxkies/k8s-tew/deployment"
 	"github.com/darxkies/k8s-tew/utils"
@@ -13,6 +16,8 @@ import (
 
 var identityFile string
 var commandRetries uint
+var retryInitialInterval uint
+var retryMaxInterval uint
 var skipSetup bool
 var skipStorageSetup bool
 var skipMonitoringSetup bool
@@ -23,6 +28,20 @@ var skipIngressSetup bool
 var skipPackagingSetup bool
 var pullImages bool
 var forceUpload bool
+var deployDryRun bool
+var skipNodes []string
+var onlyNodes []string
+var progressJSON bool
+var progressOutput string
+var imageBundleDirectory string
+var drainNodes bool
+var drainTimeout uint
+var drainGracePeriod uint
+var drainForce bool
+var versionsLock string
+var maxConcurrentTasks uint
+var keepGoing bool
+var pullImagesFallback bool
 
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
@@ -35,13 +54,44 @@ var deployCmd = &cobra.Command{
 			os.Exit(-1)
 		}
 
-		_deployment := deployment.NewDeployment(_config, identityFile, pullImages, forceUpload, parallel, commandRetries, skipSetup, skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup)
+		if len(versionsLock) > 0 {
+			if error := loadVersionsLock(versionsLock, &_config.Config.Versions); error != nil {
+				log.WithFields(log.Fields{"error": error}).Error("Failed to load versions lock")
+
+				os.Exit(-1)
+			}
+		}
+
+		_deployment := deployment.NewDeployment(_config, identityFile, pullImages, forceUpload, parallel, maxConcurrentTasks, commandRetries, retryInitialInterval, retryMaxInterval, deployDryRun, skipSetup, skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup, skipNodes, onlyNodes, drainNodes, drainTimeout, drainGracePeriod, drainForce, imageBundleDirectory, keepGoing, pullImagesFallback)
+
+		utils.SetProgressJSON(progressJSON)
+
+		if error := setupProgressOutput(progressOutput); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to open progress output file")
+
+			os.Exit(-1)
+		}
 
 		utils.SetProgressSteps(_deployment.Steps() + 1)
 
 		utils.ShowProgress()
 
-		if error := _deployment.Deploy(); error != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+
+			log.Info("Cancelling deployment...")
+
+			cancel()
+		}()
+
+		if error := _deployment.Deploy(ctx); error != nil {
 			log.WithFields(log.Fields{"error": error}).Error("Failed deploying")
 
 			os.Exit(-2)
@@ -56,16 +106,32 @@ var deployCmd = &cobra.Command{
 func init() {
 	deployCmd.Flags().StringVarP(&identityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
 	deployCmd.Flags().UintVarP(&commandRetries, "command-retries", "r", 300, "The count of command retries during the setup")
+	deployCmd.Flags().UintVar(&retryInitialInterval, "retry-initial-interval", utils.RETRY_INITIAL_INTERVAL_SECONDS, "Initial delay in seconds between command retries, doubled after each failure")
+	deployCmd.Flags().UintVar(&retryMaxInterval, "retry-max-interval", utils.RETRY_MAX_INTERVAL_SECONDS, "Maximum delay in seconds between command retries")
 	deployCmd.Flags().BoolVar(&skipSetup, "skip-setup", false, "Skip setup steps")
 	deployCmd.Flags().BoolVar(&skipStorageSetup, "skip-storage-setup", false, "Skip storage setup and all other setup steps that require storage")
 	deployCmd.Flags().BoolVar(&skipMonitoringSetup, "skip-monitoring-setup", false, "Skip monitoring setup")
 	deployCmd.Flags().BoolVar(&skipLoggingSetup, "skip-logging-setup", false, "Skip logging setup")
 	deployCmd.Flags().BoolVar(&skipBackupSetup, "skip-backup-setup", false, "Skip backup setup")
-	deployCmd.Flags().BoolVar(&skipShowcaseSetup, "skip-showcase-setup", false, "Skip showcase setup")
+	deployCmd.Flags().BoolVar(&skipShowcaseSetup, "skip-showcase-setup", true, "Skip showcase setup")
 	deployCmd.Flags().BoolVar(&skipIngressSetup, "skip-ingress-setup", false, "Skip ingress setup")
 	deployCmd.Flags().BoolVar(&skipPackagingSetup, "skip-packaging-setup", false, "Skip packaging setup")
 	deployCmd.Flags().BoolVar(&pullImages, "pull-images", false, "Pull images")
+	deployCmd.Flags().BoolVar(&pullImagesFallback, "pull-images-fallback", false, "If a crictl image pull fails, retry it with ctr directly against containerd's k8s.io namespace instead of failing the deploy, only used together with --pull-images")
+	deployCmd.Flags().StringVar(&imageBundleDirectory, "image-bundle-directory", "", "Import images from the tarballs and manifest an export-images bundle wrote to this directory instead of pulling them from a registry, only used together with --pull-images")
 	deployCmd.Flags().BoolVar(&parallel, "parallel", false, "Run steps in parallel")
+	deployCmd.Flags().UintVar(&maxConcurrentTasks, "max-concurrent-tasks", 0, "Maximum number of image pulls/file uploads running at once across a parallel step, only used together with --parallel. 0 means unbounded")
 	deployCmd.Flags().BoolVar(&forceUpload, "force-upload", false, "Files are uploaded without without any checks")
+	deployCmd.Flags().BoolVar(&deployDryRun, "dry-run", false, "Log what would be uploaded, pulled, tainted and run on each node without touching it")
+	deployCmd.Flags().BoolVar(&drainNodes, "drain-nodes", false, "Cordon and drain each node, respecting PodDisruptionBudgets, before configuring its taint, then uncordon it again; requires a working control plane")
+	deployCmd.Flags().UintVar(&drainTimeout, "drain-timeout", 120, "Seconds to wait for a node's evicted pods to terminate before giving up, only used together with --drain-nodes")
+	deployCmd.Flags().UintVar(&drainGracePeriod, "drain-grace-period", 30, "Grace period in seconds given to each evicted pod to shut down, only used together with --drain-nodes")
+	deployCmd.Flags().BoolVar(&drainForce, "drain-force", false, "Force delete pods still running once drain-timeout elapses instead of failing, only used together with --drain-nodes")
+	deployCmd.Flags().StringSliceVar(&skipNodes, "skip-nodes", []string{}, "Exclude nodes matching these names or labels from the deployment")
+	deployCmd.Flags().StringSliceVar(&onlyNodes, "only-nodes", []string{}, "Restrict the deployment to nodes matching these names or labels")
+	deployCmd.Flags().BoolVar(&progressJSON, "progress-json", !utils.IsTerminal(), "Emit one JSON progress line per step on stdout instead of the interactive spinner, e.g. for parsing in CI logs")
+	deployCmd.Flags().StringVar(&progressOutput, "progress-output", "", "File to write JSON progress lines to instead of stdout, only used together with --progress-json")
+	deployCmd.Flags().StringVar(&versionsLock, "versions-lock", "", "Load versions from a lockfile created by versions-lock, pinning exactly those versions instead of the config's")
+	deployCmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Keep deploying to the remaining nodes when one fails instead of aborting the whole deploy, reporting a summary of the failed nodes at the end with a non-zero exit code")
 	RootCmd.AddCommand(deployCmd)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var etcdRestoreSnapshot string
+var etcdRestoreIdentityFile string
+var etcdRestoreForce bool
+
+// etcdIsServing checks whether any configured etcd endpoint still answers a health check
+func etcdIsServing() bool {
+	return utils.RunCommand(fmt.Sprintf("%s endpoint health", getEtcdctlCommand())) == nil
+}
+
+// stopAndRestoreETCDOnNode stops k8s-tew on nodeName, uploads the snapshot and restores it into a fresh directory
+// with the node's original member identity, then replaces the etcd data directory with it. It deliberately does
+// not start k8s-tew again - a freshly-restored member has a new cluster ID, so starting it while another
+// controller is still running its old, pre-restore etcd on the same peer port would raft-peer two different
+// cluster identities on the same initial-advertise-peer-urls set, causing cluster-ID-mismatch failures or a
+// quorum that never forms. Every controller must go through this before any of them is started again
+func stopAndRestoreETCDOnNode(nodeDeployment *deployment.NodeDeployment, nodeName string, node *config.Node, initialCluster string) error {
+	remoteSnapshot := path.Join(_config.GetFullTargetAssetDirectory(utils.TEMPORARY_DIRECTORY), path.Base(etcdRestoreSnapshot))
+	remoteDataDirectory := _config.GetFullTargetAssetDirectory(utils.ETCD_DATA_DIRECTORY)
+	remoteRestoredDirectory := remoteDataDirectory + ".restored"
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Stopping etcd")
+
+	if _, error := nodeDeployment.Execute("stop-service", fmt.Sprintf("systemctl stop %s", utils.SERVICE_NAME)); error != nil {
+		return fmt.Errorf("could not stop '%s' on node '%s' (%s)", utils.SERVICE_NAME, nodeName, error.Error())
+	}
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Uploading snapshot")
+
+	if error := nodeDeployment.UploadFile(etcdRestoreSnapshot, remoteSnapshot); error != nil {
+		return error
+	}
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Restoring snapshot")
+
+	restoreCommand := fmt.Sprintf("rm -rf %s && %s snapshot restore %s --name %s --initial-cluster %s --initial-cluster-token etcd-cluster --initial-advertise-peer-urls https://%s:2380 --data-dir %s",
+		remoteRestoredDirectory, _config.GetFullTargetAssetFilename(utils.ETCDCTL_BINARY), remoteSnapshot, nodeName, initialCluster, node.IP, remoteRestoredDirectory)
+
+	if _, error := nodeDeployment.Execute("restore-snapshot", restoreCommand); error != nil {
+		return fmt.Errorf("could not restore snapshot on node '%s' (%s)", nodeName, error.Error())
+	}
+
+	replaceCommand := fmt.Sprintf("rm -rf %s && mv %s %s && rm -f %s", remoteDataDirectory, remoteRestoredDirectory, remoteDataDirectory, remoteSnapshot)
+
+	if _, error := nodeDeployment.Execute("replace-data-directory", replaceCommand); error != nil {
+		return fmt.Errorf("could not replace the etcd data directory on node '%s' (%s)", nodeName, error.Error())
+	}
+
+	return nil
+}
+
+// startETCDOnNode starts k8s-tew on nodeName. Only called once every controller has been stopped and restored by
+// stopAndRestoreETCDOnNode
+func startETCDOnNode(nodeDeployment *deployment.NodeDeployment, nodeName string) error {
+	log.WithFields(log.Fields{"node": nodeName}).Info("Starting etcd")
+
+	if _, error := nodeDeployment.Execute("start-service", fmt.Sprintf("systemctl daemon-reload && systemctl enable %s && systemctl start %s", utils.SERVICE_NAME, utils.SERVICE_NAME)); error != nil {
+		return fmt.Errorf("could not start '%s' on node '%s' (%s)", utils.SERVICE_NAME, nodeName, error.Error())
+	}
+
+	return nil
+}
+
+func etcdRestore() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	if len(etcdRestoreSnapshot) == 0 {
+		return errors.New("snapshot filename is required")
+	}
+
+	if !utils.FileExists(etcdRestoreSnapshot) {
+		return fmt.Errorf("snapshot '%s' not found", etcdRestoreSnapshot)
+	}
+
+	if !etcdRestoreForce && etcdIsServing() {
+		return errors.New("etcd is still serving, refusing to restore a snapshot over a live cluster (pass --force to override)")
+	}
+
+	controllers := map[string]*config.Node{}
+
+	for name, node := range _config.Config.Nodes {
+		if node.IsController() {
+			controllers[name] = node
+		}
+	}
+
+	if len(controllers) == 0 {
+		return errors.New("no controllers found")
+	}
+
+	initialCluster, error := _config.ApplyTemplate("etcd-cluster", "{{etcd_cluster}}")
+	if error != nil {
+		return error
+	}
+
+	nodeDeployments := map[string]*deployment.NodeDeployment{}
+
+	for name, node := range controllers {
+		nodeDeployments[name] = deployment.NewNodeDeployment(etcdRestoreIdentityFile, name, node, _config, false, false, false)
+	}
+
+	defer func() {
+		for _, nodeDeployment := range nodeDeployments {
+			nodeDeployment.Close()
+		}
+	}()
+
+	utils.SetProgressSteps(len(controllers) * 2)
+
+	utils.ShowProgress()
+
+	defer utils.HideProgress()
+
+	// Stop every controller and restore the snapshot on all of them before starting any - a freshly-restored
+	// member has a new cluster ID, so starting one while another still runs its old etcd would never form quorum
+	for name, node := range controllers {
+		if error := stopAndRestoreETCDOnNode(nodeDeployments[name], name, node, initialCluster); error != nil {
+			return error
+		}
+
+		utils.IncreaseProgressStep()
+	}
+
+	for name := range controllers {
+		if error := startETCDOnNode(nodeDeployments[name], name); error != nil {
+			return error
+		}
+
+		utils.IncreaseProgressStep()
+	}
+
+	return nil
+}
+
+var etcdRestoreCmd = &cobra.Command{
+	Use:   "etcd-restore",
+	Short: "Restores etcd from a snapshot",
+	Long:  "Restores a snapshot taken with etcd-snapshot onto every controller, recreating the same member set. Stops k8s-tew and restores the snapshot on every controller first, then starts k8s-tew again on every controller, since starting a restored member while another controller still runs its pre-restore etcd would prevent quorum from forming. Refuses to run while etcd is still serving unless --force is set",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := etcdRestore(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to restore etcd")
+
+			os.Exit(-1)
+		}
+
+		log.Info("Done")
+	},
+}
+
+func init() {
+	etcdRestoreCmd.Flags().StringVarP(&etcdRestoreSnapshot, "snapshot", "s", "", "Path of the snapshot file to restore")
+	etcdRestoreCmd.Flags().StringVarP(&etcdRestoreIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	etcdRestoreCmd.Flags().BoolVar(&etcdRestoreForce, "force", false, "Restore even if etcd still appears to be serving")
+	RootCmd.AddCommand(etcdRestoreCmd)
+}
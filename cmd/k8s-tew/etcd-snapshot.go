@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var etcdSnapshotDestination string
+var etcdSnapshotMinioDestination string
+
+func etcdSnapshot() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	utils.SetProgressSteps(1)
+
+	if error := utils.CreateDirectoryIfMissing(_config.GetFullLocalAssetDirectory(utils.ETCD_SNAPSHOTS_DIRECTORY)); error != nil {
+		return error
+	}
+
+	destination := etcdSnapshotDestination
+
+	if len(destination) == 0 {
+		destination = path.Join(_config.GetFullLocalAssetDirectory(utils.ETCD_SNAPSHOTS_DIRECTORY), fmt.Sprintf("etcd-snapshot-%s.db", time.Now().Format("20060102-150405")))
+	}
+
+	log.WithFields(log.Fields{"destination": destination}).Info("Taking etcd snapshot")
+
+	if error := utils.RunCommand(fmt.Sprintf("%s snapshot save %s", getEtcdctlCommand(), destination)); error != nil {
+		return fmt.Errorf("could not take etcd snapshot (%s)", error.Error())
+	}
+
+	if len(etcdSnapshotMinioDestination) > 0 {
+		log.WithFields(log.Fields{"destination": etcdSnapshotMinioDestination}).Info("Uploading etcd snapshot to Minio")
+
+		if error := utils.RunCommand(fmt.Sprintf("mc cp %s %s", destination, etcdSnapshotMinioDestination)); error != nil {
+			return fmt.Errorf("could not upload etcd snapshot to '%s' (%s)", etcdSnapshotMinioDestination, error.Error())
+		}
+	}
+
+	utils.IncreaseProgressStep()
+
+	log.WithFields(log.Fields{"destination": destination}).Info("Took etcd snapshot")
+
+	return nil
+}
+
+var etcdSnapshotCmd = &cobra.Command{
+	Use:   "etcd-snapshot",
+	Short: "Takes a snapshot of etcd",
+	Long:  "Takes a timestamped etcd snapshot with etcdctl against the configured endpoints and the generated kubernetes certificate, saving it locally and, if --minio-destination is set, also uploading it there with the mc client (expected to already be on the PATH). This is independent of the Velero/Ark application-level backups and is meant for disaster recovery of the cluster state itself",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := etcdSnapshot(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to take etcd snapshot")
+
+			os.Exit(-1)
+		}
+
+		log.Info("Done")
+	},
+}
+
+func init() {
+	etcdSnapshotCmd.Flags().StringVarP(&etcdSnapshotDestination, "destination", "d", "", "Local path of the snapshot file (default: a timestamped file under the etcd-snapshots directory)")
+	etcdSnapshotCmd.Flags().StringVar(&etcdSnapshotMinioDestination, "minio-destination", "", "If set, the snapshot is also uploaded there with the mc client (e.g. minio/velero/etcd-snapshot.db)")
+	RootCmd.AddCommand(etcdSnapshotCmd)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var exportImagesDirectory string
+var exportImagesSkipStorageSetup bool
+var exportImagesSkipMonitoringSetup bool
+var exportImagesSkipLoggingSetup bool
+var exportImagesSkipBackupSetup bool
+var exportImagesSkipShowcaseSetup bool
+var exportImagesSkipIngressSetup bool
+var exportImagesSkipPackagingSetup bool
+
+var exportImagesCmd = &cobra.Command{
+	Use:   "export-images",
+	Short: "Pulls and exports images into a bundle for air-gapped transfer",
+	Long:  "Pulls every image the cluster setup requires - the same list and registry mirror deploy's --pull-images would use, minus whatever the --skip-*-setup flags below exclude - with a throwaway local containerd instance, exports each to a tarball in the target directory and records their digests in manifest.json. The bundle can be copied to an air-gapped environment and loaded onto nodes with deploy's --image-bundle-directory flag instead of pulling from a registry",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(exportImagesDirectory) == 0 {
+			log.Error("--directory is required")
+
+			os.Exit(-1)
+		}
+
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		_imageBundle := deployment.NewImageBundle(_config, exportImagesDirectory, exportImagesSkipStorageSetup, exportImagesSkipMonitoringSetup, exportImagesSkipLoggingSetup, exportImagesSkipBackupSetup, exportImagesSkipShowcaseSetup, exportImagesSkipIngressSetup, exportImagesSkipPackagingSetup)
+
+		utils.SetProgressJSON(progressJSON)
+
+		if error := setupProgressOutput(progressOutput); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to open progress output file")
+
+			os.Exit(-1)
+		}
+
+		utils.SetProgressSteps(_imageBundle.Steps())
+
+		utils.ShowProgress()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+
+			log.Info("Cancelling export...")
+
+			cancel()
+		}()
+
+		if error := _imageBundle.Export(ctx); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed exporting images")
+
+			os.Exit(-2)
+		}
+
+		utils.HideProgress()
+
+		log.Info("Done")
+	},
+}
+
+func init() {
+	exportImagesCmd.Flags().StringVarP(&exportImagesDirectory, "directory", "d", "", "Directory to write the image bundle (tarballs and manifest.json) to")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipStorageSetup, "skip-storage-setup", false, "Exclude images that require storage")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipMonitoringSetup, "skip-monitoring-setup", false, "Exclude images that require monitoring")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipLoggingSetup, "skip-logging-setup", false, "Exclude images that require logging")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipBackupSetup, "skip-backup-setup", false, "Exclude images that require backup")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipShowcaseSetup, "skip-showcase-setup", true, "Exclude images that require the showcase")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipIngressSetup, "skip-ingress-setup", false, "Exclude images that require ingress")
+	exportImagesCmd.Flags().BoolVar(&exportImagesSkipPackagingSetup, "skip-packaging-setup", false, "Exclude images that require packaging")
+	exportImagesCmd.Flags().BoolVar(&progressJSON, "progress-json", !utils.IsTerminal(), "Emit one JSON progress line per step on stdout instead of the interactive spinner, e.g. for parsing in CI logs")
+	exportImagesCmd.Flags().StringVar(&progressOutput, "progress-output", "", "File to write JSON progress lines to instead of stdout, only used together with --progress-json")
+	RootCmd.AddCommand(exportImagesCmd)
+}
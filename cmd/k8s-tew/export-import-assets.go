@@ -0,0 +1,257 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/utils"
+
+	"golang.org/x/crypto/openpgp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportAssetsFilename    string
+	exportAssetsPassphrase  string
+	exportAssetsIncludeData bool
+	importAssetsFilename    string
+	importAssetsPassphrase  string
+)
+
+// exportAssets tars up the asset tree (certificates, kubeconfigs, configs, ...) rooted at the base directory, so
+// it can be backed up or transferred to another operator's machine. The dynamic data directory (etcd, containerd
+// and kubelet state) is excluded by default since it is large and not needed to reconstruct the control state
+func exportAssets() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	excludedDirectory := ""
+
+	if !exportAssetsIncludeData {
+		excludedDirectory = _config.GetFullLocalAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY)
+	}
+
+	outputFile, error := os.Create(exportAssetsFilename)
+	if error != nil {
+		return fmt.Errorf("could not create '%s' (%s)", exportAssetsFilename, error.Error())
+	}
+
+	defer outputFile.Close()
+
+	var output io.WriteCloser = outputFile
+
+	if len(exportAssetsPassphrase) > 0 {
+		output, error = openpgp.SymmetricallyEncrypt(outputFile, []byte(exportAssetsPassphrase), nil, nil)
+		if error != nil {
+			return fmt.Errorf("could not encrypt '%s' (%s)", exportAssetsFilename, error.Error())
+		}
+
+		defer output.Close()
+	}
+
+	gzipWriter := gzip.NewWriter(output)
+	defer gzipWriter.Close()
+
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	baseDirectory := _config.BaseDirectory
+
+	return filepath.Walk(baseDirectory, addToTar(tarWriter, baseDirectory, excludedDirectory))
+}
+
+// addToTar returns a filepath.WalkFunc that writes every file below baseDirectory, except excludedDirectory, into
+// tarWriter using paths relative to baseDirectory
+func addToTar(tarWriter *tar.Writer, baseDirectory, excludedDirectory string) filepath.WalkFunc {
+	return func(filename string, info os.FileInfo, error error) error {
+		if error != nil {
+			return error
+		}
+
+		if filename == baseDirectory {
+			return nil
+		}
+
+		if len(excludedDirectory) > 0 && (filename == excludedDirectory || strings.HasPrefix(filename, excludedDirectory+string(os.PathSeparator))) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relativeFilename, error := filepath.Rel(baseDirectory, filename)
+		if error != nil {
+			return error
+		}
+
+		header, error := tar.FileInfoHeader(info, "")
+		if error != nil {
+			return error
+		}
+
+		header.Name = relativeFilename
+
+		if error := tarWriter.WriteHeader(header); error != nil {
+			return error
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		file, error := os.Open(filename)
+		if error != nil {
+			return error
+		}
+
+		defer file.Close()
+
+		_, error = io.Copy(tarWriter, file)
+
+		return error
+	}
+}
+
+// passphrasePrompt returns an openpgp.PromptFunction that hands out the given passphrase once; a second prompt
+// (a wrong passphrase) is treated as a failure instead of prompting again
+func passphrasePrompt(passphrase string) openpgp.PromptFunction {
+	promptedOnce := false
+
+	return func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if promptedOnce {
+			return nil, fmt.Errorf("wrong passphrase")
+		}
+
+		promptedOnce = true
+
+		return []byte(passphrase), nil
+	}
+}
+
+// importAssets extracts a tarball created by exportAssets back into the base directory, reconstructing the asset
+// tree
+func importAssets() error {
+	_config = config.NewInternalConfig(getBaseDirectory())
+
+	inputFile, error := os.Open(importAssetsFilename)
+	if error != nil {
+		return fmt.Errorf("could not open '%s' (%s)", importAssetsFilename, error.Error())
+	}
+
+	defer inputFile.Close()
+
+	var input io.Reader = inputFile
+
+	if len(importAssetsPassphrase) > 0 {
+		messageDetails, error := openpgp.ReadMessage(inputFile, nil, passphrasePrompt(importAssetsPassphrase), nil)
+		if error != nil {
+			return fmt.Errorf("could not decrypt '%s' (%s)", importAssetsFilename, error.Error())
+		}
+
+		input = messageDetails.UnverifiedBody
+	}
+
+	gzipReader, error := gzip.NewReader(input)
+	if error != nil {
+		return fmt.Errorf("could not read '%s' (%s)", importAssetsFilename, error.Error())
+	}
+
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+
+	baseDirectory := _config.BaseDirectory
+
+	for {
+		header, error := tarReader.Next()
+
+		if error == io.EOF {
+			break
+		}
+
+		if error != nil {
+			return error
+		}
+
+		fullFilename := path.Join(baseDirectory, header.Name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if error := utils.CreateDirectoryIfMissing(fullFilename); error != nil {
+				return error
+			}
+
+		case tar.TypeReg:
+			if error := utils.CreateDirectoryIfMissing(path.Dir(fullFilename)); error != nil {
+				return error
+			}
+
+			outputFile, error := os.OpenFile(fullFilename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode))
+			if error != nil {
+				return error
+			}
+
+			defer outputFile.Close()
+
+			if _, error := io.Copy(outputFile, tarReader); error != nil {
+				return error
+			}
+
+		default:
+		}
+	}
+
+	log.WithFields(log.Fields{"filename": importAssetsFilename, "base-directory": baseDirectory}).Info("Assets imported")
+
+	return nil
+}
+
+var exportAssetsCmd = &cobra.Command{
+	Use:   "export-assets",
+	Short: "Exports the asset tree as a tarball",
+	Long:  "Tars the asset tree (certificates, kubeconfigs, configs) up for backup or transfer to another operator's machine. Optionally encrypted with a passphrase",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := exportAssets(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Export failed")
+
+			os.Exit(-1)
+		}
+
+		log.WithFields(log.Fields{"filename": exportAssetsFilename}).Info("Assets exported")
+	},
+}
+
+var importAssetsCmd = &cobra.Command{
+	Use:   "import-assets",
+	Short: "Imports an asset tree tarball",
+	Long:  "Restores an asset tree tarball, created by export-assets, into the base directory",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := importAssets(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Import failed")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	exportAssetsCmd.Flags().StringVarP(&exportAssetsFilename, "filename", "f", "k8s-tew-assets.tar.gz", "Tarball filename")
+	exportAssetsCmd.Flags().StringVarP(&exportAssetsPassphrase, "passphrase", "p", "", "Passphrase to encrypt the tarball with (default: unencrypted)")
+	exportAssetsCmd.Flags().BoolVar(&exportAssetsIncludeData, "include-data", false, "Include the dynamic data directory (etcd, containerd, kubelet state) instead of excluding it")
+	RootCmd.AddCommand(exportAssetsCmd)
+
+	importAssetsCmd.Flags().StringVarP(&importAssetsFilename, "filename", "f", "k8s-tew-assets.tar.gz", "Tarball filename")
+	importAssetsCmd.Flags().StringVarP(&importAssetsPassphrase, "passphrase", "p", "", "Passphrase to decrypt the tarball with (default: unencrypted)")
+	RootCmd.AddCommand(importAssetsCmd)
+}
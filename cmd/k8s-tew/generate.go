@@ -13,6 +13,7 @@ import (
 
 var forceDownload bool
 var parallel bool
+var skipChecksumVerification bool
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
@@ -26,7 +27,7 @@ var generateCmd = &cobra.Command{
 			os.Exit(-1)
 		}
 
-		downloader := download.NewDownloader(_config, forceDownload, parallel)
+		downloader := download.NewDownloader(_config, forceDownload, parallel, skipChecksumVerification)
 		generator := generate.NewGenerator(_config)
 
 		utils.SetProgressSteps(2 + downloader.Steps() + generator.Steps() + 1)
@@ -71,5 +72,6 @@ func init() {
 	generateCmd.Flags().UintVarP(&commandRetries, "command-retries", "r", 300, "The count of command retries during the setup")
 	generateCmd.Flags().BoolVar(&forceDownload, "force-download", false, "Force download")
 	generateCmd.Flags().BoolVar(&parallel, "parallel", false, "Download in parallel")
+	generateCmd.Flags().BoolVar(&skipChecksumVerification, "skip-checksum-verification", false, "Skip checksum verification of downloaded artifacts, e.g. when using locally-mirrored artifacts")
 	RootCmd.AddCommand(generateCmd)
 }
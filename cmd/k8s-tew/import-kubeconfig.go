@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	importKubeconfigSource      string
+	importKubeconfigTarget      string
+	importKubeconfigContextName string
+	importKubeconfigSetCurrent  bool
+)
+
+// kubeconfigFile is a loosely typed mirror of a kubeconfig file, keeping every unknown field intact while merging
+type kubeconfigFile struct {
+	APIVersion     string                   `yaml:"apiVersion"`
+	Kind           string                   `yaml:"kind"`
+	Clusters       []map[string]interface{} `yaml:"clusters"`
+	Users          []map[string]interface{} `yaml:"users"`
+	Contexts       []map[string]interface{} `yaml:"contexts"`
+	CurrentContext string                   `yaml:"current-context,omitempty"`
+}
+
+func loadKubeconfigFile(filename string) (*kubeconfigFile, error) {
+	kubeconfig := &kubeconfigFile{APIVersion: "v1", Kind: "Config"}
+
+	if !utils.FileExists(filename) {
+		return kubeconfig, nil
+	}
+
+	content, error := ioutil.ReadFile(filename)
+	if error != nil {
+		return nil, error
+	}
+
+	if error := yaml.Unmarshal(content, kubeconfig); error != nil {
+		return nil, error
+	}
+
+	return kubeconfig, nil
+}
+
+func findByName(entries []map[string]interface{}, name string) bool {
+	for _, entry := range entries {
+		if entry["name"] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uniqueName suffixes name with -2, -3, ... until it does not collide with any entry already in the target kubeconfig
+func uniqueName(target *kubeconfigFile, name string) string {
+	candidate := name
+
+	for index := 2; findByName(target.Clusters, candidate) || findByName(target.Users, candidate) || findByName(target.Contexts, candidate); index++ {
+		candidate = fmt.Sprintf("%s-%d", name, index)
+	}
+
+	return candidate
+}
+
+func importKubeconfig() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	if len(importKubeconfigSource) == 0 {
+		importKubeconfigSource = _config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG)
+	}
+
+	source, error := loadKubeconfigFile(importKubeconfigSource)
+	if error != nil {
+		return fmt.Errorf("could not load source kubeconfig '%s' (%s)", importKubeconfigSource, error.Error())
+	}
+
+	if len(source.Clusters) == 0 || len(source.Users) == 0 || len(source.Contexts) == 0 {
+		return fmt.Errorf("source kubeconfig '%s' does not have a cluster, user and context to import", importKubeconfigSource)
+	}
+
+	target, error := loadKubeconfigFile(importKubeconfigTarget)
+	if error != nil {
+		return fmt.Errorf("could not load target kubeconfig '%s' (%s)", importKubeconfigTarget, error.Error())
+	}
+
+	contextName := importKubeconfigContextName
+	if len(contextName) == 0 {
+		contextName = _config.Config.ClusterName
+	}
+
+	contextName = uniqueName(target, contextName)
+
+	cluster := source.Clusters[0]
+	cluster["name"] = contextName
+
+	user := source.Users[0]
+	user["name"] = contextName
+
+	context := source.Contexts[0]
+	context["name"] = contextName
+	context["context"] = map[string]interface{}{"cluster": contextName, "user": contextName}
+
+	target.Clusters = append(target.Clusters, cluster)
+	target.Users = append(target.Users, user)
+	target.Contexts = append(target.Contexts, context)
+
+	if importKubeconfigSetCurrent {
+		target.CurrentContext = contextName
+	}
+
+	content, error := yaml.Marshal(target)
+	if error != nil {
+		return error
+	}
+
+	if error := utils.CreateDirectoryIfMissing(path.Dir(importKubeconfigTarget)); error != nil {
+		return error
+	}
+
+	if error := ioutil.WriteFile(importKubeconfigTarget, content, 0644); error != nil {
+		return error
+	}
+
+	log.WithFields(log.Fields{"context": contextName, "target": importKubeconfigTarget}).Info("Imported kubeconfig")
+
+	return nil
+}
+
+var importKubeconfigCmd = &cobra.Command{
+	Use:   "import-kubeconfig",
+	Short: "Merges the cluster's admin kubeconfig into an existing kubeconfig",
+	Long:  "Merges the generated admin kubeconfig's cluster, user and context into an existing kubeconfig file, without touching its other entries. Name collisions are resolved by suffixing the context name",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := importKubeconfig(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to import kubeconfig")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	importKubeconfigCmd.Flags().StringVarP(&importKubeconfigSource, "kubeconfig", "k", "", "Source kubeconfig to import (default: the generated admin kubeconfig)")
+	importKubeconfigCmd.Flags().StringVarP(&importKubeconfigTarget, "target", "t", path.Join(os.Getenv("HOME"), ".kube/config"), "Target kubeconfig to merge into")
+	importKubeconfigCmd.Flags().StringVarP(&importKubeconfigContextName, "context-name", "c", "", "Name used for the imported cluster/user/context (default: the configured cluster name)")
+	importKubeconfigCmd.Flags().BoolVar(&importKubeconfigSetCurrent, "set-current-context", true, "Make the imported context the current context")
+	RootCmd.AddCommand(importKubeconfigCmd)
+}
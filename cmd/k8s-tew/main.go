@@ -18,6 +18,8 @@ import (
 
 var debug *bool
 var hideProgress *bool
+var logLevel *string
+var logFormat *string
 var baseDirectory string
 var _config *config.InternalConfig
 
@@ -62,11 +64,36 @@ func getBaseDirectory() string {
 	return result
 }
 
+// setupProgressOutput redirects progress events to filename when it is non-empty, otherwise it leaves the
+// default of stdout in place
+func setupProgressOutput(filename string) error {
+	if len(filename) == 0 {
+		return nil
+	}
+
+	file, error := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if error != nil {
+		return error
+	}
+
+	utils.SetProgressWriter(file)
+
+	return nil
+}
+
 // bootstrap loads the configuration and performs other checks such as the need for root rights
 func bootstrap(needsRoot bool) error {
+	if error := utils.SetLogLevel(*logLevel); error != nil {
+		return error
+	}
+
 	utils.SetDebug(*debug)
 	utils.SupressProgress(*hideProgress)
 
+	if error := utils.SetLogFormat(*logFormat); error != nil {
+		return error
+	}
+
 	if needsRoot && !utils.IsRoot() {
 		return errors.New("this program needs root rights")
 	}
@@ -79,6 +106,8 @@ func bootstrap(needsRoot bool) error {
 func main() {
 	debug = RootCmd.PersistentFlags().BoolP("debug", "d", false, "Show debug messages")
 	hideProgress = RootCmd.PersistentFlags().Bool("hide-progress", false, "Hide progress")
+	logLevel = RootCmd.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error, fatal or panic)")
+	logFormat = RootCmd.PersistentFlags().String("log-format", "text", "Log format (text or json); json also disables the interactive progress spinner")
 	RootCmd.PersistentFlags().StringVar(&baseDirectory, "base-directory", getDefaultBaseDirectory(), "Base directory")
 
 	if _error := RootCmd.Execute(); _error != nil {
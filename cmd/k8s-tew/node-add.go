@@ -17,6 +17,7 @@ var nodeIP string
 var nodeIndex uint
 var nodeLabels string
 var nodeSelf bool
+var nodeArch string
 
 func addNode() error {
 	// Load config and check the rights
@@ -73,7 +74,7 @@ func addNode() error {
 		_config.Config.DeploymentDirectory = _config.BaseDirectory
 	}
 
-	if _, error = _config.AddNode(nodeName, nodeIP, nodeIndex, labels); error != nil {
+	if _, error = _config.AddNode(nodeName, nodeIP, nodeIndex, labels, nodeArch); error != nil {
 		return error
 	}
 
@@ -103,5 +104,6 @@ func init() {
 	nodeAddCmd.Flags().UintVarP(&nodeIndex, "index", "x", 0, "The unique index of the node.")
 	nodeAddCmd.Flags().StringVarP(&nodeLabels, "labels", "l", fmt.Sprintf("%s,%s", utils.NODE_CONTROLLER, utils.NODE_WORKER), "The labels of the node which define the attributes of the node.")
 	nodeAddCmd.Flags().BoolVarP(&nodeSelf, "self", "s", false, "Add this machine by infering the name, the ip and assuming it is a controller and a worker")
+	nodeAddCmd.Flags().StringVarP(&nodeArch, "arch", "a", "", fmt.Sprintf("The CPU architecture of the node (%s or %s). Defaults to %s", utils.DEFAULT_ARCH, utils.ARCH_ARM64, utils.DEFAULT_ARCH))
 	RootCmd.AddCommand(nodeAddCmd)
 }
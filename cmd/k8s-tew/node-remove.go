@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/darxkies/k8s-tew/utils"
 	log "github.com/sirupsen/logrus"
@@ -9,6 +12,51 @@ import (
 )
 
 var removeNodeName string
+var removeNodeCleanupCluster bool
+
+func getEtcdctlCommand() string {
+	return fmt.Sprintf("%s --cacert %s --cert %s --key %s --endpoints %s", _config.GetFullLocalAssetFilename(utils.ETCDCTL_BINARY), _config.GetFullLocalAssetFilename(utils.CA_PEM), _config.GetFullLocalAssetFilename(utils.KUBERNETES_PEM), _config.GetFullLocalAssetFilename(utils.KUBERNETES_KEY_PEM), strings.Join(_config.GetETCDClientEndpoints(), ","))
+}
+
+type etcdMember struct {
+	ID   uint64 `json:"ID"`
+	Name string `json:"name"`
+}
+
+type etcdMemberList struct {
+	Members []etcdMember `json:"members"`
+}
+
+// removeETCDMember removes nodeName's etcd member, if it still has one, by looking it up by name since k8s-tew
+// names every etcd member after its node
+func removeETCDMember(nodeName string) error {
+	output, error := utils.RunCommandWithOutput(fmt.Sprintf("%s member list --write-out json", getEtcdctlCommand()))
+	if error != nil {
+		return fmt.Errorf("could not list etcd members (%s)", error.Error())
+	}
+
+	members := etcdMemberList{}
+
+	if error := json.Unmarshal([]byte(output), &members); error != nil {
+		return fmt.Errorf("could not parse etcd member list (%s)", error.Error())
+	}
+
+	for _, member := range members.Members {
+		if member.Name != nodeName {
+			continue
+		}
+
+		if error := utils.RunCommand(fmt.Sprintf("%s member remove %x", getEtcdctlCommand(), member.ID)); error != nil {
+			return fmt.Errorf("could not remove etcd member '%s' (%s)", nodeName, error.Error())
+		}
+
+		return nil
+	}
+
+	log.WithFields(log.Fields{"node": nodeName}).Debug("Node was not an etcd member")
+
+	return nil
+}
 
 func removeNode() error {
 	// Load config and check the rights
@@ -16,9 +64,38 @@ func removeNode() error {
 		return error
 	}
 
+	node, ok := _config.Config.Nodes[removeNodeName]
+	if !ok {
+		return fmt.Errorf("node '%s' not found", removeNodeName)
+	}
+
 	utils.SetProgressSteps(1)
 
-	if error := _config.RemoveNode(removeNodeName); error != nil {
+	if removeNodeCleanupCluster {
+		if node.IsController() || node.IsWorker() {
+			log.WithFields(log.Fields{"node": removeNodeName}).Info("Draining node")
+
+			if error := utils.RunCommand(fmt.Sprintf("%s drain %s --ignore-daemonsets --delete-local-data --force", getKubectlCommand(), removeNodeName)); error != nil {
+				log.WithFields(log.Fields{"node": removeNodeName, "error": error}).Warn("Could not drain node, continuing with removal")
+			}
+
+			log.WithFields(log.Fields{"node": removeNodeName}).Info("Deleting node object")
+
+			if error := utils.RunCommand(fmt.Sprintf("%s delete node %s --ignore-not-found", getKubectlCommand(), removeNodeName)); error != nil {
+				log.WithFields(log.Fields{"node": removeNodeName, "error": error}).Warn("Could not delete node object, continuing with removal")
+			}
+		}
+
+		if node.IsController() && !_config.Config.ExternalETCD.Enabled() {
+			log.WithFields(log.Fields{"node": removeNodeName}).Info("Removing etcd member")
+
+			if error := removeETCDMember(removeNodeName); error != nil {
+				log.WithFields(log.Fields{"node": removeNodeName, "error": error}).Warn("Could not remove etcd member, continuing with removal")
+			}
+		}
+	}
+
+	if _, error := _config.RemoveNode(removeNodeName); error != nil {
 		return error
 	}
 
@@ -32,7 +109,7 @@ func removeNode() error {
 var nodeRemoveCmd = &cobra.Command{
 	Use:   "node-remove",
 	Short: "Remove a node",
-	Long:  "Remove a node",
+	Long:  "Removes a node from the cluster. Unless --cleanup-cluster=false, it drains and deletes the matching Kubernetes node object and, if the node was a controller, removes its etcd member. It always deletes the node's local kubelet certificates and configuration, and refuses to remove the last controller, warning instead if the removal would leave etcd with less than 3 controllers",
 	Run: func(cmd *cobra.Command, args []string) {
 		if error := removeNode(); error != nil {
 			log.WithFields(log.Fields{"error": error}).Error("Failed to remove node")
@@ -44,5 +121,6 @@ var nodeRemoveCmd = &cobra.Command{
 
 func init() {
 	nodeRemoveCmd.Flags().StringVarP(&removeNodeName, "name", "n", "", "Unique name of the node")
+	nodeRemoveCmd.Flags().BoolVar(&removeNodeCleanupCluster, "cleanup-cluster", true, "Drain and delete the Kubernetes node object and remove the etcd member, if any, before removing the node from the configuration")
 	RootCmd.AddCommand(nodeRemoveCmd)
 }
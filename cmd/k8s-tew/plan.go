@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/generate"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var planOutputFilename string
+
+const planSchemaVersion = 1
+
+// Plan is the machine readable result of a dry-run. It lists every file that generate would create or update and
+// every command that would run on every node, without touching the filesystem or a node. A CI job can parse it to
+// gate merges or require approval whenever the control plane configuration changes
+type Plan struct {
+	SchemaVersion int                       `json:"schema-version"`
+	Files         []utils.PlannedFileChange `json:"files"`
+	Commands      []utils.PlannedCommand    `json:"commands"`
+}
+
+// planCommands works out, for every node, which of the global commands would run on it and how they would be
+// rendered. It relies on the label matching already used by servers.Servers and deployment.Deployment, but since
+// config.Node has no per-node OS, it cannot take a command's OS restriction into account
+func planCommands() {
+	for nodeName, node := range _config.Config.Nodes {
+		_config.SetNode(nodeName, node)
+
+		for _, command := range _config.Config.Commands {
+			if !config.CompareLabels(node.Labels, command.Labels) {
+				continue
+			}
+
+			renderedCommand, error := _config.ApplyTemplate(command.Name, command.Command)
+			if error != nil {
+				renderedCommand = command.Command
+			}
+
+			utils.RecordPlannedCommand(nodeName, command.Name, renderedCommand)
+		}
+	}
+}
+
+func plan() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	utils.SetDryRun(true)
+	defer utils.SetDryRun(false)
+
+	_config.Generate()
+
+	generator := generate.NewGenerator(_config)
+
+	if error := generator.GenerateFiles(); error != nil {
+		return error
+	}
+
+	planCommands()
+
+	result := Plan{SchemaVersion: planSchemaVersion, Files: utils.GetPlannedFileChanges(), Commands: utils.GetPlannedCommands()}
+
+	content, error := json.MarshalIndent(result, "", "  ")
+	if error != nil {
+		return error
+	}
+
+	if len(planOutputFilename) == 0 {
+		fmt.Println(string(content))
+
+		return nil
+	}
+
+	return ioutil.WriteFile(planOutputFilename, content, 0644)
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show what generate would change",
+	Long:  "Simulates generate without writing anything, emitting a versioned JSON document listing every file that would be created or updated and every command that would run per node, so automation can gate merges on control plane changes",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := plan(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Plan failed")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&planOutputFilename, "output", "o", "", "Write the plan to a file instead of stdout")
+	RootCmd.AddCommand(planCmd)
+}
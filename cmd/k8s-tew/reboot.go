@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rebootCommand string
+var rebootReadyRetries uint
+
+func getKubectlCommand() string {
+	return fmt.Sprintf("%s --kubeconfig %s", _config.GetFullLocalAssetFilename(utils.KUBECTL_BINARY), _config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG))
+}
+
+func waitForNodeReady(nodeName string) error {
+	for retries := uint(0); retries < rebootReadyRetries; retries++ {
+		output, error := utils.RunCommandWithOutput(fmt.Sprintf(`%s get node %s --output jsonpath='{.status.conditions[?(@.type=="Ready")].status}'`, getKubectlCommand(), nodeName))
+
+		if error == nil && strings.TrimSpace(output) == "True" {
+			return nil
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+
+	return fmt.Errorf("node '%s' did not become ready again in time", nodeName)
+}
+
+func rebootNode(nodeName string, node *config.Node) error {
+	if node.IsWorker() {
+		log.WithFields(log.Fields{"node": nodeName}).Info("Draining node")
+
+		if error := utils.RunCommand(fmt.Sprintf("%s drain %s --ignore-daemonsets --delete-local-data --force", getKubectlCommand(), nodeName)); error != nil {
+			return fmt.Errorf("could not drain node '%s' (%s)", nodeName, error.Error())
+		}
+	} else {
+		log.WithFields(log.Fields{"node": nodeName}).Info("Cordoning node")
+
+		if error := utils.RunCommand(fmt.Sprintf("%s cordon %s", getKubectlCommand(), nodeName)); error != nil {
+			return fmt.Errorf("could not cordon node '%s' (%s)", nodeName, error.Error())
+		}
+	}
+
+	nodeDeployment := deployment.NewNodeDeployment(identityFile, nodeName, node, _config, false, false, false)
+	defer nodeDeployment.Close()
+
+	log.WithFields(log.Fields{"node": nodeName, "_command": rebootCommand}).Info("Rebooting node")
+
+	// The reboot command is expected to tear down the connection before returning, so its error is not fatal
+	if _, error := nodeDeployment.Execute("reboot", rebootCommand); error != nil {
+		log.WithFields(log.Fields{"node": nodeName, "error": error}).Debug("Reboot command closed the connection")
+	}
+
+	// Give the node time to actually go down before polling for it to come back
+	time.Sleep(10 * time.Second)
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Waiting for node to become ready again")
+
+	if error := waitForNodeReady(nodeName); error != nil {
+		return error
+	}
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Uncordoning node")
+
+	if error := utils.RunCommand(fmt.Sprintf("%s uncordon %s", getKubectlCommand(), nodeName)); error != nil {
+		return fmt.Errorf("could not uncordon node '%s' (%s)", nodeName, error.Error())
+	}
+
+	return nil
+}
+
+func rollingReboot() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	sortedNodeKeys := _config.GetSortedNodeKeys()
+
+	utils.SetProgressSteps(len(sortedNodeKeys))
+
+	utils.ShowProgress()
+
+	defer utils.HideProgress()
+
+	for _, nodeName := range sortedNodeKeys {
+		node := _config.Config.Nodes[nodeName]
+
+		if error := rebootNode(nodeName, node); error != nil {
+			return error
+		}
+
+		utils.IncreaseProgressStep()
+	}
+
+	return nil
+}
+
+var rebootCmd = &cobra.Command{
+	Use:   "reboot",
+	Short: "Rolling reboot",
+	Long:  "Reboots all nodes one at a time, draining workers and cordoning controllers beforehand and waiting for each node to become ready again before moving on, so the cluster stays available throughout",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := rollingReboot(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Rolling reboot failed")
+
+			os.Exit(-1)
+		}
+
+		log.Info("Done")
+	},
+}
+
+func init() {
+	rebootCmd.Flags().StringVar(&rebootCommand, "reboot-command", "sudo reboot", "Command executed over SSH on each node to trigger the reboot")
+	rebootCmd.Flags().UintVar(&rebootReadyRetries, "ready-retries", 60, "The count of retries, with a 5 second pause between each, while waiting for a node to become ready again after rebooting")
+	RootCmd.AddCommand(rebootCmd)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/generate"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var regenerateKubeconfigsUpload bool
+var regenerateKubeconfigsIdentityFile string
+
+var regenerateKubeconfigsCmd = &cobra.Command{
+	Use:   "regenerate-kubeconfigs",
+	Short: "Regenerate every kubeconfig from the current configuration",
+	Long:  "Rewrites the admin, controller-manager, scheduler, proxy and kubelet-* kubeconfigs from the current configuration - e.g. after the load balancer vip or the apiserver port changed - without rotating the CA or any leaf certificate, and unless --upload=false uploads them to every node and restarts the affected servers",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		generator := generate.NewGenerator(_config)
+
+		utils.SetProgressSteps(1)
+
+		if error := generator.RegenerateKubeConfigs(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed regenerating kubeconfigs")
+
+			os.Exit(-1)
+		}
+
+		utils.IncreaseProgressStep()
+
+		log.Info("Regenerated kubeconfigs")
+
+		if !regenerateKubeconfigsUpload {
+			return
+		}
+
+		_deployment := deployment.NewDeployment(_config, regenerateKubeconfigsIdentityFile, false, true, parallel, 0, commandRetries, retryInitialInterval, retryMaxInterval, false, true, true, true, true, true, true, true, true, []string{}, []string{}, false, 0, 0, false, "", false, false)
+
+		if error := _deployment.UploadFiles(context.Background()); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed uploading regenerated kubeconfigs")
+
+			os.Exit(-1)
+		}
+
+		log.Info("Uploaded regenerated kubeconfigs and restarted affected servers")
+	},
+}
+
+func init() {
+	regenerateKubeconfigsCmd.Flags().BoolVar(&regenerateKubeconfigsUpload, "upload", true, "Upload the regenerated kubeconfigs to every node and restart the servers that changed")
+	regenerateKubeconfigsCmd.Flags().StringVarP(&regenerateKubeconfigsIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	RootCmd.AddCommand(regenerateKubeconfigsCmd)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/generate"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var renderOutputDirectory string
+var renderNodeName string
+
+// newRenderConfig clones _config so rendering never touches the real base directory: the clone's BaseDirectory is
+// redirected to outputDirectory and, when nodeName is given, its Nodes are narrowed down to that single node, since
+// several templates (containerd config, kubelet kubeconfig, ...) are rendered once per node
+func newRenderConfig(outputDirectory, nodeName string) (*config.InternalConfig, error) {
+	renderedConfig := *_config.Config
+
+	if len(nodeName) > 0 {
+		node, ok := _config.Config.Nodes[nodeName]
+		if !ok {
+			return nil, fmt.Errorf("node '%s' not found", nodeName)
+		}
+
+		renderedConfig.Nodes = config.Nodes{nodeName: node}
+	}
+
+	return &config.InternalConfig{BaseDirectory: outputDirectory, Config: &renderedConfig}, nil
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render all templates into a directory for inspection",
+	Long:  "Render all templates into a directory for inspection",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Load config and check the rights
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Render failed")
+
+			os.Exit(-1)
+		}
+
+		_config.Generate()
+
+		renderConfig, error := newRenderConfig(renderOutputDirectory, renderNodeName)
+		if error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Render failed")
+
+			os.Exit(-1)
+		}
+
+		if error := utils.CreateDirectoryIfMissing(renderOutputDirectory); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Render failed")
+
+			os.Exit(-1)
+		}
+
+		generator := generate.NewGenerator(renderConfig)
+
+		utils.SetProgressSteps(generator.Steps())
+
+		utils.ShowProgress()
+
+		if error := generator.GenerateFiles(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Render failed")
+
+			os.Exit(-1)
+		}
+
+		utils.HideProgress()
+
+		log.WithFields(log.Fields{"_directory": renderOutputDirectory}).Info("Done")
+	},
+}
+
+func init() {
+	renderCmd.Flags().StringVar(&renderOutputDirectory, "output", "", "Directory to render the templates into")
+	renderCmd.Flags().StringVar(&renderNodeName, "node", "", "Only render templates for the given node; by default templates are rendered for all nodes")
+	renderCmd.MarkFlagRequired("output")
+	RootCmd.AddCommand(renderCmd)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/generate"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rotateCA bool
+var rotateCertificatesRestartServers bool
+var rotateCertificatesIdentityFile string
+
+var rotateCertificatesCmd = &cobra.Command{
+	Use:   "rotate-certificates",
+	Short: "Rotate the cluster's leaf certificates",
+	Long:  "Re-issues every leaf certificate (kubernetes, admin, proxy, controller-manager, scheduler, kubelet-*, service-account, aggregator) against the existing CA and, unless --restart-servers=false, uploads them to every node and restarts the affected servers. --rotate-ca additionally regenerates the CA itself and re-signs everything against it",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		generator := generate.NewGenerator(_config)
+
+		utils.SetProgressSteps(1)
+
+		if error := generator.RotateCertificates(rotateCA); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed rotating certificates")
+
+			os.Exit(-1)
+		}
+
+		utils.IncreaseProgressStep()
+
+		log.Info("Rotated certificates")
+
+		if !rotateCertificatesRestartServers {
+			return
+		}
+
+		_deployment := deployment.NewDeployment(_config, rotateCertificatesIdentityFile, false, true, parallel, 0, commandRetries, retryInitialInterval, retryMaxInterval, false, true, true, true, true, true, true, true, true, []string{}, []string{}, false, 0, 0, false, "", false, false)
+
+		if error := _deployment.UploadFiles(context.Background()); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed uploading rotated certificates")
+
+			os.Exit(-1)
+		}
+
+		log.Info("Uploaded rotated certificates and restarted affected servers")
+	},
+}
+
+func init() {
+	rotateCertificatesCmd.Flags().BoolVar(&rotateCA, "rotate-ca", false, "Also regenerate the CA itself and re-sign every certificate against it")
+	rotateCertificatesCmd.Flags().BoolVar(&rotateCertificatesRestartServers, "restart-servers", true, "Upload the rotated certificates to every node and restart the servers that changed")
+	rotateCertificatesCmd.Flags().StringVarP(&rotateCertificatesIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	RootCmd.AddCommand(rotateCertificatesCmd)
+}
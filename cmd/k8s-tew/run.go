@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"time"
 
 	"github.com/darxkies/k8s-tew/servers"
 	"github.com/darxkies/k8s-tew/utils"
@@ -11,6 +12,17 @@ import (
 )
 
 var killTimeout uint
+var startupWait uint
+var healthAddress string
+var vipStatusAddress string
+var logToFile bool
+var logDirectory string
+var maxLogSizeMegaBytes uint
+var readinessTimeout uint
+var commandTimeout uint
+var commandRetriesBudget uint
+var runProgressJSON bool
+var runProgressOutput string
 
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -35,13 +47,25 @@ var runCmd = &cobra.Command{
 			os.Exit(-1)
 		}
 
-		serversContainer := servers.NewServers(_config, killTimeout)
+		if len(logDirectory) == 0 {
+			logDirectory = _config.GetFullLocalAssetDirectory(utils.LOGGING_DIRECTORY)
+		}
+
+		serversContainer := servers.NewServers(_config, killTimeout, startupWait, healthAddress, vipStatusAddress, logToFile, logDirectory, maxLogSizeMegaBytes, readinessTimeout)
+
+		utils.SetProgressJSON(runProgressJSON)
+
+		if error := setupProgressOutput(runProgressOutput); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to open progress output file")
+
+			os.Exit(-1)
+		}
 
 		utils.SetProgressSteps(serversContainer.Steps())
 
 		utils.ShowProgress()
 
-		if error := serversContainer.Run(commandRetries); error != nil {
+		if error := serversContainer.Run(commandRetries, time.Duration(commandTimeout)*time.Second, time.Duration(commandRetriesBudget)*time.Second); error != nil {
 			log.WithFields(log.Fields{"error": error}).Error("Failed to run")
 
 			os.Exit(-1)
@@ -51,6 +75,17 @@ var runCmd = &cobra.Command{
 
 func init() {
 	runCmd.Flags().UintVarP(&commandRetries, "command-retries", "r", 300, "The count of command retries")
-	runCmd.Flags().UintVar(&killTimeout, "kill-timeout", 10, "Kill timeout for child processes")
+	runCmd.Flags().UintVar(&killTimeout, "kill-timeout", 10, "Grace period in seconds given to child processes to stop after SIGTERM before they get SIGKILLed")
+	runCmd.Flags().UintVar(&startupWait, "startup-wait", 2, "Seconds to wait between starting each server dependency tier (e.g. etcd/containerd before kube-apiserver/kubelet)")
+	runCmd.Flags().StringVar(&healthAddress, "health-address", "", "Listen address for the optional HTTP health endpoint, e.g. 127.0.0.1:8081 (empty disables it)")
+	runCmd.Flags().StringVar(&vipStatusAddress, "vip-status-address", "", "Listen address for the optional HTTP endpoint reporting VIP manager leadership/raft state, e.g. 127.0.0.1:8082 (empty disables it)")
+	runCmd.Flags().BoolVar(&logToFile, "log-to-file", true, "Log every server to its own file under --log-directory instead of the k8s-tew process' stdout/stderr")
+	runCmd.Flags().StringVar(&logDirectory, "log-directory", "", "Directory the per-server log files are written to (defaults to the logging asset directory)")
+	runCmd.Flags().UintVar(&maxLogSizeMegaBytes, "max-log-size", 100, "Maximum size in megabytes a server log file is allowed to grow to before it is rotated (0 disables rotation)")
+	runCmd.Flags().UintVar(&readinessTimeout, "readiness-timeout", 120, "Seconds to wait for every server with a readiness probe to become ready before running commands against them")
+	runCmd.Flags().UintVar(&commandTimeout, "command-timeout", utils.COMMAND_TIMEOUT, "Seconds a single bootstrap command attempt is allowed to run before being killed and counted as a failed attempt")
+	runCmd.Flags().UintVar(&commandRetriesBudget, "command-retries-budget", 0, "Total seconds across all attempts of a bootstrap command before giving up even if command-retries attempts remain (0 disables the budget, only command-retries bounds the retries)")
+	runCmd.Flags().BoolVar(&runProgressJSON, "progress-json", !utils.IsTerminal(), "Emit one JSON progress line per step on stdout instead of the interactive spinner, e.g. for parsing in CI logs")
+	runCmd.Flags().StringVar(&runProgressOutput, "progress-output", "", "File to write JSON progress lines to instead of stdout, only used together with --progress-json")
 	RootCmd.AddCommand(runCmd)
 }
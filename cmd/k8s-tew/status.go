@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var statusIdentityFile string
+var statusThresholdDays uint
+var statusJSON bool
+
+const statusSchemaVersion = 1
+
+// statusControlPlaneServers are the servers whose local process status status checks over SSH, since they are
+// not Kubernetes pods but systemd-supervised processes started directly by k8s-tew
+var statusControlPlaneServers = []string{"etcd", "kube-apiserver", "kube-scheduler", "kube-controller-manager"}
+
+// statusFeatureNamespaces maps each optional feature to the namespace its workloads are deployed into, so their
+// pod readiness can be reported per feature instead of per namespace
+var statusFeatureNamespaces = map[string]string{
+	utils.FEATURE_STORAGE:    "storage",
+	utils.FEATURE_MONITORING: "monitoring",
+	utils.FEATURE_LOGGING:    "logging",
+	utils.FEATURE_BACKUP:     "backup",
+	utils.FEATURE_SHOWCASE:   "showcase",
+	utils.FEATURE_INGRESS:    "networking",
+	utils.FEATURE_METALLB:    "networking",
+}
+
+// NodeStatus is the machine readable readiness of a single cluster node, as reported by status
+type NodeStatus struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// ServerStatus is the machine readable local process status of a single control-plane server on a single node
+type ServerStatus struct {
+	Node    string `json:"node"`
+	Server  string `json:"server"`
+	Running bool   `json:"running"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FeatureStatus is the machine readable pod readiness summary of a single enabled feature
+type FeatureStatus struct {
+	Feature   string `json:"feature"`
+	Namespace string `json:"namespace"`
+	ReadyPods int    `json:"ready-pods"`
+	TotalPods int    `json:"total-pods"`
+}
+
+// VirtualIPStatus is the machine readable leadership of a single configured virtual ip
+type VirtualIPStatus struct {
+	Role   string `json:"role"`
+	IP     string `json:"ip"`
+	Leader string `json:"leader,omitempty"`
+}
+
+// StatusReport is the machine readable result of status
+type StatusReport struct {
+	SchemaVersion      int                 `json:"schema-version"`
+	APIServerReachable bool                `json:"apiserver-reachable"`
+	APIServerError     string              `json:"apiserver-error,omitempty"`
+	Nodes              []NodeStatus        `json:"nodes,omitempty"`
+	Servers            []ServerStatus      `json:"servers"`
+	Features           []FeatureStatus     `json:"features,omitempty"`
+	VirtualIPs         []VirtualIPStatus   `json:"virtual-ips,omitempty"`
+	Certificates       []CertificateStatus `json:"certificates"`
+}
+
+// enabledFeatures returns the set of features the generated commands depend on, i.e. the features this cluster
+// actually has enabled
+func enabledFeatures() map[string]bool {
+	result := map[string]bool{}
+
+	for _, command := range _config.Config.Commands {
+		for _, feature := range command.Features {
+			result[feature] = true
+		}
+	}
+
+	return result
+}
+
+// collectNodeStatuses lists every cluster node through the admin kubeconfig and reports its readiness
+func collectNodeStatuses(clientset *kubernetes.Clientset) ([]NodeStatus, error) {
+	nodes, error := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if error != nil {
+		return nil, error
+	}
+
+	result := []NodeStatus{}
+
+	for _, node := range nodes.Items {
+		result = append(result, NodeStatus{Name: node.Name, Ready: deployment.IsNodeReady(node)})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// collectFeatureStatuses reports, for every enabled feature, how many of its pods are ready
+func collectFeatureStatuses(clientset *kubernetes.Clientset) ([]FeatureStatus, error) {
+	result := []FeatureStatus{}
+
+	for feature := range enabledFeatures() {
+		namespace, found := statusFeatureNamespaces[feature]
+		if !found {
+			continue
+		}
+
+		pods, error := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		if error != nil {
+			return nil, fmt.Errorf("could not list pods in namespace '%s' (%s)", namespace, error.Error())
+		}
+
+		ready := 0
+
+		for _, pod := range pods.Items {
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == "Ready" && condition.Status == "True" {
+					ready++
+
+					break
+				}
+			}
+		}
+
+		result = append(result, FeatureStatus{Feature: feature, Namespace: namespace, ReadyPods: ready, TotalPods: len(pods.Items)})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Feature < result[j].Feature })
+
+	return result, nil
+}
+
+// collectServerStatuses checks, over SSH, whether every control-plane server k8s-tew deployed to a node is still
+// running there, independently of whether the API server itself can be reached
+func collectServerStatuses() []ServerStatus {
+	result := []ServerStatus{}
+
+	for nodeName, node := range _config.Config.Nodes {
+		for _, serverConfig := range _config.Config.Servers {
+			if !serverConfig.Enabled {
+				continue
+			}
+
+			found := false
+
+			for _, name := range statusControlPlaneServers {
+				if serverConfig.Name == name {
+					found = true
+
+					break
+				}
+			}
+
+			if !found || !config.CompareLabels(node.Labels, serverConfig.Labels) {
+				continue
+			}
+
+			running, error := checkServerRunning(nodeName, node, serverConfig.Name)
+
+			status := ServerStatus{Node: nodeName, Server: serverConfig.Name, Running: running}
+
+			if error != nil {
+				status.Error = error.Error()
+			}
+
+			result = append(result, status)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Node != result[j].Node {
+			return result[i].Node < result[j].Node
+		}
+
+		return result[i].Server < result[j].Server
+	})
+
+	return result
+}
+
+// checkServerRunning checks, over SSH, whether a process named server is running on the given node
+func checkServerRunning(nodeName string, node *config.Node, server string) (bool, error) {
+	nodeDeployment := deployment.NewNodeDeployment(statusIdentityFile, nodeName, node, _config, false, false, false)
+	defer nodeDeployment.Close()
+
+	_, error := nodeDeployment.Execute(fmt.Sprintf("status-%s", server), fmt.Sprintf("pgrep -x %s > /dev/null", server))
+
+	return error == nil, error
+}
+
+// virtualIPLeader checks, over SSH, which of the eligible nodes currently has ip assigned to an interface, i.e.
+// which one is the current raft leader for that virtual ip. Returns "" if none of them do, or none were reachable
+func virtualIPLeader(ip string, nodeNames []string) string {
+	for _, nodeName := range nodeNames {
+		node, found := _config.Config.Nodes[nodeName]
+		if !found {
+			continue
+		}
+
+		nodeDeployment := deployment.NewNodeDeployment(statusIdentityFile, nodeName, node, _config, false, false, false)
+
+		output, error := nodeDeployment.Execute("status-vip", fmt.Sprintf("ip addr show | grep -q '%s' && echo yes || echo no", ip))
+
+		nodeDeployment.Close()
+
+		if error == nil && strings.TrimSpace(output) == "yes" {
+			return nodeName
+		}
+	}
+
+	return ""
+}
+
+// collectVirtualIPStatuses reports, for every configured controller/worker virtual ip, which node currently holds
+// it, over SSH, independently of whether the API server itself can be reached
+func collectVirtualIPStatuses() []VirtualIPStatus {
+	result := []VirtualIPStatus{}
+
+	roles := []struct {
+		role       string
+		virtualIPs []config.VirtualIP
+		eligible   func(*config.Node) bool
+	}{
+		{utils.NODE_CONTROLLER, _config.Config.ControllerVirtualIPs, func(node *config.Node) bool { return node.IsController() }},
+		{utils.NODE_WORKER, _config.Config.WorkerVirtualIPs, func(node *config.Node) bool { return node.IsWorker() }},
+	}
+
+	for _, role := range roles {
+		nodeNames := []string{}
+
+		for nodeName, node := range _config.Config.Nodes {
+			if role.eligible(node) {
+				nodeNames = append(nodeNames, nodeName)
+			}
+		}
+
+		sort.Strings(nodeNames)
+
+		for _, virtualIP := range role.virtualIPs {
+			result = append(result, VirtualIPStatus{Role: role.role, IP: virtualIP.IP, Leader: virtualIPLeader(virtualIP.IP, nodeNames)})
+		}
+	}
+
+	return result
+}
+
+// status aggregates cluster and component health into a single report, degrading gracefully - by skipping
+// everything that needs the API server - when it cannot be reached
+func status() (StatusReport, error) {
+	report := StatusReport{SchemaVersion: statusSchemaVersion}
+
+	report.Servers = collectServerStatuses()
+	report.VirtualIPs = collectVirtualIPStatuses()
+
+	certificates, error := collectCertificatesStatus(statusThresholdDays)
+	if error != nil {
+		return report, error
+	}
+
+	report.Certificates = certificates
+
+	clientset, error := deployment.GetClusterClientset(_config)
+	if error != nil {
+		report.APIServerError = error.Error()
+
+		return report, nil
+	}
+
+	nodes, error := collectNodeStatuses(clientset)
+	if error != nil {
+		report.APIServerError = error.Error()
+
+		return report, nil
+	}
+
+	features, error := collectFeatureStatuses(clientset)
+	if error != nil {
+		report.APIServerError = error.Error()
+
+		return report, nil
+	}
+
+	report.APIServerReachable = true
+	report.Nodes = nodes
+	report.Features = features
+
+	return report, nil
+}
+
+func printStatusReport(report StatusReport) {
+	if report.APIServerReachable {
+		log.Info("API server is reachable")
+	} else {
+		log.WithFields(log.Fields{"error": report.APIServerError}).Warn("API server is not reachable, only local checks are reported")
+	}
+
+	for _, node := range report.Nodes {
+		if node.Ready {
+			log.WithFields(log.Fields{"node": node.Name}).Info("Node is ready")
+		} else {
+			log.WithFields(log.Fields{"node": node.Name}).Warn("Node is not ready")
+		}
+	}
+
+	for _, server := range report.Servers {
+		fields := log.Fields{"node": server.Node, "server": server.Server}
+
+		if server.Running {
+			log.WithFields(fields).Info("Server is running")
+		} else {
+			fields["error"] = server.Error
+
+			log.WithFields(fields).Warn("Server is not running")
+		}
+	}
+
+	for _, feature := range report.Features {
+		log.WithFields(log.Fields{"feature": feature.Feature, "namespace": feature.Namespace, "ready-pods": feature.ReadyPods, "total-pods": feature.TotalPods}).Info("Feature pod status")
+	}
+
+	for _, virtualIP := range report.VirtualIPs {
+		if len(virtualIP.Leader) > 0 {
+			log.WithFields(log.Fields{"role": virtualIP.Role, "ip": virtualIP.IP, "leader": virtualIP.Leader}).Info("Virtual ip leader")
+		} else {
+			log.WithFields(log.Fields{"role": virtualIP.Role, "ip": virtualIP.IP}).Warn("Virtual ip has no reachable leader")
+		}
+	}
+
+	for _, certificate := range report.Certificates {
+		fields := log.Fields{"filename": certificate.Filename, "days-remaining": certificate.DaysRemaining}
+
+		if certificate.Expired {
+			log.WithFields(fields).Error("Certificate expired")
+		} else if certificate.ExpiringSoon {
+			log.WithFields(fields).Warn("Certificate expiring soon")
+		}
+	}
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report cluster and component health",
+	Long:  "Reports node readiness, control-plane component health, the current virtual ip leaders, enabled features' pod status and certificate expiry warnings. Local server process status, over SSH, and certificate expiry are reported even when the API server cannot be reached",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		utils.SetProgressSteps(1)
+
+		report, error := status()
+		if error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to collect status")
+
+			os.Exit(-1)
+		}
+
+		if statusJSON {
+			content, error := json.MarshalIndent(report, "", "  ")
+			if error != nil {
+				log.WithFields(log.Fields{"error": error}).Error("Failed to marshal status")
+
+				os.Exit(-1)
+			}
+
+			fmt.Println(string(content))
+
+			return
+		}
+
+		printStatusReport(report)
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	statusCmd.Flags().UintVar(&statusThresholdDays, "threshold-days", 30, "Report a certificate as expiring soon if it expires within this many days")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output the report as JSON instead of logging each entry")
+	RootCmd.AddCommand(statusCmd)
+}
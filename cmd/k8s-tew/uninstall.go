@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var uninstallConfirm bool
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove k8s-tew from a remote cluster",
+	Long:  "Stops and disables the k8s-tew service, cleans up the CNI's configuration and network interfaces, unmounts anything left under the containerd/kubelet data directories and deletes k8s-tew's files on each selected node",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !uninstallConfirm {
+			log.Error("Refusing to uninstall without --confirm")
+
+			os.Exit(-1)
+		}
+
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		_uninstallation := deployment.NewUninstallation(_config, identityFile, skipNodes, onlyNodes)
+
+		utils.SetProgressJSON(progressJSON)
+
+		if error := setupProgressOutput(progressOutput); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to open progress output file")
+
+			os.Exit(-1)
+		}
+
+		utils.SetProgressSteps(_uninstallation.Steps())
+
+		utils.ShowProgress()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+
+			log.Info("Cancelling uninstall...")
+
+			cancel()
+		}()
+
+		if error := _uninstallation.Uninstall(ctx); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed uninstalling")
+
+			os.Exit(-2)
+		}
+
+		utils.HideProgress()
+
+		log.Info("Done")
+	},
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallConfirm, "confirm", false, "Confirm that k8s-tew should be removed from the selected nodes; required, the command refuses to run without it")
+	uninstallCmd.Flags().StringVarP(&identityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	uninstallCmd.Flags().StringSliceVar(&skipNodes, "skip-nodes", []string{}, "Exclude nodes matching these names or labels from the uninstall")
+	uninstallCmd.Flags().StringSliceVar(&onlyNodes, "only-nodes", []string{}, "Restrict the uninstall to nodes matching these names or labels")
+	uninstallCmd.Flags().BoolVar(&progressJSON, "progress-json", !utils.IsTerminal(), "Emit one JSON progress line per step on stdout instead of the interactive spinner, e.g. for parsing in CI logs")
+	uninstallCmd.Flags().StringVar(&progressOutput, "progress-output", "", "File to write JSON progress lines to instead of stdout, only used together with --progress-json")
+	RootCmd.AddCommand(uninstallCmd)
+}
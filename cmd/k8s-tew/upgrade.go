@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/download"
+	"github.com/darxkies/k8s-tew/generate"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var upgradeK8SVersion string
+var upgradeIdentityFile string
+var upgradeReadyTimeout uint
+
+// upgradeBatch uploads the new binaries to nodeNames, restarting the servers that changed, and then waits for
+// all of them to report Ready again before the next batch starts
+func upgradeBatch(ctx context.Context, label string, nodeNames []string) error {
+	if len(nodeNames) == 0 {
+		return nil
+	}
+
+	log.WithFields(log.Fields{"nodes": nodeNames}).Infof("Upgrading %s", label)
+
+	_deployment := deployment.NewDeployment(_config, upgradeIdentityFile, false, true, parallel, 0, commandRetries, retryInitialInterval, retryMaxInterval, false, true, true, true, true, true, true, true, true, []string{}, nodeNames, false, 0, 0, false, "", false, false)
+
+	if error := _deployment.UploadFiles(ctx); error != nil {
+		return fmt.Errorf("could not upgrade %s (%s)", label, error.Error())
+	}
+
+	if error := deployment.WaitForNodes(_config, nodeNames, time.Duration(upgradeReadyTimeout)*time.Second); error != nil {
+		return fmt.Errorf("%s did not become healthy after upgrading (%s)", label, error.Error())
+	}
+
+	log.Infof("%s are healthy", label)
+
+	return nil
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrades the cluster's Kubernetes version in place",
+	Long:  "Downloads the Kubernetes binaries for --k8s-version, regenerates the assets and uploads them node by node - controllers first, then workers - restarting the affected servers and waiting for each node to report Ready before moving on. The control plane's VIP keeps the API server reachable throughout the controller rollout. Aborts and reports as soon as any node fails its post-restart health check, leaving the remaining nodes untouched",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		if len(upgradeK8SVersion) == 0 {
+			log.Error("--k8s-version is required")
+
+			os.Exit(-1)
+		}
+
+		_config.Config.Versions.K8S = upgradeK8SVersion
+
+		if error := _config.Config.Versions.Validate(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Invalid version")
+
+			os.Exit(-1)
+		}
+
+		if error := _config.Save(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed saving config")
+
+			os.Exit(-1)
+		}
+
+		downloader := download.NewDownloader(_config, false, parallel, false)
+
+		if error := downloader.DownloadBinaries(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed downloading binaries")
+
+			os.Exit(-1)
+		}
+
+		if error := generate.NewGenerator(_config).GenerateFiles(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed generating files")
+
+			os.Exit(-1)
+		}
+
+		controllers := []string{}
+		workers := []string{}
+
+		for _, nodeName := range _config.GetSortedNodeKeys() {
+			node := _config.Config.Nodes[nodeName]
+
+			if node.IsController() {
+				controllers = append(controllers, nodeName)
+			} else if node.IsWorker() {
+				workers = append(workers, nodeName)
+			}
+		}
+
+		ctx := context.Background()
+
+		if error := upgradeBatch(ctx, "controllers", controllers); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Upgrade failed")
+
+			os.Exit(-2)
+		}
+
+		if error := upgradeBatch(ctx, "workers", workers); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Upgrade failed")
+
+			os.Exit(-2)
+		}
+
+		log.WithFields(log.Fields{"k8s-version": upgradeK8SVersion}).Info("Upgrade done")
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().StringVar(&upgradeK8SVersion, "k8s-version", "", "Target Kubernetes version to upgrade the cluster to")
+	upgradeCmd.Flags().StringVarP(&upgradeIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	upgradeCmd.Flags().UintVar(&upgradeReadyTimeout, "ready-timeout", 300, "Seconds to wait for a batch of nodes to become Ready again after being upgraded")
+	upgradeCmd.Flags().BoolVar(&parallel, "parallel", false, "Upload to the nodes of a batch in parallel")
+	upgradeCmd.Flags().UintVarP(&commandRetries, "command-retries", "r", 300, "The count of command retries during the setup")
+	upgradeCmd.Flags().UintVar(&retryInitialInterval, "retry-initial-interval", utils.RETRY_INITIAL_INTERVAL_SECONDS, "Initial delay in seconds between command retries, doubled after each failure")
+	upgradeCmd.Flags().UintVar(&retryMaxInterval, "retry-max-interval", utils.RETRY_MAX_INTERVAL_SECONDS, "Maximum delay in seconds between command retries")
+	RootCmd.AddCommand(upgradeCmd)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/deployment"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var verifyAPIServerIdentityFile string
+
+// getAPIServerConfig returns the generated kube-apiserver server configuration
+func getAPIServerConfig() (config.ServerConfig, error) {
+	for _, serverConfig := range _config.Config.Servers {
+		if serverConfig.Name == "kube-apiserver" {
+			return serverConfig, nil
+		}
+	}
+
+	return config.ServerConfig{}, fmt.Errorf("'kube-apiserver' not found in the generated servers")
+}
+
+// getExpectedAPIServerFlags renders the kube-apiserver arguments for the given node the same way the server would on start
+func getExpectedAPIServerFlags(nodeName string, node *config.Node, serverConfig config.ServerConfig) (map[string]string, error) {
+	nodeConfig := *_config
+	nodeConfig.Name = nodeName
+	nodeConfig.Node = node
+
+	flags := map[string]string{}
+
+	for key, value := range serverConfig.Arguments {
+		if len(value) == 0 {
+			flags[key] = ""
+
+			continue
+		}
+
+		renderedValue, error := nodeConfig.ApplyTemplate(fmt.Sprintf("kube-apiserver.%s", key), value)
+		if error != nil {
+			return nil, error
+		}
+
+		flags[key] = renderedValue
+	}
+
+	return flags, nil
+}
+
+// getActualAPIServerFlags fetches the effective command line arguments of the running kube-apiserver process over SSH
+func getActualAPIServerFlags(nodeName string, node *config.Node) (map[string]string, error) {
+	nodeDeployment := deployment.NewNodeDeployment(verifyAPIServerIdentityFile, nodeName, node, _config, false, false, false)
+	defer nodeDeployment.Close()
+
+	output, error := nodeDeployment.Execute("get-apiserver-cmdline", "tr '\\0' '\\n' < /proc/$(pgrep -x kube-apiserver | head -n 1)/cmdline")
+	if error != nil {
+		return nil, fmt.Errorf("could not fetch kube-apiserver command line on '%s' (%s)", nodeName, error.Error())
+	}
+
+	flags := map[string]string{}
+
+	for _, argument := range strings.Split(output, "\n") {
+		argument = strings.TrimSpace(argument)
+
+		if !strings.HasPrefix(argument, "--") {
+			continue
+		}
+
+		argument = strings.TrimPrefix(argument, "--")
+
+		tokens := strings.SplitN(argument, "=", 2)
+
+		if len(tokens) == 2 {
+			flags[tokens[0]] = tokens[1]
+		} else {
+			flags[tokens[0]] = ""
+		}
+	}
+
+	return flags, nil
+}
+
+// verifyAPIServer compares the running kube-apiserver flags on every controller against what k8s-tew would generate
+func verifyAPIServer() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	utils.SetProgressSteps(1)
+
+	serverConfig, error := getAPIServerConfig()
+	if error != nil {
+		return error
+	}
+
+	mismatches := 0
+
+	for nodeName, node := range _config.Config.Nodes {
+		if !node.IsController() {
+			continue
+		}
+
+		expectedFlags, error := getExpectedAPIServerFlags(nodeName, node, serverConfig)
+		if error != nil {
+			return error
+		}
+
+		actualFlags, error := getActualAPIServerFlags(nodeName, node)
+		if error != nil {
+			return error
+		}
+
+		for flag, expectedValue := range expectedFlags {
+			actualValue, found := actualFlags[flag]
+
+			if !found {
+				mismatches++
+
+				log.WithFields(log.Fields{"node": nodeName, "flag": flag, "expected": expectedValue}).Warn("Flag is missing on the running kube-apiserver")
+
+				continue
+			}
+
+			if actualValue != expectedValue {
+				mismatches++
+
+				log.WithFields(log.Fields{"node": nodeName, "flag": flag, "expected": expectedValue, "actual": actualValue}).Warn("Flag does not match the generated configuration")
+			}
+		}
+
+		for flag, actualValue := range actualFlags {
+			if _, found := expectedFlags[flag]; found {
+				continue
+			}
+
+			mismatches++
+
+			log.WithFields(log.Fields{"node": nodeName, "flag": flag, "actual": actualValue}).Warn("Unexpected flag found on the running kube-apiserver")
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("found %d mismatching kube-apiserver flag(s)", mismatches)
+	}
+
+	log.Info("All controllers' kube-apiserver flags match the generated configuration")
+
+	return nil
+}
+
+var verifyAPIServerCmd = &cobra.Command{
+	Use:   "verify-apiserver",
+	Short: "Compare the running kube-apiserver flags against the generated configuration",
+	Long:  "Fetches the effective kube-apiserver command line from every controller over SSH and diffs it against what k8s-tew would generate from the current configuration, to detect manual tampering or configuration drift",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := verifyAPIServer(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed to verify kube-apiserver")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	verifyAPIServerCmd.Flags().StringVarP(&verifyAPIServerIdentityFile, "identity-file", "i", path.Join(os.Getenv("HOME"), ".ssh/id_rsa"), "SSH identity file")
+	RootCmd.AddCommand(verifyAPIServerCmd)
+}
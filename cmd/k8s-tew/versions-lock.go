@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/darxkies/k8s-tew/config"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var versionsLockFilename string
+
+// exportVersionsLock resolves the effective versions - compile-time defaults merged with config overrides - and
+// writes them to versionsLockFilename as YAML, so they can be committed and re-applied later with deploy's
+// --versions-lock flag for reproducible deploys
+func exportVersionsLock() error {
+	if error := bootstrap(false); error != nil {
+		return error
+	}
+
+	yamlOutput, error := yaml.Marshal(_config.Config.Versions)
+	if error != nil {
+		return error
+	}
+
+	return ioutil.WriteFile(versionsLockFilename, yamlOutput, 0644)
+}
+
+// loadVersionsLock reads a YAML lockfile created by versions-lock and unmarshals it onto versions, overriding
+// every version it sets so a deploy pins exactly those versions instead of whatever the config currently resolves
+func loadVersionsLock(filename string, versions *config.Versions) error {
+	yamlContent, error := ioutil.ReadFile(filename)
+	if error != nil {
+		return fmt.Errorf("could not read '%s' (%s)", filename, error.Error())
+	}
+
+	if error := yaml.Unmarshal(yamlContent, versions); error != nil {
+		return fmt.Errorf("could not parse '%s' (%s)", filename, error.Error())
+	}
+
+	return versions.Validate()
+}
+
+var versionsLockCmd = &cobra.Command{
+	Use:   "versions-lock",
+	Short: "Exports the resolved versions to a lockfile",
+	Long:  "Resolves all effective versions - compile-time defaults merged with config overrides - and writes them to a YAML lockfile that can be committed and re-applied with deploy's --versions-lock flag for reproducible deploys and deterministic rollbacks",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := exportVersionsLock(); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Versions lock export failed")
+
+			os.Exit(-1)
+		}
+
+		log.WithFields(log.Fields{"filename": versionsLockFilename}).Info("Versions lock exported")
+	},
+}
+
+func init() {
+	versionsLockCmd.Flags().StringVarP(&versionsLockFilename, "filename", "f", "k8s-tew-versions-lock.yaml", "Versions lockfile filename")
+	RootCmd.AddCommand(versionsLockCmd)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/darxkies/k8s-tew/deployment"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var waitForClusterTimeout uint
+
+var waitForClusterCmd = &cobra.Command{
+	Use:   "wait-for-cluster",
+	Short: "Waits until the cluster is ready",
+	Long:  "Polls the API server, through the admin kubeconfig, until every node is Ready and CoreDNS/the configured CNI are up, or the timeout elapses. Useful for CI pipelines that deploy and then run tests against the cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		if error := bootstrap(false); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Failed initializing")
+
+			os.Exit(-1)
+		}
+
+		if error := deployment.WaitForCluster(_config, time.Duration(waitForClusterTimeout)*time.Second); error != nil {
+			log.WithFields(log.Fields{"error": error}).Error("Cluster not ready")
+
+			os.Exit(-1)
+		}
+	},
+}
+
+func init() {
+	waitForClusterCmd.Flags().UintVar(&waitForClusterTimeout, "timeout", 300, "Seconds to wait for the cluster to become ready before giving up")
+	RootCmd.AddCommand(waitForClusterCmd)
+}
@@ -0,0 +1,123 @@
+package config
+
+import "fmt"
+
+// AlertmanagerSecretRef points at a key within a Kubernetes secret that already exists in the monitoring
+// namespace, so sensitive values - Slack webhook URLs, SMTP passwords, PagerDuty service keys - never have
+// to be inlined into the k8s-tew config. The secret is mounted into Alertmanager by name and referenced from
+// its config by file path, the same mechanism the Alertmanager CRD's spec.secrets uses
+type AlertmanagerSecretRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// Validate makes sure both name and key are set
+func (secretRef AlertmanagerSecretRef) Validate() error {
+	if len(secretRef.Name) == 0 {
+		return fmt.Errorf("secret reference is missing a name")
+	}
+
+	if len(secretRef.Key) == 0 {
+		return fmt.Errorf("secret reference is missing a key")
+	}
+
+	return nil
+}
+
+// AlertmanagerReceiver defines one Alertmanager receiver. A receiver may combine a Slack channel, an email
+// address and a PagerDuty integration, each sourcing its secret from a AlertmanagerSecretRef
+type AlertmanagerReceiver struct {
+	Name                         string                 `yaml:"name"`
+	SlackChannel                 string                 `yaml:"slack-channel,omitempty"`
+	SlackWebhookSecretRef        *AlertmanagerSecretRef `yaml:"slack-webhook-secret-ref,omitempty"`
+	EmailTo                      string                 `yaml:"email-to,omitempty"`
+	EmailPasswordSecretRef       *AlertmanagerSecretRef `yaml:"email-password-secret-ref,omitempty"`
+	PagerDutyServiceKeySecretRef *AlertmanagerSecretRef `yaml:"pagerduty-service-key-secret-ref,omitempty"`
+}
+
+// Validate makes sure the receiver has a name, at least one receiver type configured and every secret
+// reference it uses is valid
+func (receiver AlertmanagerReceiver) Validate() error {
+	if len(receiver.Name) == 0 {
+		return fmt.Errorf("alertmanager receiver is missing a name")
+	}
+
+	if len(receiver.SlackChannel) == 0 && len(receiver.EmailTo) == 0 && receiver.PagerDutyServiceKeySecretRef == nil {
+		return fmt.Errorf("alertmanager receiver '%s' has no slack-channel, email-to or pagerduty-service-key-secret-ref configured", receiver.Name)
+	}
+
+	if len(receiver.SlackChannel) > 0 {
+		if receiver.SlackWebhookSecretRef == nil {
+			return fmt.Errorf("alertmanager receiver '%s' has a slack-channel but no slack-webhook-secret-ref", receiver.Name)
+		}
+
+		if error := receiver.SlackWebhookSecretRef.Validate(); error != nil {
+			return fmt.Errorf("alertmanager receiver '%s' has an invalid slack-webhook-secret-ref (%s)", receiver.Name, error.Error())
+		}
+	}
+
+	if len(receiver.EmailTo) > 0 && receiver.EmailPasswordSecretRef != nil {
+		if error := receiver.EmailPasswordSecretRef.Validate(); error != nil {
+			return fmt.Errorf("alertmanager receiver '%s' has an invalid email-password-secret-ref (%s)", receiver.Name, error.Error())
+		}
+	}
+
+	if receiver.PagerDutyServiceKeySecretRef != nil {
+		if error := receiver.PagerDutyServiceKeySecretRef.Validate(); error != nil {
+			return fmt.Errorf("alertmanager receiver '%s' has an invalid pagerduty-service-key-secret-ref (%s)", receiver.Name, error.Error())
+		}
+	}
+
+	return nil
+}
+
+// AlertmanagerRoute routes alerts matching every label in match to receiver, in addition to the default
+// catch-all route k8s-tew always configures
+type AlertmanagerRoute struct {
+	Receiver string            `yaml:"receiver"`
+	Match    map[string]string `yaml:"match,omitempty"`
+	Continue bool              `yaml:"continue,omitempty"`
+}
+
+// Validate makes sure the route has a receiver and at least one match label
+func (route AlertmanagerRoute) Validate() error {
+	if len(route.Receiver) == 0 {
+		return fmt.Errorf("alertmanager route is missing a receiver")
+	}
+
+	if len(route.Match) == 0 {
+		return fmt.Errorf("alertmanager route for receiver '%s' has no match labels", route.Receiver)
+	}
+
+	return nil
+}
+
+// ValidateAlertmanagerReceiversAndRoutes makes sure every receiver and route validates on its own, that no
+// two receivers share a name and that every route's receiver refers to a configured one
+func ValidateAlertmanagerReceiversAndRoutes(receivers []AlertmanagerReceiver, routes []AlertmanagerRoute) error {
+	seen := map[string]bool{}
+
+	for _, receiver := range receivers {
+		if error := receiver.Validate(); error != nil {
+			return error
+		}
+
+		if seen[receiver.Name] {
+			return fmt.Errorf("duplicate alertmanager receiver '%s'", receiver.Name)
+		}
+
+		seen[receiver.Name] = true
+	}
+
+	for _, route := range routes {
+		if error := route.Validate(); error != nil {
+			return error
+		}
+
+		if !seen[route.Receiver] {
+			return fmt.Errorf("alertmanager route refers to unknown receiver '%s'", route.Receiver)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// ValidateAPIServerExtraIPs makes sure every configured apiserver-extra-ip, which is appended as-is to the
+// kubernetes and aggregator certificates' SAN list, is actually a parseable IP address
+func ValidateAPIServerExtraIPs(ips []string) error {
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid apiserver-extra-ip '%s'", ip)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package config
+
+// defaultAuditPolicy audits only the metadata of every request - who did what to which resource, when -
+// without request/response bodies, which is verbose enough for most compliance needs while keeping the
+// audit log small enough to be on by default
+const defaultAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+  - level: Metadata
+`
+
+// AuditConfig controls kube-apiserver's audit logging. Policy is the audit policy YAML applied to every
+// request; MaxAge, MaxBackup and MaxSize bound how long, how many and how large the rotated AUDIT_LOG files
+// are allowed to grow before being discarded
+type AuditConfig struct {
+	Policy    string `yaml:"policy,omitempty"`
+	MaxAge    uint   `yaml:"max-age,omitempty"`
+	MaxBackup uint   `yaml:"max-backup,omitempty"`
+	MaxSize   uint   `yaml:"max-size,omitempty"`
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// BackupSchedule defines one Velero backup schedule rendered as a Schedule custom resource
+type BackupSchedule struct {
+	Name               string   `yaml:"name"`
+	Schedule           string   `yaml:"schedule"`
+	IncludedNamespaces []string `yaml:"included-namespaces,omitempty"`
+	ExcludedNamespaces []string `yaml:"excluded-namespaces,omitempty"`
+	TTL                string   `yaml:"ttl,omitempty"`
+}
+
+var cronFieldExpression = regexp.MustCompile(`^[0-9*/,-]+$`)
+
+// validateCronExpression makes sure expression has the 5 whitespace separated fields (minute hour day month weekday)
+// a standard cron expression requires, each containing only digits, '*', '/', ',' and '-'
+func validateCronExpression(expression string) error {
+	fields := regexp.MustCompile(`\s+`).Split(expression, -1)
+
+	if len(fields) != 5 {
+		return fmt.Errorf("expected 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	for _, field := range fields {
+		if !cronFieldExpression.MatchString(field) {
+			return fmt.Errorf("invalid field '%s'", field)
+		}
+	}
+
+	return nil
+}
+
+// Validate makes sure the schedule has a name, a valid cron expression and, if set, a valid Go duration for the TTL
+func (backupSchedule BackupSchedule) Validate() error {
+	if len(backupSchedule.Name) == 0 {
+		return fmt.Errorf("backup schedule is missing a name")
+	}
+
+	if error := validateCronExpression(backupSchedule.Schedule); error != nil {
+		return fmt.Errorf("backup schedule '%s' has an invalid cron expression '%s' (%s)", backupSchedule.Name, backupSchedule.Schedule, error.Error())
+	}
+
+	if len(backupSchedule.TTL) > 0 {
+		if _, error := time.ParseDuration(backupSchedule.TTL); error != nil {
+			return fmt.Errorf("backup schedule '%s' has an invalid ttl '%s' (%s)", backupSchedule.Name, backupSchedule.TTL, error.Error())
+		}
+	}
+
+	return nil
+}
+
+// ValidateBackupSchedules makes sure every schedule validates on its own and that no two schedules share a
+// name, since they are rendered into Schedule custom resources named after it
+func ValidateBackupSchedules(backupSchedules []BackupSchedule) error {
+	seen := map[string]bool{}
+
+	for _, backupSchedule := range backupSchedules {
+		if error := backupSchedule.Validate(); error != nil {
+			return error
+		}
+
+		if seen[backupSchedule.Name] {
+			return fmt.Errorf("duplicate backup schedule '%s'", backupSchedule.Name)
+		}
+
+		seen[backupSchedule.Name] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import "fmt"
+
+// CephPoolConfig overrides the replication size, minimum size and placement group count of one of k8s-tew's
+// Ceph pools ("rbd", "cephfs-data", "cephfs-metadata")
+type CephPoolConfig struct {
+	Size    uint `yaml:"size,omitempty"`
+	MinSize uint `yaml:"min-size,omitempty"`
+	PGCount uint `yaml:"pg-count,omitempty"`
+}
+
+var builtinCephPools = map[string]CephPoolConfig{
+	"rbd":             {Size: 3, MinSize: 2, PGCount: 256},
+	"cephfs-data":     {Size: 3, MinSize: 2, PGCount: 128},
+	"cephfs-metadata": {Size: 3, MinSize: 2, PGCount: 128},
+}
+
+// isPowerOfTwo reports whether value is a power of two, the placement group count Ceph requires
+func isPowerOfTwo(value uint) bool {
+	return value > 0 && value&(value-1) == 0
+}
+
+// ValidateCephPools makes sure every override targets a known pool, has a size of at least min-size and a
+// pg-count that is a power of two
+func ValidateCephPools(cephPools map[string]CephPoolConfig) error {
+	for identifier, cephPool := range cephPools {
+		builtinCephPool, ok := builtinCephPools[identifier]
+		if !ok {
+			return fmt.Errorf("unknown ceph pool '%s', expected one of rbd, cephfs-data, cephfs-metadata", identifier)
+		}
+
+		size := builtinCephPool.Size
+		if cephPool.Size > 0 {
+			size = cephPool.Size
+		}
+
+		minSize := builtinCephPool.MinSize
+		if cephPool.MinSize > 0 {
+			minSize = cephPool.MinSize
+		}
+
+		if size < minSize {
+			return fmt.Errorf("ceph pool '%s' has size %d smaller than min-size %d", identifier, size, minSize)
+		}
+
+		if cephPool.PGCount > 0 && !isPowerOfTwo(cephPool.PGCount) {
+			return fmt.Errorf("ceph pool '%s' has pg-count %d which is not a power of two", identifier, cephPool.PGCount)
+		}
+	}
+
+	return nil
+}
+
+// GetCephPool merges the configured override for identifier, if any, with k8s-tew's built-in defaults
+func (config *InternalConfig) GetCephPool(identifier string) CephPoolConfig {
+	cephPool := builtinCephPools[identifier]
+
+	override, ok := config.Config.CephPools[identifier]
+	if !ok {
+		return cephPool
+	}
+
+	if override.Size > 0 {
+		cephPool.Size = override.Size
+	}
+
+	if override.MinSize > 0 {
+		cephPool.MinSize = override.MinSize
+	}
+
+	if override.PGCount > 0 {
+		cephPool.PGCount = override.PGCount
+	}
+
+	return cephPool
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateCertificateAlgorithm makes sure algorithm selects one of the key algorithms k8s-tew knows how to
+// generate, and that curve names a supported curve when algorithm is ecdsa. The CA and every leaf certificate in
+// a cluster share the same algorithm, so there is nothing further to reconcile across certificates
+func ValidateCertificateAlgorithm(algorithm, curve string) error {
+	if !utils.SupportedCertificateAlgorithms[algorithm] {
+		return fmt.Errorf("unsupported certificate algorithm '%s', expected one of '%s', '%s'", algorithm, utils.CERTIFICATE_ALGORITHM_RSA, utils.CERTIFICATE_ALGORITHM_ECDSA)
+	}
+
+	if algorithm != utils.CERTIFICATE_ALGORITHM_ECDSA {
+		return nil
+	}
+
+	if !utils.SupportedECDSACurves[curve] {
+		return fmt.Errorf("unsupported ecdsa curve '%s', expected one of '%s', '%s', '%s', '%s'", curve, utils.ECDSA_CURVE_P224, utils.ECDSA_CURVE_P256, utils.ECDSA_CURVE_P384, utils.ECDSA_CURVE_P521)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var clusterNameExpression = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateClusterName makes sure the cluster name is a valid DNS label, since it ends up in kubeconfig
+// cluster/context names as well as resource names derived from it
+func ValidateClusterName(clusterName string) error {
+	if len(clusterName) == 0 {
+		return fmt.Errorf("cluster name is required")
+	}
+
+	if !clusterNameExpression.MatchString(clusterName) {
+		return fmt.Errorf("cluster name '%s' has to be a valid DNS label (lowercase alphanumeric characters or '-', and must start and end with an alphanumeric character)", clusterName)
+	}
+
+	return nil
+}
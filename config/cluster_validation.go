@@ -0,0 +1,401 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+var validFeatures = map[string]bool{
+	utils.FEATURE_STORAGE:    true,
+	utils.FEATURE_MONITORING: true,
+	utils.FEATURE_LOGGING:    true,
+	utils.FEATURE_BACKUP:     true,
+	utils.FEATURE_SHOWCASE:   true,
+	utils.FEATURE_INGRESS:    true,
+	utils.FEATURE_PACKAGING:  true,
+	utils.FEATURE_METALLB:    true,
+}
+
+// Validate walks Nodes, Servers and Commands and collects every problem it can find instead of stopping at the
+// first one, so a malformed cluster definition can be fixed in a single pass instead of being rediscovered one
+// error at a time across repeated deploy attempts
+func (config *InternalConfig) Validate() error {
+	problems := []string{}
+
+	problems = append(problems, config.validateNodeDefinitions()...)
+	problems = append(problems, config.validateClusterNetworking()...)
+	problems = append(problems, config.validateClusterSizing()...)
+	problems = append(problems, config.validateVirtualIPs()...)
+	problems = append(problems, config.validateMetalLBAddressPool()...)
+	problems = append(problems, config.validateCommandFeatures()...)
+	problems = append(problems, config.validateCommandDependencies()...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid cluster definition:\n- %s", strings.Join(problems, "\n- "))
+}
+
+func (config *InternalConfig) validateNodeDefinitions() []string {
+	problems := []string{}
+
+	nodeNameForIP := map[string]string{}
+	hasController := false
+	hasWorker := false
+
+	for nodeName, node := range config.Config.Nodes {
+		if otherNodeName, ok := nodeNameForIP[node.IP]; ok {
+			problems = append(problems, fmt.Sprintf("nodes '%s' and '%s' both use ip '%s'", otherNodeName, nodeName, node.IP))
+		} else {
+			nodeNameForIP[node.IP] = nodeName
+		}
+
+		if len(node.Labels) == 0 {
+			problems = append(problems, fmt.Sprintf("node '%s' has no labels, expected at least one of %s, %s, %s", nodeName, utils.NODE_BOOTSTRAPPER, utils.NODE_CONTROLLER, utils.NODE_WORKER))
+		}
+
+		hasController = hasController || node.IsController()
+		hasWorker = hasWorker || node.IsWorker()
+	}
+
+	if hasController && !hasWorker {
+		problems = append(problems, "cluster has controller nodes but no worker node so no workloads could be scheduled")
+	}
+
+	return problems
+}
+
+// parseCIDRList parses value as a comma-separated list of CIDRs, which is how dual-stack cluster-ip-range and
+// cluster-cidr values are configured. Entries that fail to parse are reported as errors instead of being silently
+// dropped
+func parseCIDRList(value string) ([]*net.IPNet, []error) {
+	networks := []*net.IPNet{}
+	errors := []error{}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+
+		if len(part) == 0 {
+			continue
+		}
+
+		_, network, error := net.ParseCIDR(part)
+		if error != nil {
+			errors = append(errors, fmt.Errorf("'%s': %s", part, error.Error()))
+
+			continue
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, errors
+}
+
+// networkFamily returns "IPv4" or "IPv6" depending on network's address family
+func networkFamily(network *net.IPNet) string {
+	if network.IP.To4() == nil {
+		return "IPv6"
+	}
+
+	return "IPv4"
+}
+
+// validateClusterNetworking validates cluster-ip-range and cluster-cidr - each either a single CIDR or a
+// comma-separated dual-stack pair - rejects a field configuring the same address family twice or the two fields
+// disagreeing on which families they cover, checks for overlaps between every range/cidr pair, and validates that
+// cluster-dns-ip and calico-typha-ip fall within cluster-ip-range for their address family
+func (config *InternalConfig) validateClusterNetworking() []string {
+	problems := []string{}
+
+	clusterIPRanges, rangeErrors := parseCIDRList(config.Config.ClusterIPRange)
+	for _, error := range rangeErrors {
+		problems = append(problems, fmt.Sprintf("invalid cluster-ip-range %s", error.Error()))
+	}
+
+	clusterCIDRs, cidrErrors := parseCIDRList(config.Config.ClusterCIDR)
+	for _, error := range cidrErrors {
+		problems = append(problems, fmt.Sprintf("invalid cluster-cidr %s", error.Error()))
+	}
+
+	rangeFamilies := map[string]bool{}
+
+	for _, network := range clusterIPRanges {
+		family := networkFamily(network)
+
+		if rangeFamilies[family] {
+			problems = append(problems, fmt.Sprintf("cluster-ip-range '%s' configures more than one %s cidr", config.Config.ClusterIPRange, family))
+		}
+
+		rangeFamilies[family] = true
+	}
+
+	cidrFamilies := map[string]bool{}
+
+	for _, network := range clusterCIDRs {
+		family := networkFamily(network)
+
+		if cidrFamilies[family] {
+			problems = append(problems, fmt.Sprintf("cluster-cidr '%s' configures more than one %s cidr", config.Config.ClusterCIDR, family))
+		}
+
+		cidrFamilies[family] = true
+	}
+
+	for family := range rangeFamilies {
+		if !cidrFamilies[family] {
+			problems = append(problems, fmt.Sprintf("cluster-ip-range '%s' configures %s but cluster-cidr '%s' does not", config.Config.ClusterIPRange, family, config.Config.ClusterCIDR))
+		}
+	}
+
+	for family := range cidrFamilies {
+		if !rangeFamilies[family] {
+			problems = append(problems, fmt.Sprintf("cluster-cidr '%s' configures %s but cluster-ip-range '%s' does not", config.Config.ClusterCIDR, family, config.Config.ClusterIPRange))
+		}
+	}
+
+	for _, clusterIPRange := range clusterIPRanges {
+		for _, clusterCIDR := range clusterCIDRs {
+			if clusterIPRange.Contains(clusterCIDR.IP) || clusterCIDR.Contains(clusterIPRange.IP) {
+				problems = append(problems, fmt.Sprintf("cluster-ip-range '%s' and cluster-cidr '%s' overlap", config.Config.ClusterIPRange, config.Config.ClusterCIDR))
+			}
+		}
+	}
+
+	problems = append(problems, config.validateServiceIP("cluster-dns-ip", config.Config.ClusterDNSIP, clusterIPRanges)...)
+	problems = append(problems, config.validateServiceIP("calico-typha-ip", config.Config.CalicoTyphaIP, clusterIPRanges)...)
+
+	return problems
+}
+
+// validateServiceIP checks that value is a parseable IP falling within one of clusterIPRanges, matching it against
+// whichever range shares its address family
+func (config *InternalConfig) validateServiceIP(label, value string, clusterIPRanges []*net.IPNet) []string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return []string{fmt.Sprintf("invalid %s '%s'", label, value)}
+	}
+
+	for _, clusterIPRange := range clusterIPRanges {
+		if clusterIPRange.Contains(ip) {
+			return []string{}
+		}
+	}
+
+	return []string{fmt.Sprintf("%s '%s' does not fall within cluster-ip-range '%s'", label, value, config.Config.ClusterIPRange)}
+}
+
+// ClusterCIDRByFamily returns the cluster-cidr entry matching family ("IPv4" or "IPv6"), or an empty string if
+// cluster-cidr does not configure that family
+func (config *InternalConfig) ClusterCIDRByFamily(family string) string {
+	networks, _ := parseCIDRList(config.Config.ClusterCIDR)
+
+	for _, network := range networks {
+		if networkFamily(network) == family {
+			return network.String()
+		}
+	}
+
+	return ""
+}
+
+// minimumBuiltinServiceIPs is a rough floor for the number of ClusterIP/NodePort Services k8s-tew's own
+// manifests create regardless of node count (CoreDNS, the dashboard, monitoring, logging, ingress, ...), used
+// as headroom on top of one Service per node when sizing cluster-ip-range
+const minimumBuiltinServiceIPs = 16
+
+// validateClusterSizing checks that cluster-cidr (the pod network) has enough node-cidr-mask-size subnets for
+// every worker node, that a subnet of that size has room for max-pods, and that cluster-ip-range (the service
+// network) has enough addresses for one Service per node plus minimumBuiltinServiceIPs of headroom. IPv6
+// ranges are skipped since k8s-tew always sizes them far larger than IPv4 would allow
+func (config *InternalConfig) validateClusterSizing() []string {
+	problems := []string{}
+
+	clusterCIDRs, cidrErrors := parseCIDRList(config.Config.ClusterCIDR)
+	if len(cidrErrors) == 0 {
+		workerCount := uint64(0)
+
+		for _, node := range config.Config.Nodes {
+			if node.IsWorker() {
+				workerCount++
+			}
+		}
+
+		for _, clusterCIDR := range clusterCIDRs {
+			if networkFamily(clusterCIDR) == "IPv6" {
+				continue
+			}
+
+			ones, bits := clusterCIDR.Mask.Size()
+			maskSize := int(config.Config.NodeCIDRMaskSize)
+
+			if maskSize <= ones || maskSize > bits {
+				problems = append(problems, fmt.Sprintf("node-cidr-mask-size %d is not a valid subnet size within cluster-cidr '%s'", maskSize, clusterCIDR.String()))
+
+				continue
+			}
+
+			nodeSubnets := uint64(1) << uint(maskSize-ones)
+
+			if nodeSubnets < workerCount {
+				problems = append(problems, fmt.Sprintf("cluster-cidr '%s' only has room for %d /%d node subnet(s), but the cluster has %d worker node(s)", clusterCIDR.String(), nodeSubnets, maskSize, workerCount))
+			}
+
+			podsPerSubnet := uint64(1)<<uint(bits-maskSize) - 2
+
+			if podsPerSubnet < uint64(config.Config.MaxPods) {
+				problems = append(problems, fmt.Sprintf("node-cidr-mask-size %d leaves only %d usable pod IP(s) per node, less than max-pods %d", maskSize, podsPerSubnet, config.Config.MaxPods))
+			}
+		}
+	}
+
+	clusterIPRanges, rangeErrors := parseCIDRList(config.Config.ClusterIPRange)
+	if len(rangeErrors) == 0 {
+		requiredServiceIPs := uint64(len(config.Config.Nodes)) + minimumBuiltinServiceIPs
+
+		for _, clusterIPRange := range clusterIPRanges {
+			if networkFamily(clusterIPRange) == "IPv6" {
+				continue
+			}
+
+			ones, bits := clusterIPRange.Mask.Size()
+			availableServiceIPs := uint64(1)<<uint(bits-ones) - 2
+
+			if availableServiceIPs < requiredServiceIPs {
+				problems = append(problems, fmt.Sprintf("cluster-ip-range '%s' only has room for %d service IP(s), less than the %d expected for %d node(s)", clusterIPRange.String(), availableServiceIPs, requiredServiceIPs, len(config.Config.Nodes)))
+			}
+		}
+	}
+
+	return problems
+}
+
+func (config *InternalConfig) validateVirtualIPs() []string {
+	problems := []string{}
+
+	_, publicNetwork, error := net.ParseCIDR(config.Config.PublicNetwork)
+	if error != nil {
+		problems = append(problems, fmt.Sprintf("invalid public-network '%s': %s", config.Config.PublicNetwork, error.Error()))
+
+		return problems
+	}
+
+	seen := map[string]bool{}
+
+	for label, virtualIPs := range map[string][]VirtualIP{"controller-virtual-ips": config.Config.ControllerVirtualIPs, "worker-virtual-ips": config.Config.WorkerVirtualIPs} {
+		for _, virtualIP := range virtualIPs {
+			if len(virtualIP.Interface) == 0 {
+				problems = append(problems, fmt.Sprintf("%s '%s' is missing its interface", label, virtualIP.IP))
+			}
+
+			ip := net.ParseIP(virtualIP.IP)
+
+			if ip == nil || !publicNetwork.Contains(ip) {
+				problems = append(problems, fmt.Sprintf("%s '%s' is not reachable from public-network '%s'", label, virtualIP.IP, config.Config.PublicNetwork))
+
+				continue
+			}
+
+			if seen[virtualIP.IP] {
+				problems = append(problems, fmt.Sprintf("duplicate virtual ip '%s'", virtualIP.IP))
+			}
+
+			seen[virtualIP.IP] = true
+		}
+	}
+
+	return problems
+}
+
+// validateMetalLBAddressPool checks that, whenever MetalLB is enabled, its address pool falls within
+// public-network and does not overlap any node ip or controller/worker virtual ip - those are already routed
+// elsewhere, so MetalLB handing the same address to a Service would conflict with them
+func (config *InternalConfig) validateMetalLBAddressPool() []string {
+	problems := []string{}
+
+	if !config.Config.MetalLB.Enabled {
+		return problems
+	}
+
+	startIP := net.ParseIP(config.Config.MetalLB.AddressPoolStart)
+	endIP := net.ParseIP(config.Config.MetalLB.AddressPoolEnd)
+
+	if startIP == nil || endIP == nil {
+		// Already reported by MetalLBConfig.Validate()
+		return problems
+	}
+
+	_, publicNetwork, error := net.ParseCIDR(config.Config.PublicNetwork)
+	if error != nil {
+		// Already reported as an invalid public-network elsewhere
+		return problems
+	}
+
+	if !publicNetwork.Contains(startIP) || !publicNetwork.Contains(endIP) {
+		problems = append(problems, fmt.Sprintf("metallb address pool '%s-%s' is not reachable from public-network '%s'", config.Config.MetalLB.AddressPoolStart, config.Config.MetalLB.AddressPoolEnd, config.Config.PublicNetwork))
+	}
+
+	addressPoolContains := func(ip net.IP) bool {
+		return ip != nil && compareIPs(startIP, ip) <= 0 && compareIPs(ip, endIP) <= 0
+	}
+
+	for nodeName, node := range config.Config.Nodes {
+		if addressPoolContains(net.ParseIP(node.IP)) {
+			problems = append(problems, fmt.Sprintf("metallb address pool '%s-%s' overlaps node '%s' ip '%s'", config.Config.MetalLB.AddressPoolStart, config.Config.MetalLB.AddressPoolEnd, nodeName, node.IP))
+		}
+	}
+
+	for label, virtualIPs := range map[string][]VirtualIP{"controller-virtual-ips": config.Config.ControllerVirtualIPs, "worker-virtual-ips": config.Config.WorkerVirtualIPs} {
+		for _, virtualIP := range virtualIPs {
+			if addressPoolContains(net.ParseIP(virtualIP.IP)) {
+				problems = append(problems, fmt.Sprintf("metallb address pool '%s-%s' overlaps %s '%s'", config.Config.MetalLB.AddressPoolStart, config.Config.MetalLB.AddressPoolEnd, label, virtualIP.IP))
+			}
+		}
+	}
+
+	return problems
+}
+
+func (config *InternalConfig) validateCommandFeatures() []string {
+	problems := []string{}
+
+	for _, command := range config.Config.Commands {
+		for _, feature := range command.Features {
+			if !validFeatures[feature] {
+				problems = append(problems, fmt.Sprintf("command '%s' references undefined feature '%s'", command.Name, feature))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateCommandDependencies checks that every command's depends-on names an existing command and that no cycle
+// exists among them, so Commands.RunDAG never has to discover either at deploy/run time
+func (config *InternalConfig) validateCommandDependencies() []string {
+	problems := []string{}
+
+	names := map[string]bool{}
+
+	for _, command := range config.Config.Commands {
+		names[command.Name] = true
+	}
+
+	for _, command := range config.Config.Commands {
+		for _, dependency := range command.DependsOn {
+			if !names[dependency] {
+				problems = append(problems, fmt.Sprintf("command '%s' depends on undefined command '%s'", command.Name, dependency))
+			}
+		}
+	}
+
+	if cycle := config.Config.Commands.FindDependencyCycle(); len(cycle) > 0 {
+		problems = append(problems, fmt.Sprintf("command dependency cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return problems
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateCNI makes sure cni selects one of the CNIs k8s-tew knows how to set up
+func ValidateCNI(cni string) error {
+	if !utils.SupportedCNIs[cni] {
+		return fmt.Errorf("unsupported cni '%s', expected one of %s, %s", cni, utils.CNI_CALICO, utils.CNI_CILIUM)
+	}
+
+	return nil
+}
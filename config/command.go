@@ -1,16 +1,19 @@
 package config
 
 type Command struct {
-	Name     string   `yaml:"name"`
-	Command  string   `yaml:"command"`
-	Labels   Labels   `yaml:"labels,omitempty"`
-	Features Features `yaml:"features,omitempty"`
-	OS       OS       `yaml:"os,omitempty"`
+	Name      string   `yaml:"name"`
+	Command   string   `yaml:"command"`
+	Labels    Labels   `yaml:"labels,omitempty"`
+	Features  Features `yaml:"features,omitempty"`
+	OS        OS       `yaml:"os,omitempty"`
+	DependsOn []string `yaml:"depends-on,omitempty"`
 }
 
 type Commands []*Command
 type OS []string
 
-func NewCommand(name string, labels Labels, features Features, os OS, command string) *Command {
-	return &Command{Name: name, Labels: labels, Features: features, OS: os, Command: command}
+// NewCommand creates a command, optionally depending on other commands by name so an executor can run it only
+// once those have finished - see Commands.RunDAG
+func NewCommand(name string, labels Labels, features Features, os OS, command string, dependsOn ...string) *Command {
+	return &Command{Name: name, Labels: labels, Features: features, OS: os, Command: command, DependsOn: dependsOn}
 }
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// FindDependencyCycle walks commands' DependsOn edges depth-first and returns the names forming a cycle (e.g.
+// ["a", "b", "a"]), or nil if there is none. Used at config-load time so a cycle is rejected before RunDAG ever
+// runs, rather than surfacing as a deadlock deep into a deploy or run
+func (commands Commands) FindDependencyCycle() []string {
+	byName := map[string]*Command{}
+
+	for _, command := range commands {
+		byName[command.Name] = command
+	}
+
+	const (
+		unvisited = 0
+		visiting  = 1
+		visited   = 2
+	)
+
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(name string) []string
+
+	visit = func(name string) []string {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return append(append([]string{}, path...), name)
+		}
+
+		command, ok := byName[name]
+		if !ok {
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range command.DependsOn {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, command := range commands {
+		if state[command.Name] == unvisited {
+			if cycle := visit(command.Name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunDAG runs every command for which shouldRun returns true, respecting DependsOn: a command only starts once
+// every dependency that is itself eligible to run has finished. Commands whose dependencies are all satisfied -
+// which, absent any DependsOn, means every eligible command on the first round - are handed to
+// utils.RunParallelTasks together, so they run concurrently when parallel is true and in their original order
+// when it is false. Commands skipped by shouldRun are reported via onSkip (may be nil) and are not waited on, so
+// a dependency that never runs in this context (e.g. it targets a different label set) cannot block its
+// dependents. A dependency cycle should already have been rejected by FindDependencyCycle at config-load time;
+// if one slips through regardless, RunDAG fails fast instead of looping forever
+func (commands Commands) RunDAG(shouldRun func(*Command) bool, onSkip func(*Command), run func(*Command) error, parallel bool) error {
+	eligible := []*Command{}
+	byName := map[string]*Command{}
+
+	for _, command := range commands {
+		if !shouldRun(command) {
+			if onSkip != nil {
+				onSkip(command)
+			}
+
+			continue
+		}
+
+		eligible = append(eligible, command)
+		byName[command.Name] = command
+	}
+
+	done := map[string]bool{}
+
+	for len(done) < len(eligible) {
+		wave := []*Command{}
+
+		for _, command := range eligible {
+			if done[command.Name] {
+				continue
+			}
+
+			ready := true
+
+			for _, dependency := range command.DependsOn {
+				if _, ok := byName[dependency]; !ok {
+					continue
+				}
+
+				if !done[dependency] {
+					ready = false
+
+					break
+				}
+			}
+
+			if ready {
+				wave = append(wave, command)
+			}
+		}
+
+		if len(wave) == 0 {
+			pending := []string{}
+
+			for _, command := range eligible {
+				if !done[command.Name] {
+					pending = append(pending, command.Name)
+				}
+			}
+
+			return fmt.Errorf("command dependency cycle detected among: %s", strings.Join(pending, ", "))
+		}
+
+		tasks := utils.Tasks{}
+
+		for _, command := range wave {
+			command := command
+
+			tasks = append(tasks, func() error {
+				if error := run(command); error != nil {
+					return fmt.Errorf("command '%s': %s", command.Name, error.Error())
+				}
+
+				return nil
+			})
+		}
+
+		if errors := utils.RunParallelTasks(tasks, parallel, 0); len(errors) > 0 {
+			return errors
+		}
+
+		for _, command := range wave {
+			done[command.Name] = true
+		}
+	}
+
+	return nil
+}
@@ -8,36 +8,93 @@ import (
 )
 
 type Config struct {
-	Version                      string      `yaml:"version"`
-	ClusterID                    string      `yaml:"cluster-id"`
-	ClusterName                  string      `yaml:"cluster-name"`
-	Email                        string      `yaml:"email"`
-	IngressDomain                string      `yaml:"ingress-domain"`
-	LoadBalancerPort             uint16      `yaml:"load-balancer-port"`
-	VIPRaftControllerPort        uint16      `yaml:"vip-raft-controller-port"`
-	VIPRaftWorkerPort            uint16      `yaml:"vip-raft-worker-port"`
-	KubernetesDashboardPort      uint16      `yaml:"kubernetes-dashboard-port"`
-	APIServerPort                uint16      `yaml:"apiserver-port,omitempty"`
-	PublicNetwork                string      `yaml:"public-network"`
-	ControllerVirtualIP          string      `yaml:"controller-virtual-ip,omitempty"`
-	ControllerVirtualIPInterface string      `yaml:"controller-virtual-ip-interface,omitempty"`
-	WorkerVirtualIP              string      `yaml:"worker-virtual-ip,omitempty"`
-	WorkerVirtualIPInterface     string      `yaml:"worker-virtual-ip-interface,omitempty"`
-	ClusterDomain                string      `yaml:"cluster-domain"`
-	ClusterIPRange               string      `yaml:"cluster-ip-range"`
-	ClusterDNSIP                 string      `yaml:"cluster-dns-ip"`
-	ClusterCIDR                  string      `yaml:"cluster-cidr"`
-	CalicoTyphaIP                string      `yaml:"calico-typha-ip"`
-	ResolvConf                   string      `yaml:"resolv-conf"`
-	DeploymentDirectory          string      `yaml:"deployment-directory,omitempty"`
-	RSASize                      uint16      `yaml:"rsa-size"`
-	CAValidityPeriod             uint        `yaml:"ca-validity-period"`
-	ClientValidityPeriod         uint        `yaml:"client-validity-period"`
-	Versions                     Versions    `yaml:"versions"`
-	Assets                       AssetConfig `yaml:"assets,omitempty"`
-	Nodes                        Nodes       `yaml:"nodes"`
-	Commands                     Commands    `yaml:"commands,omitempty"`
-	Servers                      Servers     `yaml:"servers,omitempty"`
+	Version                                   string                        `yaml:"version"`
+	ClusterID                                 string                        `yaml:"cluster-id"`
+	ClusterName                               string                        `yaml:"cluster-name"`
+	Email                                     string                        `yaml:"email"`
+	IngressDomain                             string                        `yaml:"ingress-domain"`
+	IngressHosts                              []string                      `yaml:"ingress-hosts,omitempty"`
+	LoadBalancerPort                          uint16                        `yaml:"load-balancer-port"`
+	VIPRaftControllerPort                     uint16                        `yaml:"vip-raft-controller-port"`
+	VIPRaftWorkerPort                         uint16                        `yaml:"vip-raft-worker-port"`
+	KubernetesDashboardPort                   uint16                        `yaml:"kubernetes-dashboard-port"`
+	APIServerPort                             uint16                        `yaml:"apiserver-port,omitempty"`
+	APIServerExtraDNSNames                    []string                      `yaml:"apiserver-extra-dns-names,omitempty"`
+	APIServerExtraIPs                         []string                      `yaml:"apiserver-extra-ips,omitempty"`
+	PublicNetwork                             string                        `yaml:"public-network"`
+	ControllerVirtualIPs                      []VirtualIP                   `yaml:"controller-virtual-ips,omitempty"`
+	WorkerVirtualIPs                          []VirtualIP                   `yaml:"worker-virtual-ips,omitempty"`
+	MetalLB                                   MetalLBConfig                 `yaml:"metallb,omitempty"`
+	EncryptionProvider                        string                        `yaml:"encryption-provider,omitempty"`
+	EncryptionKMSEndpoint                     string                        `yaml:"encryption-kms-endpoint,omitempty"`
+	CNI                                       string                        `yaml:"cni"`
+	WeaveEncryptionPassword                   string                        `yaml:"weave-encryption-password,omitempty"`
+	IngressController                         string                        `yaml:"ingress-controller,omitempty"`
+	LoggingBackend                            string                        `yaml:"logging-backend,omitempty"`
+	GrafanaAdminUsername                      string                        `yaml:"grafana-admin-username,omitempty"`
+	GrafanaAdminPassword                      string                        `yaml:"grafana-admin-password,omitempty"`
+	PrometheusRetention                       string                        `yaml:"prometheus-retention,omitempty"`
+	PrometheusStorageSize                     string                        `yaml:"prometheus-storage-size,omitempty"`
+	AlertManagerStorageSize                   string                        `yaml:"alertmanager-storage-size,omitempty"`
+	AlertmanagerReceivers                     []AlertmanagerReceiver        `yaml:"alertmanager-receivers,omitempty"`
+	AlertmanagerRoutes                        []AlertmanagerRoute           `yaml:"alertmanager-routes,omitempty"`
+	ControllerTaints                          []ControllerTaint             `yaml:"controller-taints,omitempty"`
+	ClusterDomain                             string                        `yaml:"cluster-domain"`
+	ClusterIPRange                            string                        `yaml:"cluster-ip-range"`
+	ClusterDNSIP                              string                        `yaml:"cluster-dns-ip"`
+	ClusterCIDR                               string                        `yaml:"cluster-cidr"`
+	CoreDNSStubDomains                        []StubDomain                  `yaml:"coredns-stub-domains,omitempty"`
+	CoreDNSUpstreamForwards                   []string                      `yaml:"coredns-upstream-forwards,omitempty"`
+	NodeCIDRMaskSize                          uint                          `yaml:"node-cidr-mask-size"`
+	MaxPods                                   uint                          `yaml:"max-pods"`
+	CalicoTyphaIP                             string                        `yaml:"calico-typha-ip"`
+	ResolvConf                                string                        `yaml:"resolv-conf"`
+	DeploymentDirectory                       string                        `yaml:"deployment-directory,omitempty"`
+	ETCDDataDirectory                         string                        `yaml:"etcd-data-directory,omitempty"`
+	ContainerdDataDirectory                   string                        `yaml:"containerd-data-directory,omitempty"`
+	KubeletDataDirectory                      string                        `yaml:"kubelet-data-directory,omitempty"`
+	PodsDataDirectory                         string                        `yaml:"pods-data-directory,omitempty"`
+	RSASize                                   uint16                        `yaml:"rsa-size"`
+	CertificateAlgorithm                      string                        `yaml:"certificate-algorithm,omitempty"`
+	CertificateECDSACurve                     string                        `yaml:"certificate-ecdsa-curve,omitempty"`
+	CAValidityPeriod                          uint                          `yaml:"ca-validity-period"`
+	ClientValidityPeriod                      uint                          `yaml:"client-validity-period"`
+	Firewall                                  bool                          `yaml:"firewall"`
+	ETCDDiscoveryDomain                       string                        `yaml:"etcd-discovery-domain,omitempty"`
+	ExternalETCD                              ExternalETCDConfig            `yaml:"external-etcd,omitempty"`
+	Audit                                     AuditConfig                   `yaml:"audit,omitempty"`
+	MetricsServerKubeletInsecureTLS           bool                          `yaml:"metrics-server-kubelet-insecure-tls"`
+	MetricsServerKubeletPreferredAddressTypes string                        `yaml:"metrics-server-kubelet-preferred-address-types,omitempty"`
+	PodSecurityNamespaces                     map[string]PodSecurityLevels  `yaml:"pod-security-namespaces,omitempty"`
+	AdditionalTrustedCAs                      []string                      `yaml:"additional-trusted-cas,omitempty"`
+	RegistryMirror                            string                        `yaml:"registry-mirror,omitempty"`
+	HTTPProxy                                 string                        `yaml:"http-proxy,omitempty"`
+	HTTPSProxy                                string                        `yaml:"https-proxy,omitempty"`
+	NoProxy                                   string                        `yaml:"no-proxy,omitempty"`
+	SchedulerProfiles                         []SchedulerProfile            `yaml:"scheduler-profiles,omitempty"`
+	Tracing                                   TracingConfig                 `yaml:"tracing,omitempty"`
+	StorageClasses                            map[string]StorageClassConfig `yaml:"storage-classes,omitempty"`
+	CephPools                                 map[string]CephPoolConfig     `yaml:"ceph-pools,omitempty"`
+	StorageBackend                            string                        `yaml:"storage-backend,omitempty"`
+	NFSServer                                 string                        `yaml:"nfs-server,omitempty"`
+	NFSPath                                   string                        `yaml:"nfs-path,omitempty"`
+	BackupSchedules                           []BackupSchedule              `yaml:"backup-schedules,omitempty"`
+	OIDC                                      OIDCConfig                    `yaml:"oidc,omitempty"`
+	ApplyMode                                 string                        `yaml:"apply-mode,omitempty"`
+	ImageGCHighThresholdPercent               uint8                         `yaml:"image-gc-high-threshold-percent"`
+	ImageGCLowThresholdPercent                uint8                         `yaml:"image-gc-low-threshold-percent"`
+	ContainerdSystemdCgroup                   bool                          `yaml:"containerd-systemd-cgroup,omitempty"`
+	ContainerdRuntimeHandlers                 []ContainerdRuntimeHandler    `yaml:"containerd-runtime-handlers,omitempty"`
+	RegistryAuths                             []RegistryAuth                `yaml:"registry-auths,omitempty"`
+	LogVerbosity                              LogVerbosity                  `yaml:"log-verbosity,omitempty"`
+	DisableSwapFstab                          bool                          `yaml:"disable-swap-fstab,omitempty"`
+	SerializeImagePulls                       bool                          `yaml:"serialize-image-pulls"`
+	ImagePullProgressDeadline                 string                        `yaml:"image-pull-progress-deadline"`
+	Versions                                  Versions                      `yaml:"versions"`
+	Assets                                    AssetConfig                   `yaml:"assets,omitempty"`
+	Nodes                                     Nodes                         `yaml:"nodes"`
+	Commands                                  Commands                      `yaml:"commands,omitempty"`
+	Servers                                   Servers                       `yaml:"servers,omitempty"`
 }
 
 func NewConfig() *Config {
@@ -53,16 +110,35 @@ func NewConfig() *Config {
 	config.KubernetesDashboardPort = utils.PORT_KUBERNETES_DASHBOARD
 	config.APIServerPort = utils.PORT_API_SERVER
 	config.PublicNetwork = utils.PUBLIC_NETWORK
+	config.CNI = utils.CNI_CALICO
+	config.IngressController = utils.INGRESS_CONTROLLER_NGINX
+	config.LoggingBackend = utils.LOGGING_BACKEND_EFK
+	config.GrafanaAdminUsername = utils.GRAFANA_ADMIN_USERNAME
+	config.PrometheusRetention = utils.PROMETHEUS_RETENTION
+	config.PrometheusStorageSize = utils.PROMETHEUS_STORAGE_SIZE
+	config.AlertManagerStorageSize = utils.ALERTMANAGER_STORAGE_SIZE
+	config.EncryptionProvider = utils.ENCRYPTION_PROVIDER_AESCBC
+	config.ControllerTaints = []ControllerTaint{{Key: utils.CONTROLLER_ONLY_TAINT_KEY, Value: utils.CONTROLLER_ONLY_TAINT_VALUE, Effect: utils.CONTROLLER_ONLY_TAINT_EFFECT}}
 	config.ClusterDomain = utils.CLUSTER_DOMAIN
 	config.ClusterIPRange = utils.CLUSTER_IP_RANGE
 	config.ClusterDNSIP = utils.CLUSTER_DNS_IP
 	config.ClusterCIDR = utils.CLUSTER_CIDR
+	config.NodeCIDRMaskSize = utils.NODE_CIDR_MASK_SIZE
+	config.MaxPods = utils.MAX_PODS
 	config.CalicoTyphaIP = utils.CALICO_TYPHA_IP
 	config.ResolvConf = utils.RESOLV_CONF
 	config.DeploymentDirectory = utils.DEPLOYMENT_DIRECTORY
 	config.RSASize = utils.RSA_SIZE
+	config.CertificateAlgorithm = utils.CERTIFICATE_ALGORITHM_RSA
+	config.CertificateECDSACurve = utils.ECDSA_CURVE_P256
+	config.StorageBackend = utils.STORAGE_BACKEND_CEPH
 	config.CAValidityPeriod = utils.CA_VALIDITY_PERIOD
 	config.ClientValidityPeriod = utils.CLIENT_VALIDITY_PERIOD
+	config.ImageGCHighThresholdPercent = utils.IMAGE_GC_HIGH_THRESHOLD_PERCENT
+	config.ImageGCLowThresholdPercent = utils.IMAGE_GC_LOW_THRESHOLD_PERCENT
+	config.SerializeImagePulls = true
+	config.ImagePullProgressDeadline = utils.IMAGE_PULL_PROGRESS_DEADLINE
+	config.Audit = AuditConfig{Policy: defaultAuditPolicy, MaxAge: utils.AUDIT_LOG_MAX_AGE, MaxBackup: utils.AUDIT_LOG_MAX_BACKUP, MaxSize: utils.AUDIT_LOG_MAX_SIZE}
 	config.Versions = NewVersions()
 	config.Assets = AssetConfig{Directories: map[string]*AssetDirectory{}, Files: map[string]*AssetFile{}}
 	config.Nodes = Nodes{}
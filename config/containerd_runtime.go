@@ -0,0 +1,42 @@
+package config
+
+import "fmt"
+
+// ContainerdRuntimeHandler defines an additional containerd CRI runtime handler (e.g. for gVisor or Kata),
+// selectable per pod via RuntimeClass, alongside the default runc runtime
+type ContainerdRuntimeHandler struct {
+	Name          string `yaml:"name"`
+	RuntimeType   string `yaml:"runtime-type"`
+	RuntimeEngine string `yaml:"runtime-engine,omitempty"`
+	RuntimeRoot   string `yaml:"runtime-root,omitempty"`
+}
+
+func (handler ContainerdRuntimeHandler) Validate() error {
+	if len(handler.Name) == 0 {
+		return fmt.Errorf("containerd runtime handler name is required")
+	}
+
+	if len(handler.RuntimeType) == 0 {
+		return fmt.Errorf("containerd runtime handler '%s' is missing a runtime-type (e.g. io.containerd.runc.v1)", handler.Name)
+	}
+
+	return nil
+}
+
+func ValidateContainerdRuntimeHandlers(handlers []ContainerdRuntimeHandler) error {
+	names := map[string]bool{}
+
+	for _, handler := range handlers {
+		if error := handler.Validate(); error != nil {
+			return error
+		}
+
+		if names[handler.Name] {
+			return fmt.Errorf("containerd runtime handler '%s' is defined more than once", handler.Name)
+		}
+
+		names[handler.Name] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ControllerTaint defines a taint applied to every controller-only node and removed from every other node, so
+// workloads without a matching toleration are kept off the control plane. Key is required, Value defaults to
+// empty and Effect has to be one of NoSchedule, PreferNoSchedule or NoExecute
+type ControllerTaint struct {
+	Key    string `yaml:"key"`
+	Value  string `yaml:"value,omitempty"`
+	Effect string `yaml:"effect"`
+}
+
+func (taint ControllerTaint) Validate() error {
+	if len(taint.Key) == 0 {
+		return fmt.Errorf("controller taint key is required")
+	}
+
+	if !utils.SupportedTaintEffects[taint.Effect] {
+		return fmt.Errorf("controller taint '%s' has invalid effect '%s', expected NoSchedule, PreferNoSchedule or NoExecute", taint.Key, taint.Effect)
+	}
+
+	return nil
+}
+
+func ValidateControllerTaints(taints []ControllerTaint) error {
+	for _, taint := range taints {
+		if error := taint.Validate(); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
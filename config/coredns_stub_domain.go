@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+)
+
+// domainNameExpression matches a DNS domain name: one or more dot-separated labels, each made of letters, digits
+// and hyphens, not starting or ending with a hyphen
+var domainNameExpression = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$`)
+
+// StubDomain forwards everything under Domain to Nameservers instead of the cluster's normal upstream, so CoreDNS
+// can resolve internal zones (e.g. a corporate domain) that the default upstream doesn't know about
+type StubDomain struct {
+	Domain      string   `yaml:"domain"`
+	Nameservers []string `yaml:"nameservers"`
+}
+
+// Validate makes sure the stub domain has a syntactically valid domain name and at least one nameserver, each a
+// parseable IP address
+func (stubDomain StubDomain) Validate() error {
+	if !domainNameExpression.MatchString(stubDomain.Domain) {
+		return fmt.Errorf("invalid domain name '%s'", stubDomain.Domain)
+	}
+
+	if len(stubDomain.Nameservers) == 0 {
+		return fmt.Errorf("stub domain '%s' has no nameservers", stubDomain.Domain)
+	}
+
+	for _, nameserver := range stubDomain.Nameservers {
+		if net.ParseIP(nameserver) == nil {
+			return fmt.Errorf("stub domain '%s' has an invalid nameserver ip '%s'", stubDomain.Domain, nameserver)
+		}
+	}
+
+	return nil
+}
+
+// ValidateStubDomains makes sure every stub domain validates on its own and that no two stub domains forward the
+// same domain, since CoreDNS would otherwise end up with two server blocks claiming the same zone
+func ValidateStubDomains(stubDomains []StubDomain) error {
+	seen := map[string]bool{}
+
+	for _, stubDomain := range stubDomains {
+		if error := stubDomain.Validate(); error != nil {
+			return error
+		}
+
+		if seen[stubDomain.Domain] {
+			return fmt.Errorf("duplicate stub domain '%s'", stubDomain.Domain)
+		}
+
+		seen[stubDomain.Domain] = true
+	}
+
+	return nil
+}
+
+// ValidateCoreDNSUpstreamForwards makes sure every configured upstream forward override is a parseable IP address
+func ValidateCoreDNSUpstreamForwards(upstreamForwards []string) error {
+	for _, nameserver := range upstreamForwards {
+		if net.ParseIP(nameserver) == nil {
+			return fmt.Errorf("invalid coredns-upstream-forwards nameserver ip '%s'", nameserver)
+		}
+	}
+
+	return nil
+}
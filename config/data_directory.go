@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/darxkies/k8s-tew/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateDataDirectories checks every overridden data directory in directories (label -> absolute path, empty
+// paths are skipped) exists or can be created, and warns - rather than failing - when it ends up on the same
+// filesystem as / ("/"), since that defeats the point of moving it onto its own mount
+func ValidateDataDirectories(directories map[string]string) error {
+	for label, directory := range directories {
+		if len(directory) == 0 {
+			continue
+		}
+
+		if !path.IsAbs(directory) {
+			return fmt.Errorf("%s '%s' has to be an absolute path", label, directory)
+		}
+
+		if error := utils.CreateDirectoryIfMissing(directory); error != nil {
+			return fmt.Errorf("%s '%s' does not exist and could not be created (%s)", label, directory, error.Error())
+		}
+
+		if sameFilesystem, error := onSameFilesystemAsRoot(directory); error == nil && sameFilesystem {
+			log.WithFields(log.Fields{label: directory}).Warn("This data directory is on the same filesystem as the root filesystem, defeating the purpose of moving it onto a separate mount")
+		}
+	}
+
+	return nil
+}
+
+// onSameFilesystemAsRoot reports whether directory lives on the same filesystem as /, comparing device numbers
+// instead of mount points since a bind mount can make two different paths share the same device
+func onSameFilesystemAsRoot(directory string) (bool, error) {
+	rootInfo, error := os.Stat("/")
+	if error != nil {
+		return false, error
+	}
+
+	directoryInfo, error := os.Stat(directory)
+	if error != nil {
+		return false, error
+	}
+
+	rootStat, ok := rootInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("could not determine the device of '/'")
+	}
+
+	directoryStat, ok := directoryInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("could not determine the device of '%s'", directory)
+	}
+
+	return rootStat.Dev == directoryStat.Dev, nil
+}
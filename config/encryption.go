@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateEncryptionProvider makes sure provider selects one of the encryption-at-rest providers k8s-tew knows
+// how to configure
+func ValidateEncryptionProvider(provider string) error {
+	if !utils.SupportedEncryptionProviders[provider] {
+		return fmt.Errorf("unsupported encryption provider '%s', expected one of %s, %s, %s", provider, utils.ENCRYPTION_PROVIDER_AESCBC, utils.ENCRYPTION_PROVIDER_AESGCM, utils.ENCRYPTION_PROVIDER_SECRETBOX)
+	}
+
+	return nil
+}
+
+// ValidateEncryptionKMSEndpoint makes sure endpoint, if set, parses as a grpc:// or unix:// url, which is what the
+// apiserver's kms provider expects to dial the external KMS plugin on
+func ValidateEncryptionKMSEndpoint(endpoint string) error {
+	if len(endpoint) == 0 {
+		return nil
+	}
+
+	parsedEndpoint, error := url.Parse(endpoint)
+	if error != nil || len(parsedEndpoint.Scheme) == 0 || len(parsedEndpoint.Host)+len(parsedEndpoint.Path) == 0 {
+		return fmt.Errorf("'%s' is not a valid encryption-kms-endpoint, expected a url such as 'unix:///var/run/kmsplugin/socket.sock'", endpoint)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import "fmt"
+
+// ExternalETCDConfig points kube-apiserver at an etcd cluster k8s-tew does not manage, instead of the etcd
+// server entry it runs on every controller by default. CAFile, CertFile and KeyFile are paths on the
+// controller nodes themselves, since k8s-tew does not deploy certificates for a cluster it doesn't own
+type ExternalETCDConfig struct {
+	Endpoints []string `yaml:"endpoints,omitempty"`
+	CAFile    string   `yaml:"ca-file,omitempty"`
+	CertFile  string   `yaml:"cert-file,omitempty"`
+	KeyFile   string   `yaml:"key-file,omitempty"`
+}
+
+// Enabled reports whether an external etcd cluster is configured, in which case k8s-tew's own etcd server is
+// disabled and kube-apiserver is pointed at Endpoints instead
+func (externalETCD ExternalETCDConfig) Enabled() bool {
+	return len(externalETCD.Endpoints) > 0
+}
+
+// Validate makes sure an external etcd cluster, once any endpoint is configured, also carries its CA/cert/key
+func (externalETCD ExternalETCDConfig) Validate() error {
+	if !externalETCD.Enabled() {
+		return nil
+	}
+
+	if len(externalETCD.CAFile) == 0 || len(externalETCD.CertFile) == 0 || len(externalETCD.KeyFile) == 0 {
+		return fmt.Errorf("external-etcd requires ca-file, cert-file and key-file to be set")
+	}
+
+	return nil
+}
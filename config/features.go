@@ -1,7 +1,14 @@
 package config
 
+// Features lists the capabilities (e.g. FEATURE_STORAGE, FEATURE_SHOWCASE) an image or command depends on. An
+// entry tagged with more than one feature, such as {FEATURE_SHOWCASE, FEATURE_STORAGE} for WordPress, depends
+// on all of them being available, not just one
 type Features []string
 
+// HasFeatures reports whether features and otherFeatures share at least one entry. When otherFeatures is the
+// set of features being skipped, this means an entry is skipped as soon as any single feature it depends on is
+// skipped - e.g. skipping only showcase leaves storage-only images untouched but still skips WordPress/MySQL,
+// since they require both
 func (features Features) HasFeatures(otherFeatures Features) bool {
 	for _, feature := range features {
 		for _, otherFeature := range otherFeatures {
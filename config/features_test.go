@@ -0,0 +1,56 @@
+package config
+
+import "testing"
+
+// TestHasFeatures pins down HasFeatures' documented ANY-match semantics: an entry is considered to have
+// otherFeatures as soon as a single feature is shared, not only when every one of its features is in otherFeatures
+func TestHasFeatures(t *testing.T) {
+	tests := []struct {
+		name          string
+		features      Features
+		otherFeatures Features
+		expected      bool
+	}{
+		{name: "single feature, matching skip set", features: Features{"storage"}, otherFeatures: Features{"storage"}, expected: true},
+		{name: "single feature, non-matching skip set", features: Features{"storage"}, otherFeatures: Features{"showcase"}, expected: false},
+		{name: "multi feature, one of them skipped", features: Features{"showcase", "storage"}, otherFeatures: Features{"showcase"}, expected: true},
+		{name: "multi feature, none of them skipped", features: Features{"showcase", "storage"}, otherFeatures: Features{"logging"}, expected: false},
+		{name: "multi feature, all of them skipped", features: Features{"showcase", "storage"}, otherFeatures: Features{"showcase", "storage"}, expected: true},
+		{name: "empty features never has any skip set", features: Features{}, otherFeatures: Features{"storage"}, expected: false},
+		{name: "nil features never has any skip set", features: nil, otherFeatures: Features{"storage"}, expected: false},
+		{name: "non-empty features against empty skip set", features: Features{"storage"}, otherFeatures: Features{}, expected: false},
+		{name: "non-empty features against nil skip set", features: Features{"storage"}, otherFeatures: nil, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := test.features.HasFeatures(test.otherFeatures); result != test.expected {
+				t.Errorf("HasFeatures(%v, %v) = %v, expected %v", test.features, test.otherFeatures, result, test.expected)
+			}
+		})
+	}
+}
+
+// TestCompareFeatures makes sure CompareFeatures delegates to HasFeatures and additionally treats a nil source or
+// destination as never matching, unlike HasFeatures itself which treats nil the same as an empty slice
+func TestCompareFeatures(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      Features
+		destination Features
+		expected    bool
+	}{
+		{name: "shared feature matches", source: Features{"storage"}, destination: Features{"storage"}, expected: true},
+		{name: "disjoint features don't match", source: Features{"storage"}, destination: Features{"showcase"}, expected: false},
+		{name: "nil source never matches", source: nil, destination: Features{"storage"}, expected: false},
+		{name: "nil destination never matches", source: Features{"storage"}, destination: nil, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := CompareFeatures(test.source, test.destination); result != test.expected {
+				t.Errorf("CompareFeatures(%v, %v) = %v, expected %v", test.source, test.destination, result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateIngressController makes sure ingressController selects one of the ingress controllers k8s-tew knows how
+// to set up
+func ValidateIngressController(ingressController string) error {
+	if !utils.SupportedIngressControllers[ingressController] {
+		return fmt.Errorf("unsupported ingress controller '%s', expected one of %s, %s", ingressController, utils.INGRESS_CONTROLLER_NGINX, utils.INGRESS_CONTROLLER_TRAEFIK)
+	}
+
+	return nil
+}
+
+// IngressClassName returns the kubernetes.io/ingress.class value matching the configured ingress controller, used
+// by every Ingress resource the other setups create (cert-manager, wordpress) so they keep routing through whichever
+// controller is actually deployed
+func (config *InternalConfig) IngressClassName() string {
+	if config.Config.IngressController == utils.INGRESS_CONTROLLER_TRAEFIK {
+		return utils.INGRESS_CONTROLLER_TRAEFIK
+	}
+
+	return utils.INGRESS_CONTROLLER_NGINX
+}
+
+// IngressDefaultBackendService returns the name of the Service that Ingress resources with no more specific rule
+// should fall back to for whichever ingress controller is configured
+func (config *InternalConfig) IngressDefaultBackendService() string {
+	if config.Config.IngressController == utils.INGRESS_CONTROLLER_TRAEFIK {
+		return "traefik-default-backend"
+	}
+
+	return "nginx-ingress-default-backend"
+}
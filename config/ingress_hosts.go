@@ -0,0 +1,19 @@
+package config
+
+import "fmt"
+
+// ValidateIngressHosts makes sure the configured ingress hostnames, for which the letsencrypt-cluster-issuer
+// and cert-manager setups request certificates and create Ingress resources, do not contain duplicates
+func ValidateIngressHosts(hosts []string) error {
+	seen := map[string]bool{}
+
+	for _, host := range hosts {
+		if seen[host] {
+			return fmt.Errorf("duplicate ingress host '%s'", host)
+		}
+
+		seen[host] = true
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/darxkies/k8s-tew/utils"
 	log "github.com/sirupsen/logrus"
@@ -40,6 +41,25 @@ func (config *InternalConfig) GetFullLocalAssetFilename(name string) string {
 	return config.GetFullAssetFilename(config.BaseDirectory, name)
 }
 
+// GetFullLocalArchAssetFilename returns the local cache path of an architecture-specific binary, partitioning the
+// download cache by arch so a mixed-architecture cluster can keep an amd64 and an arm64 copy side by side
+func (config *InternalConfig) GetFullLocalArchAssetFilename(name, arch string) string {
+	filename := config.GetFullLocalAssetFilename(name)
+
+	return path.Join(path.Dir(filename), arch, path.Base(filename))
+}
+
+// GetFullLocalDeployableAssetFilename returns where to pick up name for deployment to the current node. For
+// architecture-specific binaries (utils.ArchBinaries) it resolves to the cache partition matching the node's own
+// arch; everything else uses the plain, architecture-independent local path
+func (config *InternalConfig) GetFullLocalDeployableAssetFilename(name string) string {
+	if config.Node != nil && utils.ArchBinaries[name] {
+		return config.GetFullLocalArchAssetFilename(name, config.Node.GetArch())
+	}
+
+	return config.GetFullLocalAssetFilename(name)
+}
+
 func (config *InternalConfig) GetRelativeAssetFilename(name string) string {
 	return config.GetFullAssetFilename("", name)
 }
@@ -118,6 +138,15 @@ func (config *InternalConfig) SetNode(nodeName string, node *Node) {
 	config.Node = node
 }
 
+// Clone returns an independent copy of the config already pointed at the given node. Unlike SetNode, it leaves the
+// original config untouched, so each clone can be handed to a concurrent task without racing on Name/Node
+func (config *InternalConfig) Clone(nodeName string, node *Node) *InternalConfig {
+	clone := *config
+	clone.SetNode(nodeName, node)
+
+	return &clone
+}
+
 func NewInternalConfig(baseDirectory string) *InternalConfig {
 	config := &InternalConfig{}
 	config.BaseDirectory = baseDirectory
@@ -131,6 +160,7 @@ func (config *InternalConfig) registerAssetDirectories() {
 	// Config
 	config.addAssetDirectory(utils.CONFIG_DIRECTORY, Labels{}, config.getRelativeConfigDirectory(), false)
 	config.addAssetDirectory(utils.CERTIFICATES_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.CONFIG_DIRECTORY), utils.CERTIFICATES_SUBDIRECTORY), false)
+	config.addAssetDirectory(utils.ADDITIONAL_CA_DIRECTORY, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, path.Join(config.GetRelativeAssetDirectory(utils.CERTIFICATES_DIRECTORY), utils.ADDITIONAL_CA_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.CNI_CONFIG_DIRECTORY, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, path.Join(config.GetRelativeAssetDirectory(utils.CONFIG_DIRECTORY), utils.CNI_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.CRI_CONFIG_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.CONFIG_DIRECTORY), utils.CRI_SUBDIRECTORY), false)
 
@@ -148,16 +178,21 @@ func (config *InternalConfig) registerAssetDirectories() {
 	config.addAssetDirectory(utils.CRI_BINARIES_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.CRI_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.CNI_BINARIES_DIRECTORY, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.CNI_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.GOBETWEEN_BINARIES_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.LOAD_BALANCER_SUBDIRECTORY), false)
-	config.addAssetDirectory(utils.ARK_BINARIES_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.ARK_SUBDIRECTORY), false)
+	config.addAssetDirectory(utils.VELERO_BINARIES_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.VELERO_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.HOST_BINARIES_DIRECTORY, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, path.Join(config.GetRelativeAssetDirectory(utils.BINARIES_DIRECTORY), utils.HOST_SUBDIRECTORY), false)
 
 	// Misc
 	config.addAssetDirectory(utils.GOBETWEEN_CONFIG_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.CONFIG_DIRECTORY), utils.LOAD_BALANCER_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY, Labels{}, path.Join(utils.VARIABLE_SUBDIRECTORY, utils.LIBRARY_SUBDIRECTORY, utils.K8S_TEW_SUBDIRECTORY), false)
-	config.addAssetDirectory(utils.ETCD_DATA_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.ETCD_SUBDIRECTORY), false)
-	config.addAssetDirectory(utils.CONTAINERD_DATA_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.CONTAINERD_SUBDIRECTORY), false)
-	config.addAssetDirectory(utils.KUBELET_DATA_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.KUBELET_SUBDIRECTORY), true)
-	config.addAssetDirectory(utils.PODS_DATA_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.KUBELET_DATA_DIRECTORY), utils.PODS_SUBDIRECTORY), false)
+	etcdDataDirectory, etcdDataDirectoryAbsolute := config.dataDirectoryOverride(config.Config.ETCDDataDirectory, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.ETCD_SUBDIRECTORY))
+	config.addAssetDirectory(utils.ETCD_DATA_DIRECTORY, Labels{}, etcdDataDirectory, etcdDataDirectoryAbsolute)
+	config.addAssetDirectory(utils.ETCD_SNAPSHOTS_DIRECTORY, Labels{}, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.ETCD_SNAPSHOTS_DIRECTORY), false)
+	containerdDataDirectory, containerdDataDirectoryAbsolute := config.dataDirectoryOverride(config.Config.ContainerdDataDirectory, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.CONTAINERD_SUBDIRECTORY))
+	config.addAssetDirectory(utils.CONTAINERD_DATA_DIRECTORY, Labels{}, containerdDataDirectory, containerdDataDirectoryAbsolute)
+	kubeletDataDirectory, _ := config.dataDirectoryOverride(config.Config.KubeletDataDirectory, path.Join(config.GetRelativeAssetDirectory(utils.DYNAMIC_DATA_DIRECTORY), utils.KUBELET_SUBDIRECTORY))
+	config.addAssetDirectory(utils.KUBELET_DATA_DIRECTORY, Labels{}, kubeletDataDirectory, true)
+	podsDataDirectory, podsDataDirectoryAbsolute := config.dataDirectoryOverride(config.Config.PodsDataDirectory, path.Join(config.GetRelativeAssetDirectory(utils.KUBELET_DATA_DIRECTORY), utils.PODS_SUBDIRECTORY))
+	config.addAssetDirectory(utils.PODS_DATA_DIRECTORY, Labels{}, podsDataDirectory, podsDataDirectoryAbsolute)
 	config.addAssetDirectory(utils.LOGGING_DIRECTORY, Labels{}, path.Join(utils.VARIABLE_SUBDIRECTORY, utils.LOGGING_SUBDIRECTORY, utils.K8S_TEW_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.SERVICE_DIRECTORY, Labels{}, path.Join(utils.CONFIG_SUBDIRECTORY, utils.SYSTEMD_SUBDIRECTORY, utils.SYSTEM_SUBDIRECTORY), false)
 	config.addAssetDirectory(utils.CONTAINERD_STATE_DIRECTORY, Labels{}, path.Join(utils.VARIABLE_SUBDIRECTORY, utils.RUN_SUBDIRECTORY, utils.K8S_TEW_SUBDIRECTORY, utils.CONTAINERD_SUBDIRECTORY), false)
@@ -212,8 +247,8 @@ func (config *InternalConfig) registerAssetFiles() {
 	// Gobetween Binary
 	config.addAssetFile(utils.GOBETWEEN_BINARY, Labels{utils.NODE_CONTROLLER}, "", utils.GOBETWEEN_BINARIES_DIRECTORY)
 
-	// Ark Binaries
-	config.addAssetFile(utils.ARK_BINARY, Labels{}, "", utils.ARK_BINARIES_DIRECTORY)
+	// Velero Binaries
+	config.addAssetFile(utils.VELERO_BINARY, Labels{}, "", utils.VELERO_BINARIES_DIRECTORY)
 
 	// Certificates
 	config.addAssetFile(utils.CA_PEM, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, "", utils.CERTIFICATES_DIRECTORY)
@@ -244,6 +279,15 @@ func (config *InternalConfig) registerAssetFiles() {
 
 	// Security
 	config.addAssetFile(utils.ENCRYPTION_CONFIG, Labels{utils.NODE_CONTROLLER}, "", utils.K8S_SECURITY_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.AUDIT_POLICY, Labels{utils.NODE_CONTROLLER}, "", utils.K8S_SECURITY_CONFIG_DIRECTORY)
+
+	if config.Config.Tracing.Enabled {
+		config.addAssetFile(utils.TRACING_CONFIG, Labels{utils.NODE_CONTROLLER}, "", utils.K8S_SECURITY_CONFIG_DIRECTORY)
+	}
+
+	if config.Config.OIDC.Enabled && len(config.Config.OIDC.CAFilename) > 0 {
+		config.addAssetFile(utils.OIDC_CA_PEM, Labels{utils.NODE_CONTROLLER}, "", utils.K8S_SECURITY_CONFIG_DIRECTORY)
+	}
 
 	// CRI
 	config.addAssetFile(utils.CONTAINERD_CONFIG, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, "", utils.CRI_CONFIG_DIRECTORY)
@@ -259,16 +303,22 @@ func (config *InternalConfig) registerAssetFiles() {
 	config.addAssetFile(utils.CEPH_SECRETS, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.CEPH_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.CEPH_CSI, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.NFS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.LOCAL_PATH_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.LETSENCRYPT_CLUSTER_ISSUER, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_CALICO_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.K8S_CILIUM_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.K8S_WEAVE_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_COREDNS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_ELASTICSEARCH_OPERATOR_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_EFK_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
-	config.addAssetFile(utils.K8S_ARK_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.K8S_VELERO_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_HEAPSTER_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_KUBERNETES_DASHBOARD_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_CERT_MANAGER_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_NGINX_INGRESS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.K8S_METALLB_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	config.addAssetFile(utils.K8S_TRAEFIK_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_METRICS_SERVER_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_PROMETHEUS_OPERATOR_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_KUBE_PROMETHEUS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
@@ -284,6 +334,10 @@ func (config *InternalConfig) registerAssetFiles() {
 	config.addAssetFile(utils.K8S_KUBE_PROMETHEUS_NODES_DASHBOARD_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.WORDPRESS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
 
+	if len(config.Config.RegistryAuths) > 0 {
+		config.addAssetFile(utils.K8S_REGISTRY_CREDENTIALS_SETUP, Labels{}, "", utils.K8S_SETUP_CONFIG_DIRECTORY)
+	}
+
 	// K8S Config
 	config.addAssetFile(utils.K8S_KUBE_SCHEDULER_CONFIG, Labels{utils.NODE_CONTROLLER}, "", utils.K8S_CONFIG_DIRECTORY)
 	config.addAssetFile(utils.K8S_KUBELET_CONFIG, Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, "", utils.K8S_CONFIG_DIRECTORY)
@@ -308,12 +362,22 @@ func (config *InternalConfig) registerAssetFiles() {
 	config.addAssetFile(utils.BASH_COMPLETION_KUBECTL, Labels{utils.NODE_CONTROLLER}, "", utils.BASH_COMPLETION_DIRECTORY)
 	config.addAssetFile(utils.BASH_COMPLETION_CRICTL, Labels{utils.NODE_CONTROLLER}, "", utils.BASH_COMPLETION_DIRECTORY)
 	config.addAssetFile(utils.BASH_COMPLETION_HELM, Labels{}, "", utils.BASH_COMPLETION_DIRECTORY)
-	config.addAssetFile(utils.BASH_COMPLETION_ARK, Labels{}, "", utils.BASH_COMPLETION_DIRECTORY)
+	config.addAssetFile(utils.BASH_COMPLETION_VELERO, Labels{}, "", utils.BASH_COMPLETION_DIRECTORY)
+
+	// Additional trusted CAs
+	for index := range config.Config.AdditionalTrustedCAs {
+		config.addAssetFile(config.GetAdditionalTrustedCAAssetName(index), Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, "", utils.ADDITIONAL_CA_DIRECTORY)
+	}
+}
+
+// GetAdditionalTrustedCAAssetName returns the asset name under which the additional-trusted-cas entry at index is deployed
+func (config *InternalConfig) GetAdditionalTrustedCAAssetName(index int) string {
+	return fmt.Sprintf("additional-ca-%d.pem", index)
 }
 
 func (config *InternalConfig) registerServers() {
 	// Servers
-	config.addServer("etcd", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.ETCD_BINARY), map[string]string{
+	etcdArguments := map[string]string{
 		"name":                        "{{.Name}}",
 		"cert-file":                   config.GetTemplateAssetFilename(utils.KUBERNETES_PEM),
 		"key-file":                    config.GetTemplateAssetFilename(utils.KUBERNETES_KEY_PEM),
@@ -328,37 +392,65 @@ func (config *InternalConfig) registerServers() {
 		"listen-client-urls":          "https://{{.Node.IP}}:2379",
 		"advertise-client-urls":       "https://{{.Node.IP}}:2379",
 		"initial-cluster-token":       "etcd-cluster",
-		"initial-cluster":             "{{etcd_cluster}}",
-		"initial-cluster-state":       "new",
 		"data-dir":                    config.GetTemplateAssetDirectory(utils.ETCD_DATA_DIRECTORY),
-	})
+	}
+
+	// DNS SRV based discovery lets peer membership survive node re-IPs, since
+	// members are looked up instead of being pinned to a static initial-cluster list
+	if len(config.Config.ETCDDiscoveryDomain) > 0 {
+		etcdArguments["discovery-srv"] = config.Config.ETCDDiscoveryDomain
+		etcdArguments["discovery-srv-name"] = "etcd-cluster"
+	} else {
+		etcdArguments["initial-cluster"] = "{{etcd_cluster}}"
+		etcdArguments["initial-cluster-state"] = "new"
+	}
+
+	// Tracing is opt-in and exports to an OTLP collector, useful for diagnosing slow requests across apiserver and etcd
+	if config.Config.Tracing.Enabled {
+		etcdArguments["experimental-enable-distributed-tracing"] = "true"
+		etcdArguments["experimental-distributed-tracing-address"] = config.Config.Tracing.OTLPEndpoint
+		etcdArguments["experimental-distributed-tracing-service-name"] = "etcd"
+	}
+
+	// An external etcd cluster is managed outside of k8s-tew, so it doesn't run one itself
+	if !config.Config.ExternalETCD.Enabled() {
+		config.addServer("etcd", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.ETCD_BINARY), etcdArguments, nil)
+	}
 
 	config.addServer("containerd", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, config.GetTemplateAssetFilename(utils.CONTAINERD_BINARY), map[string]string{
 		"config": config.GetTemplateAssetFilename(utils.CONTAINERD_CONFIG),
-	})
+	}, config.getProxyEnvironment())
 
 	config.addServer("gobetween", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.GOBETWEEN_BINARY), map[string]string{
 		"config": config.GetTemplateAssetFilename(utils.GOBETWEEN_CONFIG),
-	})
+	}, nil)
+
+	kubeAPIServerAdmissionPlugins := "Initializers,NamespaceLifecycle,NodeRestriction,LimitRanger,ServiceAccount,DefaultStorageClass,ResourceQuota"
+
+	// PodSecurity is only worth enforcing once at least one namespace has levels configured
+	if len(config.Config.PodSecurityNamespaces) > 0 {
+		kubeAPIServerAdmissionPlugins += ",PodSecurity"
+	}
 
-	config.addServer("kube-apiserver", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.KUBE_APISERVER_BINARY), map[string]string{
+	kubeAPIServerArguments := map[string]string{
 		"allow-privileged":                        "true",
 		"advertise-address":                       "{{.Node.IP}}",
 		"apiserver-count":                         "{{controllers_count}}",
-		"audit-log-maxage":                        "30",
-		"audit-log-maxbackup":                     "3",
-		"audit-log-maxsize":                       "100",
+		"audit-log-maxage":                        fmt.Sprintf("%d", config.Config.Audit.MaxAge),
+		"audit-log-maxbackup":                     fmt.Sprintf("%d", config.Config.Audit.MaxBackup),
+		"audit-log-maxsize":                       fmt.Sprintf("%d", config.Config.Audit.MaxSize),
 		"audit-log-path":                          path.Join(config.GetTemplateAssetDirectory(utils.LOGGING_DIRECTORY), utils.AUDIT_LOG),
+		"audit-policy-file":                       config.GetTemplateAssetFilename(utils.AUDIT_POLICY),
 		"authorization-mode":                      "Node,RBAC",
 		"bind-address":                            "0.0.0.0",
 		"secure-port":                             "{{.Config.APIServerPort}}",
 		"client-ca-file":                          config.GetTemplateAssetFilename(utils.CA_PEM),
-		"enable-admission-plugins":                "Initializers,NamespaceLifecycle,NodeRestriction,LimitRanger,ServiceAccount,DefaultStorageClass,ResourceQuota",
+		"enable-admission-plugins":                kubeAPIServerAdmissionPlugins,
 		"enable-aggregator-routing":               "true",
 		"enable-swagger-ui":                       "true",
-		"etcd-cafile":                             config.GetTemplateAssetFilename(utils.CA_PEM),
-		"etcd-certfile":                           config.GetTemplateAssetFilename(utils.KUBERNETES_PEM),
-		"etcd-keyfile":                            config.GetTemplateAssetFilename(utils.KUBERNETES_KEY_PEM),
+		"etcd-cafile":                             config.getETCDCAFile(),
+		"etcd-certfile":                           config.getETCDCertFile(),
+		"etcd-keyfile":                            config.getETCDKeyFile(),
 		"etcd-servers":                            "{{etcd_servers}}",
 		"event-ttl":                               "1h",
 		"experimental-encryption-provider-config": config.GetTemplateAssetFilename(utils.ENCRYPTION_CONFIG),
@@ -380,14 +472,39 @@ func (config *InternalConfig) registerServers() {
 		"requestheader-extra-headers-prefix":      "X-Remote-Extra-",
 		"requestheader-group-headers":             "X-Remote-Group",
 		"requestheader-username-headers":          "X-Remote-User",
-		"v": "0",
-	})
+		"v":                                       fmt.Sprintf("%d", config.Config.LogVerbosity.APIServer),
+	}
+
+	if config.Config.Tracing.Enabled {
+		kubeAPIServerArguments["tracing-config-file"] = config.GetTemplateAssetFilename(utils.TRACING_CONFIG)
+	}
+
+	if config.Config.OIDC.Enabled {
+		kubeAPIServerArguments["oidc-issuer-url"] = config.Config.OIDC.IssuerURL
+		kubeAPIServerArguments["oidc-client-id"] = config.Config.OIDC.ClientID
+
+		if len(config.Config.OIDC.UsernameClaim) > 0 {
+			kubeAPIServerArguments["oidc-username-claim"] = config.Config.OIDC.UsernameClaim
+		}
+
+		if len(config.Config.OIDC.GroupsClaim) > 0 {
+			kubeAPIServerArguments["oidc-groups-claim"] = config.Config.OIDC.GroupsClaim
+		}
+
+		if len(config.Config.OIDC.CAFilename) > 0 {
+			kubeAPIServerArguments["oidc-ca-file"] = config.GetTemplateAssetFilename(utils.OIDC_CA_PEM)
+		}
+	}
+
+	config.addServer("kube-apiserver", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.KUBE_APISERVER_BINARY), kubeAPIServerArguments, nil)
+	config.setReadinessProbe("kube-apiserver", "{{.Node.IP}}:{{.Config.APIServerPort}}", 5)
 
 	config.addServer("kube-controller-manager", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.KUBE_CONTROLLER_MANAGER_BINARY), map[string]string{
 		"address":                          "0.0.0.0",
 		"allocate-node-cidrs":              "true",
 		"cluster-cidr":                     "{{.Config.ClusterCIDR}}",
 		"cluster-name":                     "kubernetes",
+		"node-cidr-mask-size":              fmt.Sprintf("%d", config.Config.NodeCIDRMaskSize),
 		"cluster-signing-cert-file":        config.GetTemplateAssetFilename(utils.CA_PEM),
 		"cluster-signing-key-file":         config.GetTemplateAssetFilename(utils.CA_KEY_PEM),
 		"kubeconfig":                       config.GetTemplateAssetFilename(utils.CONTROLLER_MANAGER_KUBECONFIG),
@@ -396,20 +513,20 @@ func (config *InternalConfig) registerServers() {
 		"service-account-private-key-file": config.GetTemplateAssetFilename(utils.SERVICE_ACCOUNT_KEY_PEM),
 		"service-cluster-ip-range":         "{{.Config.ClusterIPRange}}",
 		"use-service-account-credentials":  "true",
-		"v": "0",
-	})
+		"v":                                fmt.Sprintf("%d", config.Config.LogVerbosity.ControllerManager),
+	}, nil)
 
 	config.addServer("kube-scheduler", Labels{utils.NODE_CONTROLLER}, config.GetTemplateAssetFilename(utils.KUBE_SCHEDULER_BINARY), map[string]string{
 		"config": config.GetTemplateAssetFilename(utils.K8S_KUBE_SCHEDULER_CONFIG),
-		"v":      "0",
-	})
+		"v":      fmt.Sprintf("%d", config.Config.LogVerbosity.Scheduler),
+	}, nil)
 
 	config.addServer("kube-proxy", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, config.GetTemplateAssetFilename(utils.KUBE_PROXY_BINARY), map[string]string{
 		"cluster-cidr": "{{.Config.ClusterCIDR}}",
 		"kubeconfig":   config.GetTemplateAssetFilename(utils.PROXY_KUBECONFIG),
 		"proxy-mode":   "iptables",
-		"v":            "0",
-	})
+		"v":            fmt.Sprintf("%d", config.Config.LogVerbosity.Proxy),
+	}, nil)
 
 	config.addServer("kubelet", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, config.GetTemplateAssetFilename(utils.KUBELET_BINARY), map[string]string{
 		"config":                       config.GetTemplateAssetFilename(utils.K8S_KUBELET_CONFIG),
@@ -417,64 +534,138 @@ func (config *InternalConfig) registerServers() {
 		"container-runtime-endpoint":   "unix://" + config.GetTemplateAssetFilename(utils.CONTAINERD_SOCK),
 		"fail-swap-on":                 "false",
 		"feature-gates":                "KubeletPluginsWatcher=true,CSIBlockVolume=true,BlockVolume=true",
-		"image-pull-progress-deadline": "2m",
+		"image-pull-progress-deadline": config.Config.ImagePullProgressDeadline,
 		"kubeconfig":                   config.GetTemplateAssetFilename(utils.KUBELET_KUBECONFIG),
+		"max-pods":                     fmt.Sprintf("%d", config.Config.MaxPods),
 		"network-plugin":               "cni",
 		"register-node":                "true",
 		"resolv-conf":                  "{{.Config.ResolvConf}}",
 		"root-dir":                     config.GetTemplateAssetDirectory(utils.KUBELET_DATA_DIRECTORY),
 		"read-only-port":               "10255",
-		"v":                            "0",
-	})
+		"serialize-image-pulls":        fmt.Sprintf("%t", config.Config.SerializeImagePulls),
+		"v":                            fmt.Sprintf("%d", config.Config.LogVerbosity.Kubelet),
+	}, nil)
+}
+
+// applyManifestCommand applies a manifest and, if it defines any CustomResourceDefinitions, waits for them to
+// become Established before re-applying the manifest. This is what makes a manifest that creates a CRD and a
+// custom resource using it in the same file converge: the custom resource fails to apply until its CRD is
+// established, and the command's normal retry loop (see Servers.runCommand/Deployment.runCommand) re-runs this
+// whole command until it does
+func applyManifestCommand(kubectlCommand, applyCommand, filename string) string {
+	return fmt.Sprintf(`%s %s; crds=$(%s get -f %s --output jsonpath='{range .items[?(@.kind=="CustomResourceDefinition")]}{.metadata.name} {end}' 2>/dev/null); for crd in $crds; do %s get crd $crd --output jsonpath='{.status.conditions[?(@.type=="Established")].status}' 2>/dev/null | grep -q True || exit 1; done; %s %s`, applyCommand, filename, kubectlCommand, filename, kubectlCommand, applyCommand, filename)
 }
 
 func (config *InternalConfig) registerCommands() {
 	kubectlCommand := fmt.Sprintf("%s --request-timeout 30s --kubeconfig %s", config.GetFullLocalAssetFilename(utils.KUBECTL_BINARY), config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG))
 	helmCommand := fmt.Sprintf("KUBECONFIG=%s HELM_HOME=%s %s", config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG), config.GetFullLocalAssetDirectory(utils.HELM_DATA_DIRECTORY), config.GetFullLocalAssetFilename(utils.HELM_BINARY))
 
+	// Server-side apply handles large CRDs (e.g. prometheus-operator's) that hit the client-side apply annotation size limit
+	applyCommand := kubectlCommand + " apply -f"
+
+	if config.Config.ApplyMode == utils.APPLY_MODE_SERVER_SIDE {
+		applyCommand = kubectlCommand + " apply --server-side --force-conflicts -f"
+	}
+
 	// Dependencies
 	config.addCommand("setup-ubuntu", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_UBUNTU}, "apt-get update && DEBIAN_FRONTEND=noninteractive apt-get install -y apt-transport-https bash-completion")
-	config.addCommand("setup-centos", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, "systemctl disable firewalld && systemctl stop firewalld && setenforce 0 && sed -i --follow-symlinks 's/SELINUX=enforcing/SELINUX=disabled/g' /etc/sysconfig/selinux")
+
+	if config.Config.Firewall {
+		config.addCommand("setup-centos", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, "setenforce 0 && sed -i --follow-symlinks 's/SELINUX=enforcing/SELINUX=disabled/g' /etc/sysconfig/selinux")
+	} else {
+		config.addCommand("setup-centos", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, "systemctl disable firewalld && systemctl stop firewalld && setenforce 0 && sed -i --follow-symlinks 's/SELINUX=enforcing/SELINUX=disabled/g' /etc/sysconfig/selinux")
+	}
+
 	config.addCommand("setup-centos-disable-selinux", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, "setenforce 0")
+
+	if config.Config.Firewall {
+		config.addCommand("firewall-ubuntu", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_UBUNTU}, fmt.Sprintf(`apt-get install -y ufw && ufw allow 22/tcp && {{if .Node.IsController}}ufw allow 2379/tcp && ufw allow 2380/tcp && ufw allow %d/tcp && ufw allow %d/tcp && {{end}}{{if .Node.IsWorker}}ufw allow %d/tcp && {{end}}ufw allow 10250/tcp && ufw allow %d/tcp && ufw --force enable`, config.Config.APIServerPort, config.Config.VIPRaftControllerPort, config.Config.VIPRaftWorkerPort, config.Config.LoadBalancerPort))
+		config.addCommand("firewall-centos", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, fmt.Sprintf(`systemctl enable firewalld && systemctl start firewalld && firewall-cmd --permanent --add-port=22/tcp {{if .Node.IsController}}--add-port=2379/tcp --add-port=2380/tcp --add-port=%d/tcp --add-port=%d/tcp {{end}}{{if .Node.IsWorker}}--add-port=%d/tcp {{end}}--add-port=10250/tcp --add-port=%d/tcp && firewall-cmd --reload`, config.Config.APIServerPort, config.Config.VIPRaftControllerPort, config.Config.VIPRaftWorkerPort, config.Config.LoadBalancerPort))
+	}
+
+	// Additional trusted CAs are deployed next to the node's own certificates, from where they get picked up into the OS trust store
+	if len(config.Config.AdditionalTrustedCAs) > 0 {
+		additionalCADirectory := config.GetTemplateAssetDirectory(utils.ADDITIONAL_CA_DIRECTORY)
+
+		config.addCommand("trust-additional-cas-ubuntu", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_UBUNTU}, fmt.Sprintf(`mkdir -p /usr/local/share/ca-certificates/k8s-tew && for file in %s/*.pem; do cp "$file" "/usr/local/share/ca-certificates/k8s-tew/$(basename "$file" .pem).crt"; done && update-ca-certificates`, additionalCADirectory))
+		config.addCommand("trust-additional-cas-centos", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{utils.OS_CENTOS}, fmt.Sprintf(`cp %s/*.pem /etc/pki/ca-trust/source/anchors/ && update-ca-trust extract`, additionalCADirectory))
+	}
 	config.addCommand("swapoff", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "swapoff -a")
+
+	if config.Config.DisableSwapFstab {
+		// swapoff only disables swap until the next reboot; this persists it by commenting the swap entries out of
+		// fstab, backing the original file up once so it can be restored manually if needed
+		config.addCommand("disable-swap-fstab", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, `matches=$(grep -n '^[^#].*[[:space:]]swap[[:space:]]' /etc/fstab); if [ -n "$matches" ]; then cp -n /etc/fstab /etc/fstab.k8s-tew-backup; sed -i '/^[^#].*[[:space:]]swap[[:space:]]/ s/^/# disabled by k8s-tew: /' /etc/fstab; echo "Disabled fstab swap entries: $matches"; fi`)
+	}
+
 	config.addCommand("load-overlay", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "modprobe overlay")
 	config.addCommand("load-btrfs", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "modprobe btrfs")
 	config.addCommand("load-br_netfilter", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "modprobe br_netfilter")
 	config.addCommand("enable-br_netfilter", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "echo '1' > /proc/sys/net/bridge/bridge-nf-call-iptables")
 	config.addCommand("enable-net-forwarding", Labels{utils.NODE_CONTROLLER, utils.NODE_WORKER}, Features{}, OS{}, "sysctl net.ipv4.conf.all.forwarding=1")
-	config.addCommand("kubelet-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBELET_SETUP)))
-	config.addCommand("admin-user-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_ADMIN_USER_SETUP)))
-	config.addCommand("calico-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_CALICO_SETUP)))
-	config.addCommand("coredns-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_COREDNS_SETUP)))
-	config.addCommand("helm-user-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_PACKAGING}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_HELM_USER_SETUP)))
-	config.addCommand("ceph-secrets", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.CEPH_SECRETS)))
-	config.addCommand("ceph-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.CEPH_SETUP)))
-	config.addCommand("ceph-csi", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.CEPH_CSI)))
+	config.addCommand("kubelet-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBELET_SETUP)))
+	config.addCommand("admin-user-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_ADMIN_USER_SETUP)))
+	switch config.Config.CNI {
+	case utils.CNI_CILIUM:
+		config.addCommand("cilium-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_CILIUM_SETUP)))
+
+	case utils.CNI_WEAVE:
+		config.addCommand("weave-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_WEAVE_SETUP)))
+
+	default:
+		config.addCommand("calico-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_CALICO_SETUP)))
+	}
+	config.addCommand("coredns-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_COREDNS_SETUP)))
+	config.addCommand("helm-user-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_PACKAGING}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_HELM_USER_SETUP)))
+	switch config.Config.StorageBackend {
+	case utils.STORAGE_BACKEND_NFS:
+		config.addCommand("nfs-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.NFS_SETUP)))
+
+	case utils.STORAGE_BACKEND_LOCAL_PATH:
+		config.addCommand("local-path-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.LOCAL_PATH_SETUP)))
+
+	default:
+		config.addCommand("ceph-secrets", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.CEPH_SECRETS)))
+		config.addCommand("ceph-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.CEPH_SETUP)))
+		config.addCommand("ceph-csi", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.CEPH_CSI)))
+	}
 	config.addCommand("helm-init", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_PACKAGING}, OS{}, fmt.Sprintf("%s init --service-account %s --upgrade", helmCommand, utils.HELM_SERVICE_ACCOUNT))
-	config.addCommand("kubernetes-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBERNETES_DASHBOARD_SETUP)))
-	config.addCommand("cert-manager-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_CERT_MANAGER_SETUP)))
-	config.addCommand("nginx-ingress-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_NGINX_INGRESS_SETUP)))
-	config.addCommand("letsencrypt-cluster-issuer-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.LETSENCRYPT_CLUSTER_ISSUER)))
-	config.addCommand("heapster-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_HEAPSTER_SETUP)))
-	config.addCommand("metrics-server-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_METRICS_SERVER_SETUP)))
-	config.addCommand("prometheus-operator-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_PROMETHEUS_OPERATOR_SETUP)))
-	config.addCommand("kube-prometheus-datasource-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_DATASOURCE_SETUP)))
-	config.addCommand("kube-prometheus-kuberntes-cluster-status-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_STATUS_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-kuberntes-cluster-health-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_HEALTH_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-kuberntes-control-plane-status-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CONTROL_PLANE_STATUS_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-kuberntes-capacity-planning-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CAPACITY_PLANNING_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-kuberntes-resource-requests-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_RESOURCE_REQUESTS_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-nodes-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_NODES_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-deployment-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_DEPLOYMENT_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-statefulset-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_STATEFULSET_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-pods-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_PODS_DASHBOARD_SETUP)))
-	config.addCommand("kube-prometheus-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_SETUP)))
-	config.addCommand("elasticsearch-operator-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_ELASTICSEARCH_OPERATOR_SETUP)))
-	config.addCommand("efk-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_EFK_SETUP)))
+	config.addCommand("kubernetes-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBERNETES_DASHBOARD_SETUP)))
+	config.addCommand("cert-manager-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_CERT_MANAGER_SETUP)))
+	if config.Config.IngressController == utils.INGRESS_CONTROLLER_TRAEFIK {
+		config.addCommand("traefik-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_TRAEFIK_SETUP)))
+	} else {
+		config.addCommand("nginx-ingress-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_NGINX_INGRESS_SETUP)))
+	}
+	config.addCommand("letsencrypt-cluster-issuer-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_INGRESS}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.LETSENCRYPT_CLUSTER_ISSUER)))
+	if config.Config.MetalLB.Enabled {
+		config.addCommand("metallb-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_METALLB}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_METALLB_SETUP)))
+	}
+	config.addCommand("heapster-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_HEAPSTER_SETUP)))
+	config.addCommand("metrics-server-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_METRICS_SERVER_SETUP)))
+	config.addCommand("prometheus-operator-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_PROMETHEUS_OPERATOR_SETUP)))
+	config.addCommand("kube-prometheus-datasource-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_DATASOURCE_SETUP)))
+	config.addCommand("kube-prometheus-kuberntes-cluster-status-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_STATUS_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-kuberntes-cluster-health-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_HEALTH_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-kuberntes-control-plane-status-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CONTROL_PLANE_STATUS_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-kuberntes-capacity-planning-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_CAPACITY_PLANNING_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-kuberntes-resource-requests-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_KUBERNETES_RESOURCE_REQUESTS_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-nodes-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_NODES_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-deployment-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_DEPLOYMENT_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-statefulset-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_STATEFULSET_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-pods-dashboard-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_PODS_DASHBOARD_SETUP)))
+	config.addCommand("kube-prometheus-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_SETUP)))
+	config.addCommand("elasticsearch-operator-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_ELASTICSEARCH_OPERATOR_SETUP)))
+	config.addCommand("efk-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_EFK_SETUP)))
 	config.addCommand("patch-kibana-service", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf(`%s get svc kibana-elasticsearch-cluster -n logging --output=jsonpath={.spec..nodePort} | grep %d || %s patch service kibana-elasticsearch-cluster -n logging -p '{"spec":{"type":"NodePort","ports":[{"port":80,"nodePort":%d}]}}'`, kubectlCommand, utils.PORT_KIBANA, kubectlCommand, utils.PORT_KIBANA))
 	config.addCommand("patch-cerebro-service", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf(`%s get svc cerebro-elasticsearch-cluster -n logging --output=jsonpath={.spec..nodePort} | grep %d || %s patch service cerebro-elasticsearch-cluster -n logging -p '{"spec":{"type":"NodePort","ports":[{"port":80,"nodePort":%d}]}}'`, kubectlCommand, utils.PORT_CEREBRO, kubectlCommand, utils.PORT_CEREBRO))
-	config.addCommand("ark-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_ARK_SETUP)))
-	config.addCommand("wordpress-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}, OS{}, fmt.Sprintf("%s apply -f %s", kubectlCommand, config.GetFullLocalAssetFilename(utils.WORDPRESS_SETUP)))
+	config.addCommand("velero-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.K8S_VELERO_SETUP)))
+	config.addCommand("wordpress-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}, OS{}, applyManifestCommand(kubectlCommand, applyCommand, config.GetFullLocalAssetFilename(utils.WORDPRESS_SETUP)))
+	if len(config.Config.RegistryAuths) > 0 {
+		// Secrets are namespaced, so the rendered manifest - which leaves metadata.namespace unset - is applied to
+		// every namespace in turn, and each namespace's default service account is patched to use them
+		config.addCommand("registry-credentials-setup", Labels{utils.NODE_BOOTSTRAPPER}, Features{}, OS{}, fmt.Sprintf(`for namespace in $(%s get namespaces -o jsonpath='{.items[*].metadata.name}'); do %s apply -f %s -n "$namespace" && %s patch serviceaccount default -n "$namespace" -p '%s'; done`, kubectlCommand, kubectlCommand, config.GetFullLocalAssetFilename(utils.K8S_REGISTRY_CREDENTIALS_SETUP), kubectlCommand, ImagePullSecretsPatch(config.Config.RegistryAuths)))
+	}
 }
 
 func (config *InternalConfig) Generate() {
@@ -484,7 +675,19 @@ func (config *InternalConfig) Generate() {
 	config.registerServers()
 }
 
-func (config *InternalConfig) addServer(name string, labels []string, command string, arguments map[string]string) {
+// setReadinessProbe enables a readiness probe on an already registered server, gating command execution
+// until the server named name accepts connections on endpoint or a probe attempt exceeds timeout seconds
+func (config *InternalConfig) setReadinessProbe(name, endpoint string, timeout uint) {
+	for index, server := range config.Config.Servers {
+		if server.Name == name {
+			config.Config.Servers[index].ReadinessProbe = ReadinessProbeConfig{Enabled: true, Endpoint: endpoint, Timeout: timeout}
+
+			return
+		}
+	}
+}
+
+func (config *InternalConfig) addServer(name string, labels []string, command string, arguments map[string]string, environment map[string]string) {
 	// Do not add if already in the list
 	for _, server := range config.Config.Servers {
 		if server.Name == name {
@@ -492,10 +695,41 @@ func (config *InternalConfig) addServer(name string, labels []string, command st
 		}
 	}
 
-	config.Config.Servers = append(config.Config.Servers, ServerConfig{Name: name, Enabled: true, Labels: labels, Command: command, Arguments: arguments, Logger: LoggerConfig{Enabled: true, Filename: path.Join(config.GetTemplateAssetDirectory(utils.LOGGING_DIRECTORY), name+".log")}})
+	config.Config.Servers = append(config.Config.Servers, ServerConfig{Name: name, Enabled: true, Labels: labels, Command: command, Arguments: arguments, Environment: environment, RestartPolicy: utils.RESTART_POLICY_ALWAYS, Logger: LoggerConfig{Enabled: true, Filename: path.Join(config.GetTemplateAssetDirectory(utils.LOGGING_DIRECTORY), name+".log")}})
+}
+
+// getProxyEnvironment returns the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment variables for a proxy-aware
+// server, derived from http-proxy/https-proxy/no-proxy, with no-proxy automatically extended to cover the
+// cluster CIDR, the cluster IP range and every node IP so in-cluster/node traffic never goes through the proxy
+func (config *InternalConfig) getProxyEnvironment() map[string]string {
+	if len(config.Config.HTTPProxy) == 0 && len(config.Config.HTTPSProxy) == 0 {
+		return nil
+	}
+
+	noProxy := []string{"localhost", "127.0.0.1", config.Config.ClusterCIDR, config.Config.ClusterIPRange}
+
+	if len(config.Config.NoProxy) > 0 {
+		noProxy = append(noProxy, config.Config.NoProxy)
+	}
+
+	for _, node := range config.Config.Nodes {
+		noProxy = append(noProxy, node.IP)
+	}
+
+	environment := map[string]string{"NO_PROXY": strings.Join(noProxy, ",")}
+
+	if len(config.Config.HTTPProxy) > 0 {
+		environment["HTTP_PROXY"] = config.Config.HTTPProxy
+	}
+
+	if len(config.Config.HTTPSProxy) > 0 {
+		environment["HTTPS_PROXY"] = config.Config.HTTPSProxy
+	}
+
+	return environment
 }
 
-func (config *InternalConfig) addCommand(name string, labels Labels, features Features, os OS, command string) {
+func (config *InternalConfig) addCommand(name string, labels Labels, features Features, os OS, command string, dependsOn ...string) {
 	// Do not add if already in the list
 	for _, command := range config.Config.Commands {
 		if command.Name == name {
@@ -503,7 +737,7 @@ func (config *InternalConfig) addCommand(name string, labels Labels, features Fe
 		}
 	}
 
-	config.Config.Commands = append(config.Config.Commands, NewCommand(name, labels, features, os, command))
+	config.Config.Commands = append(config.Config.Commands, NewCommand(name, labels, features, os, command, dependsOn...))
 }
 
 func (config *InternalConfig) addAssetFile(name string, labels Labels, filename, directory string) {
@@ -514,6 +748,17 @@ func (config *InternalConfig) addAssetDirectory(name string, labels Labels, dire
 	config.Config.Assets.Directories[name] = NewAssetDirectory(labels, directory, absolute)
 }
 
+// dataDirectoryOverride returns override if set, so a data directory such as kubelet-data can be pointed at an
+// absolute path outside the usual assets tree - e.g. a separate mounted volume on nodes with a small OS disk -
+// falling back to fallback otherwise. The directory is only absolute when it has actually been overridden
+func (config *InternalConfig) dataDirectoryOverride(override, fallback string) (string, bool) {
+	if len(override) == 0 {
+		return fallback, false
+	}
+
+	return override, true
+}
+
 func (config *InternalConfig) Dump() {
 	log.WithFields(log.Fields{"base-directory": config.BaseDirectory}).Info("Config")
 	log.WithFields(log.Fields{"name": config.Name}).Info("Config")
@@ -598,6 +843,167 @@ func (config *InternalConfig) Load() error {
 		return fmt.Errorf("Unsupported config version '%s'", config.Config.Version)
 	}
 
+	for namespace, levels := range config.Config.PodSecurityNamespaces {
+		if error := levels.Validate(); error != nil {
+			return fmt.Errorf("pod security namespace '%s': %s", namespace, error.Error())
+		}
+	}
+
+	for _, profile := range config.Config.SchedulerProfiles {
+		if error := profile.Validate(); error != nil {
+			return fmt.Errorf("scheduler profile: %s", error.Error())
+		}
+	}
+
+	for nodeName, node := range config.Config.Nodes {
+		for _, device := range node.Devices {
+			if !path.IsAbs(device) {
+				return fmt.Errorf("node '%s' has a non-absolute device path '%s'", nodeName, device)
+			}
+		}
+
+		if error := node.Validate(); error != nil {
+			return fmt.Errorf("node '%s': %s", nodeName, error.Error())
+		}
+	}
+
+	if error := config.Config.Tracing.Validate(); error != nil {
+		return error
+	}
+
+	if error := config.Config.Versions.Validate(); error != nil {
+		return error
+	}
+
+	config.Config.Versions.ApplyRegistryMirror(config.Config.RegistryMirror)
+
+	if error := ValidateStorageClasses(config.Config.StorageClasses); error != nil {
+		return error
+	}
+
+	if error := ValidateCephPools(config.Config.CephPools); error != nil {
+		return error
+	}
+
+	if error := config.Config.ExternalETCD.Validate(); error != nil {
+		return error
+	}
+
+	if error := ValidateStorageBackend(config.Config.StorageBackend, config.Config.NFSServer); error != nil {
+		return error
+	}
+
+	if error := ValidateBackupSchedules(config.Config.BackupSchedules); error != nil {
+		return error
+	}
+
+	if error := ValidateStubDomains(config.Config.CoreDNSStubDomains); error != nil {
+		return error
+	}
+
+	if error := ValidateCoreDNSUpstreamForwards(config.Config.CoreDNSUpstreamForwards); error != nil {
+		return error
+	}
+
+	if error := ValidatePrometheusDuration("prometheus-retention", config.Config.PrometheusRetention); error != nil {
+		return error
+	}
+
+	if error := ValidateStorageSize("prometheus-storage-size", config.Config.PrometheusStorageSize); error != nil {
+		return error
+	}
+
+	if error := ValidateStorageSize("alertmanager-storage-size", config.Config.AlertManagerStorageSize); error != nil {
+		return error
+	}
+
+	if error := ValidateAlertmanagerReceiversAndRoutes(config.Config.AlertmanagerReceivers, config.Config.AlertmanagerRoutes); error != nil {
+		return error
+	}
+
+	if error := config.Config.MetalLB.Validate(); error != nil {
+		return error
+	}
+
+	if error := ValidateEncryptionProvider(config.Config.EncryptionProvider); error != nil {
+		return error
+	}
+
+	if error := ValidateEncryptionKMSEndpoint(config.Config.EncryptionKMSEndpoint); error != nil {
+		return error
+	}
+
+	if error := ValidateDataDirectories(map[string]string{
+		"etcd-data-directory":       config.Config.ETCDDataDirectory,
+		"containerd-data-directory": config.Config.ContainerdDataDirectory,
+		"kubelet-data-directory":    config.Config.KubeletDataDirectory,
+		"pods-data-directory":       config.Config.PodsDataDirectory,
+	}); error != nil {
+		return error
+	}
+
+	for _, server := range config.Config.Servers {
+		if error := server.Validate(); error != nil {
+			return error
+		}
+	}
+
+	if error := config.Config.OIDC.Validate(); error != nil {
+		return error
+	}
+
+	if len(config.Config.ApplyMode) > 0 && config.Config.ApplyMode != utils.APPLY_MODE_CLIENT_SIDE && config.Config.ApplyMode != utils.APPLY_MODE_SERVER_SIDE {
+		return fmt.Errorf("invalid apply mode '%s'", config.Config.ApplyMode)
+	}
+
+	if config.Config.ImageGCHighThresholdPercent <= config.Config.ImageGCLowThresholdPercent {
+		return fmt.Errorf("image-gc-high-threshold-percent (%d) has to be greater than image-gc-low-threshold-percent (%d)", config.Config.ImageGCHighThresholdPercent, config.Config.ImageGCLowThresholdPercent)
+	}
+
+	if error := ValidateContainerdRuntimeHandlers(config.Config.ContainerdRuntimeHandlers); error != nil {
+		return error
+	}
+
+	if error := ValidateRegistryAuths(config.Config.RegistryAuths); error != nil {
+		return error
+	}
+
+	if error := ValidateClusterName(config.Config.ClusterName); error != nil {
+		return error
+	}
+
+	if error := ValidateCNI(config.Config.CNI); error != nil {
+		return error
+	}
+
+	if error := ValidateIngressController(config.Config.IngressController); error != nil {
+		return error
+	}
+
+	if error := ValidateLoggingBackend(config.Config.LoggingBackend); error != nil {
+		return error
+	}
+
+	if error := ValidateControllerTaints(config.Config.ControllerTaints); error != nil {
+		return error
+	}
+
+	if error := ValidateIngressHosts(config.Config.IngressHosts); error != nil {
+		return error
+	}
+
+	if error := ValidateAPIServerExtraIPs(config.Config.APIServerExtraIPs); error != nil {
+		return error
+	}
+
+	if error := ValidateCertificateAlgorithm(config.Config.CertificateAlgorithm, config.Config.CertificateECDSACurve); error != nil {
+		return error
+	}
+
+	if _, error := time.ParseDuration(config.Config.ImagePullProgressDeadline); error != nil {
+		return fmt.Errorf("invalid image-pull-progress-deadline '%s' (%s)", config.Config.ImagePullProgressDeadline, error.Error())
+	}
+
 	if len(config.Name) == 0 {
 		config.Name, error = os.Hostname()
 
@@ -621,17 +1027,58 @@ func (config *InternalConfig) Load() error {
 	return nil
 }
 
-func (config *InternalConfig) RemoveNode(name string) error {
-	if _, ok := config.Config.Nodes[name]; !ok {
-		return errors.New("node not found")
+// RemoveNode removes name from the cluster's node list, refusing to remove the last controller since that would
+// leave the cluster without a control plane, and warning when the removal would leave etcd with less than the
+// recommended 3 controllers to keep its quorum tolerant of a further failure. It also deletes the kubelet
+// certificate, kubeconfig and kubelet configuration files generated locally for the node, so stale credentials for a
+// node that no longer exists don't linger in the local assets directory. It is up to the caller to also drain and
+// delete the corresponding Kubernetes node object and, if the node was a controller, remove its etcd member.
+func (config *InternalConfig) RemoveNode(name string) (*Node, error) {
+	node, ok := config.Config.Nodes[name]
+	if !ok {
+		return nil, errors.New("node not found")
+	}
+
+	if node.IsController() {
+		controllersCount := 0
+
+		for _, otherNode := range config.Config.Nodes {
+			if otherNode.IsController() {
+				controllersCount++
+			}
+		}
+
+		if controllersCount <= 1 {
+			return nil, errors.New("cannot remove the last controller")
+		}
+
+		if controllersCount-1 < 3 {
+			log.WithFields(log.Fields{"node": name, "remaining-controllers": controllersCount - 1}).Warn("Removing this controller leaves etcd with less than 3 controllers, so losing one more would break quorum")
+		}
 	}
 
 	delete(config.Config.Nodes, name)
 
-	return nil
+	config.removeNodeAssetFiles(name, node)
+
+	return node, nil
+}
+
+// removeNodeAssetFiles deletes the local, per-node kubelet certificate, kubeconfig and kubelet configuration files
+// belonging to a node that was just removed from the cluster
+func (config *InternalConfig) removeNodeAssetFiles(name string, node *Node) {
+	config.SetNode(name, node)
+
+	for _, assetName := range []string{utils.KUBELET_PEM, utils.KUBELET_KEY_PEM, utils.KUBELET_KUBECONFIG, utils.K8S_KUBELET_CONFIG} {
+		filename := config.GetFullLocalAssetFilename(assetName)
+
+		if error := os.Remove(filename); error != nil && !os.IsNotExist(error) {
+			log.WithFields(log.Fields{"node": name, "filename": filename, "error": error}).Warn("Could not remove stale node asset")
+		}
+	}
 }
 
-func (config *InternalConfig) AddNode(name string, ip string, index uint, labels []string) (*Node, error) {
+func (config *InternalConfig) AddNode(name string, ip string, index uint, labels []string, arch string) (*Node, error) {
 	name = strings.Trim(name, " \n")
 
 	if len(name) == 0 {
@@ -642,12 +1089,25 @@ func (config *InternalConfig) AddNode(name string, ip string, index uint, labels
 		return nil, errors.New("invalid or wrong ip format")
 	}
 
-	config.Config.Nodes[name] = NewNode(ip, index, labels)
+	node := NewNode(ip, index, labels)
+	node.Arch = arch
+
+	if error := node.Validate(); error != nil {
+		return nil, error
+	}
+
+	config.Config.Nodes[name] = node
 
 	return config.Config.Nodes[name], nil
 }
 
+// GetETCDClientEndpoints returns the external etcd endpoints if configured, otherwise the endpoints of the
+// etcd server k8s-tew runs itself on every controller
 func (config *InternalConfig) GetETCDClientEndpoints() []string {
+	if config.Config.ExternalETCD.Enabled() {
+		return config.Config.ExternalETCD.Endpoints
+	}
+
 	result := []string{}
 
 	for _, node := range config.Config.Nodes {
@@ -659,6 +1119,35 @@ func (config *InternalConfig) GetETCDClientEndpoints() []string {
 	return result
 }
 
+// getETCDCAFile returns the CA kube-apiserver verifies the etcd server's certificate against - the external
+// etcd's CA if configured, otherwise k8s-tew's own cluster CA
+func (config *InternalConfig) getETCDCAFile() string {
+	if config.Config.ExternalETCD.Enabled() {
+		return config.Config.ExternalETCD.CAFile
+	}
+
+	return config.GetTemplateAssetFilename(utils.CA_PEM)
+}
+
+// getETCDCertFile returns the client certificate kube-apiserver authenticates to etcd with - the external
+// etcd's certificate if configured, otherwise k8s-tew's own kubernetes certificate
+func (config *InternalConfig) getETCDCertFile() string {
+	if config.Config.ExternalETCD.Enabled() {
+		return config.Config.ExternalETCD.CertFile
+	}
+
+	return config.GetTemplateAssetFilename(utils.KUBERNETES_PEM)
+}
+
+// getETCDKeyFile returns the private key matching getETCDCertFile
+func (config *InternalConfig) getETCDKeyFile() string {
+	if config.Config.ExternalETCD.Enabled() {
+		return config.Config.ExternalETCD.KeyFile
+	}
+
+	return config.GetTemplateAssetFilename(utils.KUBERNETES_KEY_PEM)
+}
+
 func (config *InternalConfig) ApplyTemplate(label string, value string) (string, error) {
 	var functions = template.FuncMap{
 		"controllers_count": func() string {
@@ -725,8 +1214,8 @@ func (config *InternalConfig) ApplyTemplate(label string, value string) (string,
 }
 
 func (config *InternalConfig) GetAPIServerIP() (string, error) {
-	if len(config.Config.ControllerVirtualIP) > 0 {
-		return config.Config.ControllerVirtualIP, nil
+	if len(config.Config.ControllerVirtualIPs) > 0 {
+		return config.Config.ControllerVirtualIPs[0].IP, nil
 	}
 
 	for _, node := range config.Config.Nodes {
@@ -739,8 +1228,8 @@ func (config *InternalConfig) GetAPIServerIP() (string, error) {
 }
 
 func (config *InternalConfig) GetWorkerIP() (string, error) {
-	if len(config.Config.WorkerVirtualIP) > 0 {
-		return config.Config.WorkerVirtualIP, nil
+	if len(config.Config.WorkerVirtualIPs) > 0 {
+		return config.Config.WorkerVirtualIPs[0].IP, nil
 	}
 
 	for _, node := range config.Config.Nodes {
@@ -764,6 +1253,31 @@ func (config *InternalConfig) GetSortedNodeKeys() []string {
 	return result
 }
 
+// GetArchs returns the sorted, deduplicated list of CPU architectures used by the configured nodes, so the
+// downloader knows which per-arch binaries to fetch for a mixed-architecture cluster. Defaults to
+// utils.DEFAULT_ARCH when no nodes are configured yet
+func (config *InternalConfig) GetArchs() []string {
+	archs := map[string]bool{}
+
+	for _, node := range config.Config.Nodes {
+		archs[node.GetArch()] = true
+	}
+
+	if len(archs) == 0 {
+		archs[utils.DEFAULT_ARCH] = true
+	}
+
+	result := []string{}
+
+	for arch := range archs {
+		result = append(result, arch)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
 func (config *InternalConfig) GetKubeAPIServerAddresses() []string {
 	result := []string{}
 
@@ -777,9 +1291,10 @@ func (config *InternalConfig) GetKubeAPIServerAddresses() []string {
 }
 
 type NodeData struct {
-	Index uint
-	Name  string
-	IP    string
+	Index   uint
+	Name    string
+	IP      string
+	Devices []string
 }
 
 func (config *InternalConfig) getLabeledOrAllNodes(label string) []NodeData {
@@ -788,7 +1303,7 @@ func (config *InternalConfig) getLabeledOrAllNodes(label string) []NodeData {
 	// Add only labeled nodes
 	for nodeName, node := range config.Config.Nodes {
 		if node.Labels.HasLabels(Labels{label}) && node.Labels.HasLabels(Labels{utils.NODE_STORAGE}) {
-			result = append(result, NodeData{Index: node.Index, Name: nodeName, IP: node.IP})
+			result = append(result, NodeData{Index: node.Index, Name: nodeName, IP: node.IP, Devices: node.Devices})
 		}
 	}
 
@@ -796,7 +1311,7 @@ func (config *InternalConfig) getLabeledOrAllNodes(label string) []NodeData {
 	if len(result) == 0 {
 		for nodeName, node := range config.Config.Nodes {
 			if node.Labels.HasLabels(Labels{label}) {
-				result = append(result, NodeData{Index: node.Index, Name: nodeName, IP: node.IP})
+				result = append(result, NodeData{Index: node.Index, Name: nodeName, IP: node.IP, Devices: node.Devices})
 			}
 		}
 	}
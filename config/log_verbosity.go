@@ -0,0 +1,11 @@
+package config
+
+// LogVerbosity holds the per-component "-v" log verbosity level, rendered into each component's server
+// arguments. Everything defaults to 0 (quiet) and can be raised temporarily to debug a specific component
+type LogVerbosity struct {
+	APIServer         uint8 `yaml:"apiserver,omitempty"`
+	ControllerManager uint8 `yaml:"controller-manager,omitempty"`
+	Scheduler         uint8 `yaml:"scheduler,omitempty"`
+	Proxy             uint8 `yaml:"proxy,omitempty"`
+	Kubelet           uint8 `yaml:"kubelet,omitempty"`
+}
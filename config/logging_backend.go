@@ -0,0 +1,16 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateLoggingBackend makes sure loggingBackend selects one of the logging backends k8s-tew knows how to set up
+func ValidateLoggingBackend(loggingBackend string) error {
+	if !utils.SupportedLoggingBackends[loggingBackend] {
+		return fmt.Errorf("unsupported logging backend '%s', expected one of %s, %s", loggingBackend, utils.LOGGING_BACKEND_EFK, utils.LOGGING_BACKEND_LOKI)
+	}
+
+	return nil
+}
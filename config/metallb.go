@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// MetalLBConfig configures an optional MetalLB layer2 deployment that hands out real IPs, taken from a range
+// within public-network, to Services of type LoadBalancer - something only the gobetween-based API server VIP
+// got before
+type MetalLBConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	AddressPoolStart string `yaml:"address-pool-start,omitempty"`
+	AddressPoolEnd   string `yaml:"address-pool-end,omitempty"`
+}
+
+// Validate makes sure address-pool-start and address-pool-end are set and parse as IPs, and that the range they
+// describe is not inverted, whenever MetalLB is enabled. Whether the range actually falls within public-network
+// and does not overlap any node ip or virtual ip is checked later, together with the rest of the cluster's
+// network layout, since that needs config.Config.Nodes and the virtual ip lists to be loaded as well
+func (metalLB MetalLBConfig) Validate() error {
+	if !metalLB.Enabled {
+		return nil
+	}
+
+	if len(metalLB.AddressPoolStart) == 0 || len(metalLB.AddressPoolEnd) == 0 {
+		return fmt.Errorf("metallb is enabled but address-pool-start and address-pool-end are not both set")
+	}
+
+	startIP := net.ParseIP(metalLB.AddressPoolStart)
+	if startIP == nil {
+		return fmt.Errorf("metallb has an invalid address-pool-start '%s'", metalLB.AddressPoolStart)
+	}
+
+	endIP := net.ParseIP(metalLB.AddressPoolEnd)
+	if endIP == nil {
+		return fmt.Errorf("metallb has an invalid address-pool-end '%s'", metalLB.AddressPoolEnd)
+	}
+
+	if compareIPs(startIP, endIP) > 0 {
+		return fmt.Errorf("metallb address-pool-start '%s' comes after address-pool-end '%s'", metalLB.AddressPoolStart, metalLB.AddressPoolEnd)
+	}
+
+	return nil
+}
+
+// AddressRange returns the address-pool-start/address-pool-end pair as the single "start-end" range MetalLB's
+// layer2 configmap expects under addresses
+func (metalLB MetalLBConfig) AddressRange() string {
+	return fmt.Sprintf("%s-%s", metalLB.AddressPoolStart, metalLB.AddressPoolEnd)
+}
+
+// compareIPs returns -1, 0 or 1 depending on whether a is lower than, equal to or higher than b, comparing both
+// as 16-byte addresses so an IPv4 address and its IPv4-in-IPv6 form compare equal
+func compareIPs(a, b net.IP) int {
+	a16 := a.To16()
+	b16 := b.To16()
+
+	for index := range a16 {
+		if a16[index] != b16[index] {
+			if a16[index] < b16[index] {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
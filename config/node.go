@@ -1,11 +1,22 @@
 package config
 
-import "github.com/darxkies/k8s-tew/utils"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/utils"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
 
 type Node struct {
-	IP     string `yaml:"ip"`
-	Index  uint   `yaml:"index"`
-	Labels Labels `yaml:"labels"`
+	IP                    string                 `yaml:"ip"`
+	Index                 uint                   `yaml:"index"`
+	Labels                Labels                 `yaml:"labels"`
+	Devices               []string               `yaml:"devices,omitempty"`
+	Arch                  string                 `yaml:"arch,omitempty"`
+	KubeletConfig         map[string]interface{} `yaml:"kubelet-config,omitempty"`
+	KubernetesLabels      map[string]string      `yaml:"kubernetes-labels,omitempty"`
+	KubernetesAnnotations map[string]string      `yaml:"kubernetes-annotations,omitempty"`
 }
 
 type Nodes map[string]*Node
@@ -14,6 +25,41 @@ func NewNode(ip string, index uint, labels []string) *Node {
 	return &Node{IP: ip, Index: index, Labels: labels}
 }
 
+// GetArch returns the node's CPU architecture (e.g. amd64, arm64), defaulting to utils.DEFAULT_ARCH when unset
+func (node *Node) GetArch() string {
+	if len(node.Arch) == 0 {
+		return utils.DEFAULT_ARCH
+	}
+
+	return node.Arch
+}
+
+// Validate makes sure the node's architecture, if set, is one k8s-tew can download binaries for, and that
+// kubernetes-labels and kubernetes-annotations are valid Kubernetes keys and values
+func (node *Node) Validate() error {
+	if len(node.Arch) > 0 && !utils.SupportedArchs[node.Arch] {
+		return fmt.Errorf("unsupported arch '%s', expected one of amd64, arm64", node.Arch)
+	}
+
+	for key, value := range node.KubernetesLabels {
+		if errors := validation.IsQualifiedName(key); len(errors) > 0 {
+			return fmt.Errorf("kubernetes label key '%s' is invalid (%s)", key, strings.Join(errors, ", "))
+		}
+
+		if errors := validation.IsValidLabelValue(value); len(errors) > 0 {
+			return fmt.Errorf("kubernetes label '%s' has an invalid value '%s' (%s)", key, value, strings.Join(errors, ", "))
+		}
+	}
+
+	for key := range node.KubernetesAnnotations {
+		if errors := validation.IsQualifiedName(key); len(errors) > 0 {
+			return fmt.Errorf("kubernetes annotation key '%s' is invalid (%s)", key, strings.Join(errors, ", "))
+		}
+	}
+
+	return nil
+}
+
 func (node *Node) IsController() bool {
 	for _, label := range node.Labels {
 		if label == utils.NODE_CONTROLLER {
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OIDCConfig configures the apiserver's OIDC authentication flags for SSO access to the cluster
+type OIDCConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	IssuerURL     string `yaml:"issuer-url,omitempty"`
+	ClientID      string `yaml:"client-id,omitempty"`
+	UsernameClaim string `yaml:"username-claim,omitempty"`
+	GroupsClaim   string `yaml:"groups-claim,omitempty"`
+	CAFilename    string `yaml:"ca-filename,omitempty"`
+}
+
+// Validate makes sure issuer-url is HTTPS and client-id is set whenever OIDC is enabled
+func (oidc OIDCConfig) Validate() error {
+	if !oidc.Enabled {
+		return nil
+	}
+
+	if !strings.HasPrefix(oidc.IssuerURL, "https://") {
+		return fmt.Errorf("oidc issuer-url '%s' has to be an https url", oidc.IssuerURL)
+	}
+
+	if len(oidc.ClientID) == 0 {
+		return fmt.Errorf("oidc is enabled but no client-id is configured")
+	}
+
+	return nil
+}
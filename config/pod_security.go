@@ -0,0 +1,76 @@
+package config
+
+import "fmt"
+
+// PodSecurityLevels holds the PodSecurity admission levels of a namespace
+type PodSecurityLevels struct {
+	Enforce string `yaml:"enforce,omitempty"`
+	Audit   string `yaml:"audit,omitempty"`
+	Warn    string `yaml:"warn,omitempty"`
+}
+
+var validPodSecurityLevels = map[string]bool{
+	"privileged": true,
+	"baseline":   true,
+	"restricted": true,
+}
+
+// ValidatePodSecurityLevel makes sure level is either empty or one of privileged, baseline, restricted
+func ValidatePodSecurityLevel(level string) error {
+	if len(level) == 0 {
+		return nil
+	}
+
+	if !validPodSecurityLevels[level] {
+		return fmt.Errorf("invalid pod security level '%s', expected one of privileged, baseline, restricted", level)
+	}
+
+	return nil
+}
+
+// Validate makes sure all configured levels are valid
+func (levels PodSecurityLevels) Validate() error {
+	if error := ValidatePodSecurityLevel(levels.Enforce); error != nil {
+		return error
+	}
+
+	if error := ValidatePodSecurityLevel(levels.Audit); error != nil {
+		return error
+	}
+
+	if error := ValidatePodSecurityLevel(levels.Warn); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// Labels renders the pod-security.kubernetes.io namespace labels matching these levels
+func (levels PodSecurityLevels) Labels() map[string]string {
+	labels := map[string]string{}
+
+	if len(levels.Enforce) > 0 {
+		labels["pod-security.kubernetes.io/enforce"] = levels.Enforce
+	}
+
+	if len(levels.Audit) > 0 {
+		labels["pod-security.kubernetes.io/audit"] = levels.Audit
+	}
+
+	if len(levels.Warn) > 0 {
+		labels["pod-security.kubernetes.io/warn"] = levels.Warn
+	}
+
+	return labels
+}
+
+// GetPodSecurityLabels returns the pod-security.kubernetes.io namespace labels configured for the given namespace, if any
+func (config *InternalConfig) GetPodSecurityLabels(namespace string) map[string]string {
+	levels, ok := config.Config.PodSecurityNamespaces[namespace]
+
+	if !ok {
+		return map[string]string{}
+	}
+
+	return levels.Labels()
+}
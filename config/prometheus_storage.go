@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// prometheusDurationExpression matches the duration format Prometheus itself accepts for flags like
+// --storage.tsdb.retention and the Prometheus/Alertmanager CRD's retention field: a sequence of
+// <number><unit> pairs, ordered from the largest unit (y) to the smallest (ms), with every pair optional
+// but at least one required
+var prometheusDurationExpression = regexp.MustCompile(`^(?:[0-9]+y)?(?:[0-9]+w)?(?:[0-9]+d)?(?:[0-9]+h)?(?:[0-9]+m)?(?:[0-9]+s)?(?:[0-9]+ms)?$`)
+
+// ValidatePrometheusDuration makes sure duration is either empty or a valid Prometheus duration string
+// such as "24h" or "30d", as accepted by the Prometheus CRD's retention field
+func ValidatePrometheusDuration(name, duration string) error {
+	if len(duration) == 0 {
+		return nil
+	}
+
+	if !prometheusDurationExpression.MatchString(duration) {
+		return fmt.Errorf("'%s' has an invalid prometheus duration '%s'", name, duration)
+	}
+
+	return nil
+}
+
+// ValidateStorageSize makes sure size is either empty or a valid Kubernetes resource quantity such as
+// "10Gi", as used for the storage PVC's requests.storage
+func ValidateStorageSize(name, size string) error {
+	if len(size) == 0 {
+		return nil
+	}
+
+	if _, error := resource.ParseQuantity(size); error != nil {
+		return fmt.Errorf("'%s' has an invalid storage size '%s' (%s)", name, size, error.Error())
+	}
+
+	return nil
+}
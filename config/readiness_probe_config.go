@@ -0,0 +1,10 @@
+package config
+
+// ReadinessProbeConfig gates command execution on a server actually accepting connections. Endpoint is
+// either a "host:port" TCP dial target or a "http(s)://..." URL; Timeout bounds a single probe attempt,
+// separate from the overall readiness timeout the run command enforces across all servers
+type ReadinessProbeConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Endpoint string `yaml:"endpoint,omitempty"`
+	Timeout  uint   `yaml:"timeout,omitempty"`
+}
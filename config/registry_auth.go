@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RegistryAuth holds the credentials for one private container registry. They are used both to authenticate
+// containerd's own pulls on every node and to render a cluster-wide imagePullSecret so pods can pull from it too.
+// Username/Password/Token can be given directly or, to avoid committing secrets to the config file, sourced from
+// an environment variable via UsernameEnv/PasswordEnv/TokenEnv instead - whichever of the two ends up non-empty wins
+type RegistryAuth struct {
+	Registry    string `yaml:"registry"`
+	Username    string `yaml:"username,omitempty"`
+	UsernameEnv string `yaml:"username-env,omitempty"`
+	Password    string `yaml:"password,omitempty"`
+	PasswordEnv string `yaml:"password-env,omitempty"`
+	Token       string `yaml:"token,omitempty"`
+	TokenEnv    string `yaml:"token-env,omitempty"`
+}
+
+var registrySecretNameCleaner = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// SecretName derives a deterministic, DNS-1123-safe Secret name for the registry, so the same registry always maps
+// to the same imagePullSecret across repeated generates instead of a new one appearing each time
+func (auth RegistryAuth) SecretName() string {
+	return "registry-cred-" + registrySecretNameCleaner.ReplaceAllString(strings.ToLower(auth.Registry), "-")
+}
+
+// resolveCredential returns value directly, falling back to the environment variable named envName when value is
+// empty, and failing loudly if envName is set but the variable isn't
+func resolveCredential(value, envName string) (string, error) {
+	if len(value) > 0 {
+		return value, nil
+	}
+
+	if len(envName) == 0 {
+		return "", nil
+	}
+
+	resolved := os.Getenv(envName)
+	if len(resolved) == 0 {
+		return "", fmt.Errorf("environment variable '%s' is not set", envName)
+	}
+
+	return resolved, nil
+}
+
+// Credentials resolves the registry's username and secret - its password if one is configured, otherwise its
+// token - reading from the environment wherever UsernameEnv/PasswordEnv/TokenEnv is used instead of a literal value
+func (auth RegistryAuth) Credentials() (username, secret string, error error) {
+	if username, error = resolveCredential(auth.Username, auth.UsernameEnv); error != nil {
+		return "", "", fmt.Errorf("registry-auth '%s' username (%s)", auth.Registry, error.Error())
+	}
+
+	password, error := resolveCredential(auth.Password, auth.PasswordEnv)
+	if error != nil {
+		return "", "", fmt.Errorf("registry-auth '%s' password (%s)", auth.Registry, error.Error())
+	}
+
+	token, error := resolveCredential(auth.Token, auth.TokenEnv)
+	if error != nil {
+		return "", "", fmt.Errorf("registry-auth '%s' token (%s)", auth.Registry, error.Error())
+	}
+
+	if len(password) > 0 {
+		secret = password
+	} else {
+		secret = token
+	}
+
+	return username, secret, nil
+}
+
+// Validate makes sure registry is set and that either a password or a token, direct or env-sourced, resolves
+func (auth RegistryAuth) Validate() error {
+	if len(auth.Registry) == 0 {
+		return fmt.Errorf("registry-auth entry is missing a registry")
+	}
+
+	if len(auth.Password) == 0 && len(auth.PasswordEnv) == 0 && len(auth.Token) == 0 && len(auth.TokenEnv) == 0 {
+		return fmt.Errorf("registry-auth '%s' needs either a password/password-env or a token/token-env", auth.Registry)
+	}
+
+	_, _, error := auth.Credentials()
+
+	return error
+}
+
+// ImagePullSecretsPatch builds the JSON strategic-merge patch body that attaches every entry in auths to a service
+// account's imagePullSecrets, for patching the default service account of every namespace
+func ImagePullSecretsPatch(auths []RegistryAuth) string {
+	names := make([]string, len(auths))
+
+	for index, auth := range auths {
+		names[index] = fmt.Sprintf(`{"name":%s}`, strconv.Quote(auth.SecretName()))
+	}
+
+	return fmt.Sprintf(`{"imagePullSecrets":[%s]}`, strings.Join(names, ","))
+}
+
+// ValidateRegistryAuths makes sure every entry resolves and that no registry is configured twice
+func ValidateRegistryAuths(auths []RegistryAuth) error {
+	registries := map[string]bool{}
+
+	for _, auth := range auths {
+		if error := auth.Validate(); error != nil {
+			return error
+		}
+
+		if registries[auth.Registry] {
+			return fmt.Errorf("registry-auth '%s' is defined more than once", auth.Registry)
+		}
+
+		registries[auth.Registry] = true
+	}
+
+	return nil
+}
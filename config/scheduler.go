@@ -0,0 +1,55 @@
+package config
+
+import "fmt"
+
+// PodTopologySpreadConstraint mirrors the PodTopologySpread plugin's default constraints
+type PodTopologySpreadConstraint struct {
+	MaxSkew           int32  `yaml:"max-skew"`
+	TopologyKey       string `yaml:"topology-key"`
+	WhenUnsatisfiable string `yaml:"when-unsatisfiable"`
+}
+
+// SchedulerProfile holds the kube-scheduler profile and plugin configuration rendered into the scheduler configuration file
+type SchedulerProfile struct {
+	Name                                string                        `yaml:"name"`
+	NodeResourcesFitScoringStrategy     string                        `yaml:"node-resources-fit-scoring-strategy,omitempty"`
+	PodTopologySpreadDefaultConstraints []PodTopologySpreadConstraint `yaml:"pod-topology-spread-default-constraints,omitempty"`
+}
+
+var validNodeResourcesFitScoringStrategies = map[string]bool{
+	"LeastAllocated":           true,
+	"MostAllocated":            true,
+	"RequestedToCapacityRatio": true,
+}
+
+var validWhenUnsatisfiable = map[string]bool{
+	"DoNotSchedule":  true,
+	"ScheduleAnyway": true,
+}
+
+// Validate makes sure the profile's name and plugin configuration are well formed
+func (profile SchedulerProfile) Validate() error {
+	if len(profile.Name) == 0 {
+		return fmt.Errorf("scheduler profile is missing a name")
+	}
+
+	if len(profile.NodeResourcesFitScoringStrategy) > 0 && !validNodeResourcesFitScoringStrategies[profile.NodeResourcesFitScoringStrategy] {
+		return fmt.Errorf("scheduler profile '%s' has invalid node-resources-fit-scoring-strategy '%s', expected one of LeastAllocated, MostAllocated, RequestedToCapacityRatio", profile.Name, profile.NodeResourcesFitScoringStrategy)
+	}
+
+	for _, constraint := range profile.PodTopologySpreadDefaultConstraints {
+		if constraint.MaxSkew <= 0 {
+			return fmt.Errorf("scheduler profile '%s' has a pod-topology-spread-default-constraints entry with a non-positive max-skew", profile.Name)
+		}
+
+		if len(constraint.TopologyKey) == 0 {
+			return fmt.Errorf("scheduler profile '%s' has a pod-topology-spread-default-constraints entry without a topology-key", profile.Name)
+		}
+
+		if !validWhenUnsatisfiable[constraint.WhenUnsatisfiable] {
+			return fmt.Errorf("scheduler profile '%s' has a pod-topology-spread-default-constraints entry with invalid when-unsatisfiable '%s', expected one of DoNotSchedule, ScheduleAnyway", profile.Name, constraint.WhenUnsatisfiable)
+		}
+	}
+
+	return nil
+}
@@ -1,16 +1,23 @@
 package config
 
 import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+
 	log "github.com/sirupsen/logrus"
 )
 
 type ServerConfig struct {
-	Name      string            `yaml:"name"`
-	Enabled   bool              `yaml:"enabled"`
-	Labels    Labels            `yaml:"labels"`
-	Logger    LoggerConfig      `yaml:"logger"`
-	Command   string            `yaml:"command"`
-	Arguments map[string]string `yaml:"arguments"`
+	Name           string               `yaml:"name"`
+	Enabled        bool                 `yaml:"enabled"`
+	Labels         Labels               `yaml:"labels"`
+	Logger         LoggerConfig         `yaml:"logger"`
+	RestartPolicy  string               `yaml:"restart-policy,omitempty"`
+	ReadinessProbe ReadinessProbeConfig `yaml:"readiness-probe,omitempty"`
+	Command        string               `yaml:"command"`
+	Arguments      map[string]string    `yaml:"arguments"`
+	Environment    map[string]string    `yaml:"environment,omitempty"`
 }
 
 type Servers []ServerConfig
@@ -21,4 +28,21 @@ func (config ServerConfig) Dump() {
 	for key, value := range config.Arguments {
 		log.WithFields(log.Fields{"name": config.Name, "argument": key, "value": value}).Info("Config server argument")
 	}
+
+	for key, value := range config.Environment {
+		log.WithFields(log.Fields{"name": config.Name, "environment": key, "value": value}).Info("Config server environment")
+	}
+}
+
+// Validate makes sure RestartPolicy, if set, is one of the policies the supervisor knows how to apply
+func (config ServerConfig) Validate() error {
+	if len(config.RestartPolicy) == 0 {
+		return nil
+	}
+
+	if config.RestartPolicy != utils.RESTART_POLICY_NEVER && config.RestartPolicy != utils.RESTART_POLICY_ON_FAILURE && config.RestartPolicy != utils.RESTART_POLICY_ALWAYS {
+		return fmt.Errorf("invalid restart policy '%s' for server '%s', expected one of '%s', '%s', '%s'", config.RestartPolicy, config.Name, utils.RESTART_POLICY_NEVER, utils.RESTART_POLICY_ON_FAILURE, utils.RESTART_POLICY_ALWAYS)
+	}
+
+	return nil
 }
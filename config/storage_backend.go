@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// ValidateStorageBackend makes sure backend selects one of the storage backends k8s-tew knows how to set up, and
+// that nfsServer is set when backend is nfs since the NFS subdir provisioner has no other way to reach the export
+func ValidateStorageBackend(backend, nfsServer string) error {
+	if !utils.SupportedStorageBackends[backend] {
+		return fmt.Errorf("unsupported storage backend '%s', expected one of '%s', '%s', '%s'", backend, utils.STORAGE_BACKEND_CEPH, utils.STORAGE_BACKEND_NFS, utils.STORAGE_BACKEND_LOCAL_PATH)
+	}
+
+	if backend != utils.STORAGE_BACKEND_NFS {
+		return nil
+	}
+
+	if len(nfsServer) == 0 {
+		return fmt.Errorf("nfs-server has to be set when storage-backend is '%s'", utils.STORAGE_BACKEND_NFS)
+	}
+
+	return nil
+}
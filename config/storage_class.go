@@ -0,0 +1,87 @@
+package config
+
+import "fmt"
+
+// StorageClassConfig overrides the name, reclaim policy, volume binding mode and default flag of one of
+// k8s-tew's built-in storage classes ("ceph", "csi-rbd", "csi-cephfs", "nfs", "local-path")
+type StorageClassConfig struct {
+	Name              string `yaml:"name,omitempty"`
+	ReclaimPolicy     string `yaml:"reclaim-policy,omitempty"`
+	VolumeBindingMode string `yaml:"volume-binding-mode,omitempty"`
+	Default           bool   `yaml:"default,omitempty"`
+}
+
+var validReclaimPolicies = map[string]bool{
+	"Delete": true,
+	"Retain": true,
+}
+
+var validVolumeBindingModes = map[string]bool{
+	"Immediate":            true,
+	"WaitForFirstConsumer": true,
+}
+
+var builtinStorageClasses = map[string]StorageClassConfig{
+	"ceph":       {Name: "ceph", ReclaimPolicy: "Delete", VolumeBindingMode: "Immediate", Default: false},
+	"csi-rbd":    {Name: "csi-rbd", ReclaimPolicy: "Delete", VolumeBindingMode: "Immediate", Default: true},
+	"csi-cephfs": {Name: "csi-cephfs", ReclaimPolicy: "Delete", VolumeBindingMode: "Immediate", Default: false},
+	"nfs":        {Name: "nfs", ReclaimPolicy: "Delete", VolumeBindingMode: "Immediate", Default: false},
+	"local-path": {Name: "local-path", ReclaimPolicy: "Delete", VolumeBindingMode: "WaitForFirstConsumer", Default: true},
+}
+
+// ValidateStorageClasses makes sure every override has a known reclaim policy and volume binding mode, and that at
+// most one storage class is marked as the cluster default
+func ValidateStorageClasses(storageClasses map[string]StorageClassConfig) error {
+	defaultCount := 0
+
+	for identifier, storageClass := range storageClasses {
+		if _, ok := builtinStorageClasses[identifier]; !ok {
+			return fmt.Errorf("unknown storage class '%s', expected one of ceph, csi-rbd, csi-cephfs, nfs, local-path", identifier)
+		}
+
+		if len(storageClass.ReclaimPolicy) > 0 && !validReclaimPolicies[storageClass.ReclaimPolicy] {
+			return fmt.Errorf("storage class '%s' has invalid reclaim-policy '%s', expected Delete or Retain", identifier, storageClass.ReclaimPolicy)
+		}
+
+		if len(storageClass.VolumeBindingMode) > 0 && !validVolumeBindingModes[storageClass.VolumeBindingMode] {
+			return fmt.Errorf("storage class '%s' has invalid volume-binding-mode '%s', expected Immediate or WaitForFirstConsumer", identifier, storageClass.VolumeBindingMode)
+		}
+
+		if storageClass.Default {
+			defaultCount++
+		}
+	}
+
+	if defaultCount > 1 {
+		return fmt.Errorf("only one storage class may be marked as default")
+	}
+
+	return nil
+}
+
+// GetStorageClass merges the configured override for identifier, if any, with k8s-tew's built-in defaults
+func (config *InternalConfig) GetStorageClass(identifier string) StorageClassConfig {
+	storageClass := builtinStorageClasses[identifier]
+
+	override, ok := config.Config.StorageClasses[identifier]
+	if !ok {
+		return storageClass
+	}
+
+	if len(override.Name) > 0 {
+		storageClass.Name = override.Name
+	}
+
+	if len(override.ReclaimPolicy) > 0 {
+		storageClass.ReclaimPolicy = override.ReclaimPolicy
+	}
+
+	if len(override.VolumeBindingMode) > 0 {
+		storageClass.VolumeBindingMode = override.VolumeBindingMode
+	}
+
+	// An explicit override always decides the default flag, even when unsetting it
+	storageClass.Default = override.Default
+
+	return storageClass
+}
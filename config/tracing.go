@@ -0,0 +1,22 @@
+package config
+
+import "fmt"
+
+// TracingConfig configures exporting apiserver and etcd distributed traces to an OTLP collector
+type TracingConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	OTLPEndpoint string `yaml:"otlp-endpoint,omitempty"`
+}
+
+// Validate makes sure an endpoint is set whenever tracing is enabled
+func (tracing TracingConfig) Validate() error {
+	if !tracing.Enabled {
+		return nil
+	}
+
+	if len(tracing.OTLPEndpoint) == 0 {
+		return fmt.Errorf("tracing is enabled but no otlp-endpoint is configured")
+	}
+
+	return nil
+}
@@ -1,6 +1,11 @@
 package config
 
-import "github.com/darxkies/k8s-tew/utils"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/darxkies/k8s-tew/utils"
+)
 
 type Versions struct {
 	Etcd                       string `yaml:"etcd"`
@@ -10,7 +15,7 @@ type Versions struct {
 	Runc                       string `yaml:"runc"`
 	CriCtl                     string `yaml:"crictl"`
 	Gobetween                  string `yaml:"gobetween"`
-	Ark                        string `yaml:"ark"`
+	Velero                     string `yaml:"velero"`
 	MinioServer                string `yaml:"minio-server"`
 	MinioClient                string `yaml:"minio-client"`
 	Pause                      string `yaml:"pause"`
@@ -21,10 +26,17 @@ type Versions struct {
 	Kibana                     string `yaml:"kibana"`
 	Cerebro                    string `yaml:"cerebro"`
 	FluentBit                  string `yaml:"fluent-bit"`
+	Loki                       string `yaml:"loki"`
+	Promtail                   string `yaml:"promtail"`
 	CalicoTypha                string `yaml:"calico-typha"`
 	CalicoNode                 string `yaml:"calico-node"`
 	CalicoCNI                  string `yaml:"calico-cni"`
+	Cilium                     string `yaml:"cilium"`
+	WeaveNet                   string `yaml:"weave-net"`
+	WeaveNPC                   string `yaml:"weave-npc"`
 	RBDProvisioner             string `yaml:"rbd-provisioner"`
+	NFSSubdirProvisioner       string `yaml:"nfs-subdir-provisioner"`
+	LocalPathProvisioner       string `yaml:"local-path-provisioner"`
 	Ceph                       string `yaml:"ceph"`
 	Heapster                   string `yaml:"heapster"`
 	AddonResizer               string `yaml:"addon-resizer"`
@@ -32,6 +44,7 @@ type Versions struct {
 	CertManagerController      string `yaml:"cert-manager-controller"`
 	NginxIngressController     string `yaml:"nginx-ingress-controller"`
 	NginxIngressDefaultBackend string `yaml:"nginx-ingress-default-backend"`
+	Traefik                    string `yaml:"traefik"`
 	MetricsServer              string `yaml:"metrics-server"`
 	PrometheusOperator         string `yaml:"prometheus-operator"`
 	PrometheusConfigReloader   string `yaml:"prometheus-config-reloader"`
@@ -49,6 +62,8 @@ type Versions struct {
 	CSICephFSPlugin            string `yaml:"csi-ceph-fs-plugin"`
 	WordPress                  string `yaml:"wordpress"`
 	MySQL                      string `yaml:"mysql"`
+	MetalLBController          string `yaml:"metallb-controller"`
+	MetalLBSpeaker             string `yaml:"metallb-speaker"`
 }
 
 func NewVersions() Versions {
@@ -60,7 +75,7 @@ func NewVersions() Versions {
 		Runc:                       utils.VERSION_RUNC,
 		CriCtl:                     utils.VERSION_CRICTL,
 		Gobetween:                  utils.VERSION_GOBETWEEN,
-		Ark:                        utils.VERSION_ARK,
+		Velero:                     utils.VERSION_VELERO,
 		MinioServer:                utils.VERSION_MINIO_SERVER,
 		MinioClient:                utils.VERSION_MINIO_CLIENT,
 		Pause:                      utils.VERSION_PAUSE,
@@ -71,10 +86,16 @@ func NewVersions() Versions {
 		Kibana:                     utils.VERSION_KIBANA,
 		Cerebro:                    utils.VERSION_CEREBRO,
 		FluentBit:                  utils.VERSION_FLUENT_BIT,
+		Loki:                       utils.VERSION_LOKI,
+		Promtail:                   utils.VERSION_PROMTAIL,
 		CalicoTypha:                utils.VERSION_CALICO_TYPHA,
 		CalicoNode:                 utils.VERSION_CALICO_NODE,
 		CalicoCNI:                  utils.VERSION_CALICO_CNI,
+		Cilium:                     utils.VERSION_CILIUM,
+		WeaveNet:                   utils.VERSION_WEAVE_NET,
+		WeaveNPC:                   utils.VERSION_WEAVE_NPC,
 		RBDProvisioner:             utils.VERSION_RBD_PROVISIONER,
+		LocalPathProvisioner:       utils.VERSION_LOCAL_PATH_PROVISIONER,
 		Ceph:                       utils.VERSION_CEPH,
 		Heapster:                   utils.VERSION_HEAPSTER,
 		AddonResizer:               utils.VERSION_ADDON_RESIZER,
@@ -82,6 +103,7 @@ func NewVersions() Versions {
 		CertManagerController:      utils.VERSION_CERT_MANAGER_CONTROLLER,
 		NginxIngressController:     utils.VERSION_NGINX_INGRESS_CONTROLLER,
 		NginxIngressDefaultBackend: utils.VERSION_NGINX_INGRESS_DEFAULT_BACKEND,
+		Traefik:                    utils.VERSION_TRAEFIK,
 		MetricsServer:              utils.VERSION_METRICS_SERVER,
 		PrometheusOperator:         utils.VERSION_PROMETHEUS_OPERATOR,
 		PrometheusConfigReloader:   utils.VERSION_PROMETHEUS_CONFIG_RELOADER,
@@ -99,5 +121,165 @@ func NewVersions() Versions {
 		CSICephFSPlugin:            utils.VERSION_CSI_CEPH_FS_PLUGIN,
 		WordPress:                  utils.VERSION_WORDPRESS,
 		MySQL:                      utils.VERSION_MYSQL,
+		MetalLBController:          utils.VERSION_METALLB_CONTROLLER,
+		MetalLBSpeaker:             utils.VERSION_METALLB_SPEAKER,
+	}
+}
+
+// validateImageVersion makes sure value parses as "name:tag", i.e. has a non-empty name and tag separated by a
+// colon, so the image_name/image_tag template functions don't silently fall back to the whole string
+func validateImageVersion(label, value string) error {
+	index := strings.LastIndex(value, ":")
+
+	if index <= 0 || index == len(value)-1 {
+		return fmt.Errorf("invalid version '%s' for '%s', expected an image reference in the form 'name:tag'", value, label)
+	}
+
+	return nil
+}
+
+// Validate makes sure every version override that is consumed as a container image still parses as "name:tag".
+// Etcd, K8S, Helm, Containerd, Runc, CriCtl and Gobetween are plain binary version strings, not image references,
+// and are therefore excluded from this check
+func (versions Versions) Validate() error {
+	images := map[string]string{
+		"velero":                        versions.Velero,
+		"minio-server":                  versions.MinioServer,
+		"minio-client":                  versions.MinioClient,
+		"pause":                         versions.Pause,
+		"core-dns":                      versions.CoreDNS,
+		"elasticsearch":                 versions.Elasticsearch,
+		"elasticsearch-cron":            versions.ElasticsearchCron,
+		"elasticsearch-operator":        versions.ElasticsearchOperator,
+		"kibana":                        versions.Kibana,
+		"cerebro":                       versions.Cerebro,
+		"fluent-bit":                    versions.FluentBit,
+		"loki":                          versions.Loki,
+		"promtail":                      versions.Promtail,
+		"calico-typha":                  versions.CalicoTypha,
+		"calico-node":                   versions.CalicoNode,
+		"calico-cni":                    versions.CalicoCNI,
+		"cilium":                        versions.Cilium,
+		"weave-net":                     versions.WeaveNet,
+		"weave-npc":                     versions.WeaveNPC,
+		"rbd-provisioner":               versions.RBDProvisioner,
+		"nfs-subdir-provisioner":        versions.NFSSubdirProvisioner,
+		"local-path-provisioner":        versions.LocalPathProvisioner,
+		"ceph":                          versions.Ceph,
+		"heapster":                      versions.Heapster,
+		"addon-resizer":                 versions.AddonResizer,
+		"kubernetes-dashboard":          versions.KubernetesDashboard,
+		"cert-manager-controller":       versions.CertManagerController,
+		"nginx-ingress-controller":      versions.NginxIngressController,
+		"nginx-ingress-default-backend": versions.NginxIngressDefaultBackend,
+		"traefik":                       versions.Traefik,
+		"metrics-server":                versions.MetricsServer,
+		"prometheus-operator":           versions.PrometheusOperator,
+		"prometheus-config-reloader":    versions.PrometheusConfigReloader,
+		"configmap-reload":              versions.ConfigMapReload,
+		"kube-state-metrics":            versions.KubeStateMetrics,
+		"grafana":                       versions.Grafana,
+		"grafana-watcher":               versions.GrafanaWatcher,
+		"prometheus":                    versions.Prometheus,
+		"prometheus-node-exporter":      versions.PrometheusNodeExporter,
+		"prometheus-alert-manager":      versions.PrometheusAlertManager,
+		"csi-attacher":                  versions.CSIAttacher,
+		"csi-provisioner":               versions.CSIProvisioner,
+		"csi-driver-registrar":          versions.CSIDriverRegistrar,
+		"csi-ceph-rbd-plugin":           versions.CSICephRBDPlugin,
+		"csi-ceph-fs-plugin":            versions.CSICephFSPlugin,
+		"wordpress":                     versions.WordPress,
+		"mysql":                         versions.MySQL,
+		"metallb-controller":            versions.MetalLBController,
+		"metallb-speaker":               versions.MetalLBSpeaker,
+	}
+
+	for label, value := range images {
+		if error := validateImageVersion(label, value); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
+
+// rewriteImageRegistry replaces the registry host of image - its component up to the first '/' - with mirror,
+// leaving the rest of the reference untouched. Values without a registry host (recognized by a '.' in that
+// first component, e.g. "quay.io" or "k8s.gcr.io") are returned unchanged
+func rewriteImageRegistry(image, mirror string) string {
+	if len(mirror) == 0 {
+		return image
+	}
+
+	index := strings.Index(image, "/")
+
+	if index <= 0 || !strings.Contains(image[:index], ".") {
+		return image
+	}
+
+	return mirror + image[index:]
+}
+
+// ApplyRegistryMirror rewrites the registry host of every version that is consumed as a container image so it
+// is pulled from mirror instead, e.g. "quay.io/calico/node:v3.1.3" becomes "mirror/calico/node:v3.1.3". Etcd,
+// K8S, Helm, Containerd, Runc, CriCtl and Gobetween are plain binary version strings, not image references, and
+// are therefore left untouched. Since deployment.images and every generated setup manifest read the image
+// reference from these fields, rewriting them here is enough to keep the pull step and the rendered manifests
+// consistent
+func (versions *Versions) ApplyRegistryMirror(mirror string) {
+	images := map[string]*string{
+		"velero":                        &versions.Velero,
+		"minio-server":                  &versions.MinioServer,
+		"minio-client":                  &versions.MinioClient,
+		"pause":                         &versions.Pause,
+		"core-dns":                      &versions.CoreDNS,
+		"elasticsearch":                 &versions.Elasticsearch,
+		"elasticsearch-cron":            &versions.ElasticsearchCron,
+		"elasticsearch-operator":        &versions.ElasticsearchOperator,
+		"kibana":                        &versions.Kibana,
+		"cerebro":                       &versions.Cerebro,
+		"fluent-bit":                    &versions.FluentBit,
+		"loki":                          &versions.Loki,
+		"promtail":                      &versions.Promtail,
+		"calico-typha":                  &versions.CalicoTypha,
+		"calico-node":                   &versions.CalicoNode,
+		"calico-cni":                    &versions.CalicoCNI,
+		"cilium":                        &versions.Cilium,
+		"weave-net":                     &versions.WeaveNet,
+		"weave-npc":                     &versions.WeaveNPC,
+		"rbd-provisioner":               &versions.RBDProvisioner,
+		"nfs-subdir-provisioner":        &versions.NFSSubdirProvisioner,
+		"local-path-provisioner":        &versions.LocalPathProvisioner,
+		"ceph":                          &versions.Ceph,
+		"heapster":                      &versions.Heapster,
+		"addon-resizer":                 &versions.AddonResizer,
+		"kubernetes-dashboard":          &versions.KubernetesDashboard,
+		"cert-manager-controller":       &versions.CertManagerController,
+		"nginx-ingress-controller":      &versions.NginxIngressController,
+		"nginx-ingress-default-backend": &versions.NginxIngressDefaultBackend,
+		"traefik":                       &versions.Traefik,
+		"metrics-server":                &versions.MetricsServer,
+		"prometheus-operator":           &versions.PrometheusOperator,
+		"prometheus-config-reloader":    &versions.PrometheusConfigReloader,
+		"configmap-reload":              &versions.ConfigMapReload,
+		"kube-state-metrics":            &versions.KubeStateMetrics,
+		"grafana":                       &versions.Grafana,
+		"grafana-watcher":               &versions.GrafanaWatcher,
+		"prometheus":                    &versions.Prometheus,
+		"prometheus-node-exporter":      &versions.PrometheusNodeExporter,
+		"prometheus-alert-manager":      &versions.PrometheusAlertManager,
+		"csi-attacher":                  &versions.CSIAttacher,
+		"csi-provisioner":               &versions.CSIProvisioner,
+		"csi-driver-registrar":          &versions.CSIDriverRegistrar,
+		"csi-ceph-rbd-plugin":           &versions.CSICephRBDPlugin,
+		"csi-ceph-fs-plugin":            &versions.CSICephFSPlugin,
+		"wordpress":                     &versions.WordPress,
+		"mysql":                         &versions.MySQL,
+		"metallb-controller":            &versions.MetalLBController,
+		"metallb-speaker":               &versions.MetalLBSpeaker,
+	}
+
+	for _, image := range images {
+		*image = rewriteImageRegistry(*image, mirror)
 	}
 }
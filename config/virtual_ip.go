@@ -0,0 +1,8 @@
+package config
+
+// VirtualIP is one floating IP managed by a role's VIPManager, bound to its own network interface so several
+// virtual IPs (e.g. one per ingress class) can be owned by the same raft election and move together on failover
+type VirtualIP struct {
+	IP        string `yaml:"ip"`
+	Interface string `yaml:"interface"`
+}
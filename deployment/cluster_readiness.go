@@ -0,0 +1,181 @@
+package deployment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/utils"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// clusterReadinessPollInterval is how often WaitForCluster re-checks node and workload readiness while waiting
+const clusterReadinessPollInterval = 5 * time.Second
+
+// getClusterClientset creates a client-go client talking to the cluster through the local admin kubeconfig
+func getClusterClientset(_config *config.InternalConfig) (*kubernetes.Clientset, error) {
+	kubeconfig := _config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG)
+
+	restConfig, error := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if error != nil {
+		return nil, error
+	}
+
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// isNodeReady reports whether node has a Ready condition with status True
+func isNodeReady(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// GetClusterClientset creates a client-go client talking to the cluster through the local admin kubeconfig, for
+// callers outside this package (e.g. the status command) that need direct API access
+func GetClusterClientset(_config *config.InternalConfig) (*kubernetes.Clientset, error) {
+	return getClusterClientset(_config)
+}
+
+// IsNodeReady reports whether node has a Ready condition with status True, for callers outside this package
+func IsNodeReady(node v1.Node) bool {
+	return isNodeReady(node)
+}
+
+// cniDaemonSetName returns the name of the DaemonSet running the configured CNI, so its rollout can be waited on
+func cniDaemonSetName(cni string) string {
+	if cni == utils.CNI_CILIUM {
+		return "cilium"
+	}
+
+	return "calico-node"
+}
+
+// nodesNotReadyReasons returns a human readable reason for every node in nodeNames that is not Ready yet, empty
+// once all of them are
+func nodesNotReadyReasons(clientset *kubernetes.Clientset, nodeNames []string) []string {
+	reasons := []string{}
+
+	for _, nodeName := range nodeNames {
+		node, error := clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+		if error != nil {
+			reasons = append(reasons, fmt.Sprintf("could not get node '%s' (%s)", nodeName, error.Error()))
+
+			continue
+		}
+
+		if !isNodeReady(*node) {
+			reasons = append(reasons, fmt.Sprintf("node '%s' is not Ready", nodeName))
+		}
+	}
+
+	return reasons
+}
+
+// WaitForNodes polls the API server, through the admin kubeconfig, until every node in nodeNames is Ready, or
+// timeout elapses. On timeout the returned error lists every node that was still not ready
+func WaitForNodes(_config *config.InternalConfig, nodeNames []string, timeout time.Duration) error {
+	clientset, error := getClusterClientset(_config)
+	if error != nil {
+		return error
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	var reasons []string
+
+	for {
+		reasons = nodesNotReadyReasons(clientset, nodeNames)
+
+		if len(reasons) == 0 {
+			log.WithFields(log.Fields{"nodes": strings.Join(nodeNames, ", ")}).Info("Nodes are ready")
+
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("nodes not ready after %s: %s", timeout, strings.Join(reasons, "; "))
+		}
+
+		log.WithFields(log.Fields{"reasons": strings.Join(reasons, "; ")}).Info("Waiting for nodes to become ready")
+
+		time.Sleep(clusterReadinessPollInterval)
+	}
+}
+
+// clusterNotReadyReasons returns a human readable reason for every node or core workload that is not ready yet,
+// empty once the cluster is considered usable
+func clusterNotReadyReasons(clientset *kubernetes.Clientset, cni string) []string {
+	reasons := []string{}
+
+	nodes, error := clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if error != nil {
+		return []string{fmt.Sprintf("could not list nodes (%s)", error.Error())}
+	}
+
+	for _, node := range nodes.Items {
+		if !isNodeReady(node) {
+			reasons = append(reasons, fmt.Sprintf("node '%s' is not Ready", node.Name))
+		}
+	}
+
+	coreDNS, error := clientset.AppsV1().Deployments("kube-system").Get("coredns", metav1.GetOptions{})
+	if error != nil {
+		reasons = append(reasons, fmt.Sprintf("could not get 'coredns' deployment (%s)", error.Error()))
+
+	} else if coreDNS.Status.AvailableReplicas < 1 {
+		reasons = append(reasons, "'coredns' deployment has no available replicas")
+	}
+
+	cniDaemonSet, error := clientset.AppsV1().DaemonSets("networking").Get(cniDaemonSetName(cni), metav1.GetOptions{})
+	if error != nil {
+		reasons = append(reasons, fmt.Sprintf("could not get '%s' daemonset (%s)", cniDaemonSetName(cni), error.Error()))
+
+	} else if cniDaemonSet.Status.NumberReady < cniDaemonSet.Status.DesiredNumberScheduled {
+		reasons = append(reasons, fmt.Sprintf("'%s' daemonset has %d/%d pods ready", cniDaemonSetName(cni), cniDaemonSet.Status.NumberReady, cniDaemonSet.Status.DesiredNumberScheduled))
+	}
+
+	return reasons
+}
+
+// WaitForCluster polls the API server, through the admin kubeconfig k8s-tew already generates, until every node
+// is Ready and CoreDNS/the configured CNI are up, or timeout elapses. On timeout the returned error lists
+// everything that was still not ready
+func WaitForCluster(_config *config.InternalConfig, timeout time.Duration) error {
+	clientset, error := getClusterClientset(_config)
+	if error != nil {
+		return error
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	var reasons []string
+
+	for {
+		reasons = clusterNotReadyReasons(clientset, _config.Config.CNI)
+
+		if len(reasons) == 0 {
+			log.Info("Cluster is ready")
+
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cluster not ready after %s: %s", timeout, strings.Join(reasons, "; "))
+		}
+
+		log.WithFields(log.Fields{"reasons": strings.Join(reasons, "; ")}).Info("Waiting for cluster to become ready")
+
+		time.Sleep(clusterReadinessPollInterval)
+	}
+}
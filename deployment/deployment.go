@@ -1,6 +1,11 @@
 package deployment
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/darxkies/k8s-tew/config"
@@ -9,33 +14,115 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const CONTROLLER_ONLY_TAINT_KEY = "node-role.kubernetes.io/master"
-
 type Image struct {
 	Name     string
 	Features config.Features
 }
 
+// NodeError pairs an error with the node it happened on, so callers such as Deploy's --keep-going mode can both
+// log a human-readable message and tell programmatically which node to treat as failed, instead of having to
+// parse the node name back out of a formatted string
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func (error *NodeError) Error() string {
+	return fmt.Sprintf("node '%s': %s", error.Node, error.Err.Error())
+}
+
 type Deployment struct {
-	config            *config.InternalConfig
-	identityFile      string
-	skipSetup         bool
-	skipSetupFeatures config.Features
-	pullImages        bool
-	forceUpload       bool
-	commandRetries    uint
-	nodes             map[string]*NodeDeployment
-	images            []Image
-	parallel          bool
+	config               *config.InternalConfig
+	identityFile         string
+	skipSetup            bool
+	skipSetupFeatures    config.Features
+	pullImages           bool
+	forceUpload          bool
+	commandRetries       uint
+	retryInitialInterval time.Duration
+	retryMaxInterval     time.Duration
+	nodes                map[string]*NodeDeployment
+	selectedNodes        []string
+	images               []Image
+	parallel             bool
+	maxConcurrency       uint
+	dryRun               bool
+	drainNodes           bool
+	drainTimeout         time.Duration
+	drainGracePeriod     time.Duration
+	drainForce           bool
+	imageBundleDirectory string
+	keepGoing            bool
+	failedNodes          map[string]error
+	pullImageFallback    bool
+}
+
+// matchesNodeFilter reports whether nodeName or any of node's labels appears in filter
+func matchesNodeFilter(nodeName string, node *config.Node, filter []string) bool {
+	for _, entry := range filter {
+		if entry == nodeName {
+			return true
+		}
+
+		for _, label := range node.Labels {
+			if entry == label {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
-func NewDeployment(_config *config.InternalConfig, identityFile string, pullImages bool, forceUpload bool, parallel bool, commandRetries uint, skipSetup, skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup bool) *Deployment {
+// SelectNodes returns the names of the nodes in config, sorted, restricted to those matching onlyNodes (if any
+// are given) and excluding those matching skipNodes - shared by Deploy and Uninstall so both operate on the same
+// subset of nodes for the same --skip-nodes/--only-nodes flags
+func SelectNodes(_config *config.InternalConfig, skipNodes, onlyNodes []string) []string {
+	selectedNodes := []string{}
+
+	for _, nodeName := range _config.GetSortedNodeKeys() {
+		node := _config.Config.Nodes[nodeName]
+
+		if len(onlyNodes) > 0 && !matchesNodeFilter(nodeName, node, onlyNodes) {
+			log.WithFields(log.Fields{"node": nodeName}).Info("Skipping node")
+
+			continue
+		}
+
+		if matchesNodeFilter(nodeName, node, skipNodes) {
+			log.WithFields(log.Fields{"node": nodeName}).Info("Skipping node")
+
+			continue
+		}
+
+		selectedNodes = append(selectedNodes, nodeName)
+	}
+
+	return selectedNodes
+}
+
+func NewDeployment(_config *config.InternalConfig, identityFile string, pullImages bool, forceUpload bool, parallel bool, maxConcurrency uint, commandRetries uint, retryInitialInterval, retryMaxInterval uint, dryRun bool, skipSetup, skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup bool, skipNodes, onlyNodes []string, drainNodes bool, drainTimeout, drainGracePeriod uint, drainForce bool, imageBundleDirectory string, keepGoing, pullImageFallback bool) *Deployment {
 	nodes := map[string]*NodeDeployment{}
 
 	for nodeName, node := range _config.Config.Nodes {
-		nodes[nodeName] = NewNodeDeployment(identityFile, nodeName, node, _config, parallel)
+		nodes[nodeName] = NewNodeDeployment(identityFile, nodeName, node, _config.Clone(nodeName, node), parallel, dryRun, pullImageFallback)
 	}
 
+	selectedNodes := SelectNodes(_config, skipNodes, onlyNodes)
+
+	skipSetupFeatures := buildSkipSetupFeatures(skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup)
+
+	deployment := &Deployment{config: _config, identityFile: identityFile, pullImages: pullImages, forceUpload: forceUpload, parallel: parallel, maxConcurrency: maxConcurrency, commandRetries: commandRetries, retryInitialInterval: time.Duration(retryInitialInterval) * time.Second, retryMaxInterval: time.Duration(retryMaxInterval) * time.Second, dryRun: dryRun, nodes: nodes, selectedNodes: selectedNodes, skipSetup: skipSetup, skipSetupFeatures: skipSetupFeatures, drainNodes: drainNodes, drainTimeout: time.Duration(drainTimeout) * time.Second, drainGracePeriod: time.Duration(drainGracePeriod) * time.Second, drainForce: drainForce, imageBundleDirectory: imageBundleDirectory, keepGoing: keepGoing, failedNodes: map[string]error{}, pullImageFallback: pullImageFallback}
+
+	deployment.images = BuildImages(_config)
+
+	return deployment
+}
+
+// buildSkipSetupFeatures turns the individual --skip-*-setup flags into the Features list HasFeatures checks
+// images and bootstrapper commands against - shared by NewDeployment and NewImageBundle so both skip exactly the
+// same images for the same flags
+func buildSkipSetupFeatures(skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup bool) config.Features {
 	skipSetupFeatures := config.Features{}
 
 	if skipStorageSetup {
@@ -66,109 +153,312 @@ func NewDeployment(_config *config.InternalConfig, identityFile string, pullImag
 		skipSetupFeatures = append(skipSetupFeatures, utils.FEATURE_PACKAGING)
 	}
 
-	deployment := &Deployment{config: _config, identityFile: identityFile, pullImages: pullImages, forceUpload: forceUpload, parallel: parallel, commandRetries: commandRetries, nodes: nodes, skipSetup: skipSetup, skipSetupFeatures: skipSetupFeatures}
-
-	deployment.images = []Image{
-		Image{Name: deployment.config.Config.Versions.Pause, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.CalicoCNI, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.CalicoNode, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.CalicoTypha, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.CoreDNS, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.MinioServer, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.MinioClient, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Ark, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Ceph, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CSIAttacher, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CSIProvisioner, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CSIDriverRegistrar, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CSICephRBDPlugin, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CSICephFSPlugin, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.RBDProvisioner, Features: config.Features{utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.FluentBit, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Elasticsearch, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.ElasticsearchCron, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.ElasticsearchOperator, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Kibana, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Cerebro, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Heapster, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.AddonResizer, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.MetricsServer, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.KubernetesDashboard, Features: config.Features{}},
-		Image{Name: deployment.config.Config.Versions.PrometheusOperator, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.PrometheusConfigReloader, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.ConfigMapReload, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.KubeStateMetrics, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Grafana, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.GrafanaWatcher, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.Prometheus, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.PrometheusNodeExporter, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.PrometheusAlertManager, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.CertManagerController, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.NginxIngressDefaultBackend, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.NginxIngressController, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.MySQL, Features: config.Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}},
-		Image{Name: deployment.config.Config.Versions.WordPress, Features: config.Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}},
+	return skipSetupFeatures
+}
+
+// BuildImages returns every container image the configured cluster setup requires, paired with the features
+// that must not be skipped for it to be needed - shared by NewDeployment and the image bundle export/import
+// commands so both work from the exact same image list
+func BuildImages(_config *config.InternalConfig) []Image {
+	images := []Image{
+		Image{Name: _config.Config.Versions.Pause, Features: config.Features{}},
+		Image{Name: _config.Config.Versions.CoreDNS, Features: config.Features{}},
+		Image{Name: _config.Config.Versions.MinioServer, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.MinioClient, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.Velero, Features: config.Features{utils.FEATURE_BACKUP, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.Heapster, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.AddonResizer, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.MetricsServer, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.KubernetesDashboard, Features: config.Features{}},
+		Image{Name: _config.Config.Versions.PrometheusOperator, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.PrometheusConfigReloader, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.ConfigMapReload, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.KubeStateMetrics, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.Grafana, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.GrafanaWatcher, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.Prometheus, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.PrometheusNodeExporter, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.PrometheusAlertManager, Features: config.Features{utils.FEATURE_MONITORING, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.CertManagerController, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.MySQL, Features: config.Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}},
+		Image{Name: _config.Config.Versions.WordPress, Features: config.Features{utils.FEATURE_SHOWCASE, utils.FEATURE_STORAGE}},
+	}
+
+	switch _config.Config.LoggingBackend {
+	case utils.LOGGING_BACKEND_LOKI:
+		images = append(images,
+			Image{Name: _config.Config.Versions.Loki, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.Promtail, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+		)
+
+	default:
+		images = append(images,
+			Image{Name: _config.Config.Versions.FluentBit, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.Elasticsearch, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.ElasticsearchCron, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.ElasticsearchOperator, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.Kibana, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.Cerebro, Features: config.Features{utils.FEATURE_LOGGING, utils.FEATURE_STORAGE}},
+		)
 	}
 
-	return deployment
+	switch _config.Config.StorageBackend {
+	case utils.STORAGE_BACKEND_NFS:
+		images = append(images, Image{Name: _config.Config.Versions.NFSSubdirProvisioner, Features: config.Features{utils.FEATURE_STORAGE}})
+
+	case utils.STORAGE_BACKEND_LOCAL_PATH:
+		images = append(images, Image{Name: _config.Config.Versions.LocalPathProvisioner, Features: config.Features{utils.FEATURE_STORAGE}})
+
+	default:
+		images = append(images,
+			Image{Name: _config.Config.Versions.Ceph, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.CSIAttacher, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.CSIProvisioner, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.CSIDriverRegistrar, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.CSICephRBDPlugin, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.CSICephFSPlugin, Features: config.Features{utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.RBDProvisioner, Features: config.Features{utils.FEATURE_STORAGE}},
+		)
+	}
+
+	switch _config.Config.IngressController {
+	case utils.INGRESS_CONTROLLER_TRAEFIK:
+		images = append(images,
+			Image{Name: _config.Config.Versions.Traefik, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.NginxIngressDefaultBackend, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
+		)
+
+	default:
+		images = append(images,
+			Image{Name: _config.Config.Versions.NginxIngressDefaultBackend, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
+			Image{Name: _config.Config.Versions.NginxIngressController, Features: config.Features{utils.FEATURE_INGRESS, utils.FEATURE_STORAGE}},
+		)
+	}
+
+	if _config.Config.MetalLB.Enabled {
+		images = append(images,
+			Image{Name: _config.Config.Versions.MetalLBController, Features: config.Features{utils.FEATURE_METALLB}},
+			Image{Name: _config.Config.Versions.MetalLBSpeaker, Features: config.Features{utils.FEATURE_METALLB}},
+		)
+	}
+
+	switch _config.Config.CNI {
+	case utils.CNI_CILIUM:
+		images = append(images, Image{Name: _config.Config.Versions.Cilium, Features: config.Features{}})
+
+	case utils.CNI_WEAVE:
+		images = append(images,
+			Image{Name: _config.Config.Versions.WeaveNet, Features: config.Features{}},
+			Image{Name: _config.Config.Versions.WeaveNPC, Features: config.Features{}},
+		)
+
+	default:
+		images = append(images,
+			Image{Name: _config.Config.Versions.CalicoCNI, Features: config.Features{}},
+			Image{Name: _config.Config.Versions.CalicoNode, Features: config.Features{}},
+			Image{Name: _config.Config.Versions.CalicoTypha, Features: config.Features{}},
+		)
+	}
+
+	return images
 }
 
 func (deployment *Deployment) Steps() int {
 	result := 0
 
 	// Files deployment
-	for _, node := range deployment.nodes {
-		result += node.Steps()
+	for _, nodeName := range deployment.selectedNodes {
+		result += deployment.nodes[nodeName].Steps()
 	}
 
 	if !deployment.skipSetup {
 		// Taint commands
-		result += len(deployment.config.Config.Nodes)
+		result += len(deployment.selectedNodes)
 
 		if deployment.pullImages {
 			// Taint commands
-			result += len(deployment.config.Config.Nodes) * len(deployment.images)
+			result += len(deployment.selectedNodes) * len(deployment.images)
 		}
 
 		// Run Commands
-		result += len(deployment.config.Config.Nodes) * len(deployment.config.Config.Commands)
+		result += len(deployment.selectedNodes) * len(deployment.config.Config.Commands)
 
 	}
 
 	return result
 }
 
-// Deploy all files to the nodes over SSH
-func (deployment *Deployment) Deploy() error {
-	sortedNodeKeys := deployment.config.GetSortedNodeKeys()
+// Deploy all files to the nodes over SSH. ctx is checked between steps so a cancellation (e.g. Ctrl-C) is honored
+// as soon as the step in progress finishes, instead of only once the whole deployment is done
+func (deployment *Deployment) Deploy(ctx context.Context) error {
+	defer deployment.Close()
+
+	if error := deployment.config.Validate(); error != nil {
+		return error
+	}
 
-	for _, nodeName := range sortedNodeKeys {
+	if error := deployment.UploadFiles(ctx); error != nil {
+		return error
+	}
+
+	if error := ctx.Err(); error != nil {
+		return error
+	}
+
+	if error := deployment.setup(ctx); error != nil {
+		return error
+	}
+
+	return deployment.failedNodesSummary()
+}
+
+// failedNodesSummary returns a single error listing every node --keep-going skipped over, sorted by name, or nil
+// if none failed. It is checked only after every remaining node has gone through the whole deployment, so a
+// --keep-going run still exits non-zero instead of silently swallowing the failures it logged along the way
+func (deployment *Deployment) failedNodesSummary() error {
+	if len(deployment.failedNodes) == 0 {
+		return nil
+	}
+
+	nodeNames := make([]string, 0, len(deployment.failedNodes))
+
+	for nodeName := range deployment.failedNodes {
+		nodeNames = append(nodeNames, nodeName)
+	}
+
+	sort.Strings(nodeNames)
+
+	summaries := make([]string, len(nodeNames))
+
+	for index, nodeName := range nodeNames {
+		summaries[index] = fmt.Sprintf("%s (%s)", nodeName, deployment.failedNodes[nodeName].Error())
+	}
+
+	return fmt.Errorf("deploy finished with %d failed node(s): %s", len(nodeNames), strings.Join(summaries, ", "))
+}
+
+// Close tears down the persistent SSH connection opened for every node, if any were opened
+func (deployment *Deployment) Close() {
+	for _, nodeDeployment := range deployment.nodes {
+		nodeDeployment.Close()
+	}
+}
+
+// UploadFiles uploads every changed asset file to every node over SSH, restarting the k8s-tew service on a node
+// as soon as any of its files changed. ctx is only checked before the uploads start since they already run as
+// a single parallel batch
+func (deployment *Deployment) UploadFiles(ctx context.Context) error {
+	if error := ctx.Err(); error != nil {
+		return error
+	}
+
+	tasks := utils.Tasks{}
+
+	for _, nodeName := range deployment.selectedNodes {
 		nodeDeployment := deployment.nodes[nodeName]
 
-		deployment.config.SetNode(nodeName, nodeDeployment.node)
+		nodeName := nodeName
 
-		if error := nodeDeployment.UploadFiles(deployment.forceUpload); error != nil {
-			return error
+		tasks = append(tasks, func() error {
+			if error := nodeDeployment.UploadFiles(deployment.forceUpload); error != nil {
+				return &NodeError{Node: nodeName, Err: error}
+			}
+
+			return nil
+		})
+	}
+
+	// Each nodeDeployment carries its own cloned config pointed at its own node, so the uploads can safely run
+	// concurrently without racing on a shared config.Node
+	errors := utils.RunParallelTasks(tasks, deployment.parallel, deployment.maxConcurrency)
+	if len(errors) == 0 {
+		return nil
+	}
+
+	if !deployment.keepGoing {
+		return errors
+	}
+
+	// Drop the failed nodes from the remaining steps instead of aborting the whole deploy, so one flaky node
+	// does not stop progress on the rest of the fleet. Deploy reports them all again as a summary at the end
+	deployment.recordNodeFailures(errors)
+
+	return nil
+}
+
+// recordNodeFailures logs each NodeError in errors and removes its node from selectedNodes, so every later
+// deployment step - taints, image pulls, bootstrapper commands - skips nodes that already failed to upload
+func (deployment *Deployment) recordNodeFailures(errors utils.Errors) {
+	for _, error := range errors {
+		nodeError, ok := error.(*NodeError)
+		if !ok {
+			log.WithFields(log.Fields{"error": error}).Error("Deployment step failed")
+
+			continue
 		}
+
+		log.WithFields(log.Fields{"node": nodeError.Node, "error": nodeError.Err}).Error("Node failed, skipping it for the rest of the deployment")
+
+		deployment.failedNodes[nodeError.Node] = nodeError.Err
 	}
 
-	return deployment.setup()
+	remainingNodes := []string{}
+
+	for _, nodeName := range deployment.selectedNodes {
+		if _, failed := deployment.failedNodes[nodeName]; !failed {
+			remainingNodes = append(remainingNodes, nodeName)
+		}
+	}
+
+	deployment.selectedNodes = remainingNodes
 }
 
-func (deployment *Deployment) runCommand(name, command string) error {
+// backoffInterval returns the delay before the given retry attempt (0-based), growing exponentially from
+// initialInterval up to maxInterval and adding jitter so that retries across nodes don't all land at once
+func backoffInterval(attempt uint, initialInterval, maxInterval time.Duration) time.Duration {
+	interval := initialInterval
+
+	for i := uint(0); i < attempt && interval < maxInterval; i++ {
+		interval *= 2
+	}
+
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+
+	return interval/2 + time.Duration(rand.Int63n(int64(interval)/2+1))
+}
+
+func (deployment *Deployment) runCommand(ctx context.Context, name, command string) error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"name": name, "_command": command}).Info("Would execute command")
+
+		return nil
+	}
+
 	var error error
 
 	log.WithFields(log.Fields{"name": name, "_command": command}).Info("Executing command")
 
 	for retries := uint(0); retries < deployment.commandRetries; retries++ {
-		// Run command
-		if error = utils.RunCommand(command); error == nil {
+		if error = ctx.Err(); error != nil {
+			return error
+		}
+
+		// Run command. ctx is passed through so a cancellation kills the command in flight rather than
+		// waiting for it to time out on its own
+		if error = utils.RunCommandContext(ctx, command); error == nil {
 			break
 		}
 
 		log.WithFields(log.Fields{"name": name, "command": command, "error": error}).Debug("Command failed")
 
-		time.Sleep(time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(backoffInterval(retries, deployment.retryInitialInterval, deployment.retryMaxInterval)):
+		}
 	}
 
 	if error != nil {
@@ -180,47 +470,152 @@ func (deployment *Deployment) runCommand(name, command string) error {
 	return nil
 }
 
-func (deployment *Deployment) runConfigureTaints() error {
+func (deployment *Deployment) runConfigureTaints(ctx context.Context) error {
+	for _, nodeName := range deployment.selectedNodes {
+		if error := ctx.Err(); error != nil {
+			return error
+		}
+
+		if error := deployment.configureNodeTaint(ctx, nodeName); error != nil {
+			if !deployment.keepGoing {
+				return error
+			}
+
+			deployment.recordNodeFailures(utils.Errors{&NodeError{Node: nodeName, Err: error}})
+
+			continue
+		}
+	}
+
+	return nil
+}
+
+// configureNodeTaint cordons/drains (if enabled), taints, labels and uncordons a single node, retrying each
+// command up to commandRetries times with backoff
+func (deployment *Deployment) configureNodeTaint(ctx context.Context, nodeName string) error {
 	var _error error
 
-	sortedNodeKeys := deployment.config.GetSortedNodeKeys()
+	nodeDeployment := deployment.nodes[nodeName]
 
-	for _, nodeName := range sortedNodeKeys {
-		nodeDeployment := deployment.nodes[nodeName]
+	deployment.config.SetNode(nodeName, nodeDeployment.node)
 
-		deployment.config.SetNode(nodeName, nodeDeployment.node)
+	if deployment.drainNodes {
+		log.WithFields(log.Fields{"node": nodeName}).Info("Cordoning node")
 
-		log.WithFields(log.Fields{"node": nodeName}).Info("Configuring taint")
+		if _error = nodeDeployment.Cordon(); _error != nil {
+			log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Cordon node failed")
 
-		for retries := uint(0); retries < deployment.commandRetries; retries++ {
-			if _error = nodeDeployment.configureTaint(); _error == nil {
-				break
-			}
+			return _error
+		}
+
+		log.WithFields(log.Fields{"node": nodeName}).Info("Draining node")
+
+		if _error = nodeDeployment.Drain(deployment.drainTimeout, int64(deployment.drainGracePeriod/time.Second), deployment.drainForce); _error != nil {
+			log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Drain node failed")
 
-			time.Sleep(time.Second)
+			return _error
 		}
+	}
 
-		utils.IncreaseProgressStep()
+	log.WithFields(log.Fields{"node": nodeName}).Info("Configuring taint")
+
+	for retries := uint(0); retries < deployment.commandRetries; retries++ {
+		if _error = ctx.Err(); _error != nil {
+			return _error
+		}
+
+		if _error = nodeDeployment.configureTaint(); _error == nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(backoffInterval(retries, deployment.retryInitialInterval, deployment.retryMaxInterval)):
+		}
+	}
 
-		if _error != nil {
-			log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Taint node failed")
+	utils.IncreaseProgressStep()
+	utils.LogProgress("configure-taint", nodeName, "Configured taint")
 
+	if _error != nil {
+		log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Taint node failed")
+
+		return _error
+	}
+
+	log.WithFields(log.Fields{"node": nodeName}).Info("Configuring labels and annotations")
+
+	for retries := uint(0); retries < deployment.commandRetries; retries++ {
+		if _error = ctx.Err(); _error != nil {
 			return _error
 		}
 
+		if _error = nodeDeployment.configureLabels(); _error == nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(backoffInterval(retries, deployment.retryInitialInterval, deployment.retryMaxInterval)):
+		}
+	}
+
+	if _error != nil {
+		log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Labelling node failed")
+
+		return _error
+	}
+
+	if deployment.drainNodes {
+		log.WithFields(log.Fields{"node": nodeName}).Info("Uncordoning node")
+
+		if _error = nodeDeployment.Uncordon(); _error != nil {
+			log.WithFields(log.Fields{"node": nodeName, "error": _error}).Error("Uncordon node failed")
+
+			return _error
+		}
 	}
 
 	return nil
 }
 
-func (deployment *Deployment) runPullImages() error {
+// shouldSkipForFeatures reports whether an entry requiring features should be skipped given skipSetupFeatures.
+// It is the single gate runPullImages and runBoostrapperCommands both go through, so they stay consistent with
+// Features.HasFeatures' documented ANY-match semantics: an entry requiring more than one feature is skipped as
+// soon as any single one of them is in skipSetupFeatures, not only once every one of them is
+func shouldSkipForFeatures(features, skipSetupFeatures config.Features) bool {
+	return features.HasFeatures(skipSetupFeatures)
+}
+
+// runPullImages makes every selected node have every required image locally, either the normal way - crictl
+// pulling it from a registry - or, when imageBundleDirectory is set, by importing the tarball an ImageBundle
+// export produced for it, verifying its digest against the bundle's manifest in the process. This lets an
+// air-gapped cluster be deployed against a bundle created on a connected machine instead of needing outbound
+// registry access on every node
+func (deployment *Deployment) runPullImages(ctx context.Context) error {
 	if !deployment.pullImages {
 		return nil
 	}
 
-	sortedNodeKeys := deployment.config.GetSortedNodeKeys()
+	manifest := []ImageManifestEntry{}
+
+	if len(deployment.imageBundleDirectory) > 0 {
+		var error error
+
+		if manifest, error = ReadImageManifest(deployment.imageBundleDirectory); error != nil {
+			return error
+		}
+	}
+
+	for _, nodeName := range deployment.selectedNodes {
+		if error := ctx.Err(); error != nil {
+			return error
+		}
 
-	for _, nodeName := range sortedNodeKeys {
 		nodeDeployment := deployment.nodes[nodeName]
 
 		deployment.config.SetNode(nodeName, nodeDeployment.node)
@@ -232,17 +627,41 @@ func (deployment *Deployment) runPullImages() error {
 
 			tasks = append(tasks, func() error {
 				defer utils.IncreaseProgressStep()
+				defer utils.LogProgress("pull-image", nodeName, image.Name)
 
-				if image.Features.HasFeatures(deployment.skipSetupFeatures) {
+				if shouldSkipForFeatures(image.Features, deployment.skipSetupFeatures) {
 					return nil
 				}
 
-				return nodeDeployment.pullImage(image.Name)
+				var error error
+
+				if len(deployment.imageBundleDirectory) == 0 {
+					error = nodeDeployment.pullImage(image.Name)
+				} else {
+					entry, found := FindImageManifestEntry(manifest, image.Name)
+					if !found {
+						error = fmt.Errorf("image '%s' not found in bundle manifest in '%s'", image.Name, deployment.imageBundleDirectory)
+					} else {
+						error = nodeDeployment.importImage(deployment.imageBundleDirectory, entry)
+					}
+				}
+
+				if error != nil {
+					return &NodeError{Node: nodeName, Err: error}
+				}
+
+				return nil
 			})
 		}
 
-		if errors := utils.RunParallelTasks(tasks, deployment.parallel); len(errors) > 0 {
-			return errors[0]
+		if errors := utils.RunParallelTasks(tasks, deployment.parallel, deployment.maxConcurrency); len(errors) > 0 {
+			if !deployment.keepGoing {
+				return errors
+			}
+
+			deployment.recordNodeFailures(errors)
+
+			continue
 		}
 	}
 
@@ -250,18 +669,22 @@ func (deployment *Deployment) runPullImages() error {
 }
 
 // Run bootstrapper commands
-func (deployment *Deployment) runBoostrapperCommands() error {
-	for _, command := range deployment.config.Config.Commands {
+func (deployment *Deployment) runBoostrapperCommands(ctx context.Context) error {
+	shouldRun := func(command *config.Command) bool {
 		if !command.Labels.HasLabels([]string{utils.NODE_BOOTSTRAPPER}) {
-			utils.IncreaseProgressStep()
+			return false
+		}
 
-			continue
+		if shouldSkipForFeatures(command.Features, deployment.skipSetupFeatures) {
+			return false
 		}
 
-		if command.Features.HasFeatures(deployment.skipSetupFeatures) {
-			utils.IncreaseProgressStep()
+		return true
+	}
 
-			continue
+	run := func(command *config.Command) error {
+		if error := ctx.Err(); error != nil {
+			return error
 		}
 
 		newCommand, error := deployment.config.ApplyTemplate(command.Name, command.Command)
@@ -269,29 +692,36 @@ func (deployment *Deployment) runBoostrapperCommands() error {
 			return error
 		}
 
-		if error := deployment.runCommand(command.Name, newCommand); error != nil {
+		if error := deployment.runCommand(ctx, command.Name, newCommand); error != nil {
 			return error
 		}
 
 		utils.IncreaseProgressStep()
+		utils.LogProgress("bootstrap-command", "", command.Name)
+
+		return nil
 	}
 
-	return nil
+	onSkip := func(command *config.Command) {
+		utils.IncreaseProgressStep()
+	}
+
+	return deployment.config.Config.Commands.RunDAG(shouldRun, onSkip, run, deployment.parallel)
 }
 
 // Setup nodes
-func (deployment *Deployment) setup() error {
+func (deployment *Deployment) setup(ctx context.Context) error {
 	if deployment.skipSetup {
 		return nil
 	}
 
-	if error := deployment.runConfigureTaints(); error != nil {
+	if error := deployment.runConfigureTaints(ctx); error != nil {
 		return error
 	}
 
-	if error := deployment.runPullImages(); error != nil {
+	if error := deployment.runPullImages(ctx); error != nil {
 		return error
 	}
 
-	return deployment.runBoostrapperCommands()
+	return deployment.runBoostrapperCommands(ctx)
 }
@@ -0,0 +1,34 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/darxkies/k8s-tew/config"
+)
+
+// TestShouldSkipForFeatures pins down the ANY-match gate shared by runPullImages and runBoostrapperCommands: an
+// entry requiring more than one feature is skipped as soon as any single one of them is in skipSetupFeatures
+func TestShouldSkipForFeatures(t *testing.T) {
+	tests := []struct {
+		name              string
+		features          config.Features
+		skipSetupFeatures config.Features
+		expected          bool
+	}{
+		{name: "single feature, matching skip set", features: config.Features{"storage"}, skipSetupFeatures: config.Features{"storage"}, expected: true},
+		{name: "single feature, non-matching skip set", features: config.Features{"storage"}, skipSetupFeatures: config.Features{"showcase"}, expected: false},
+		{name: "multi feature, one of them skipped", features: config.Features{"showcase", "storage"}, skipSetupFeatures: config.Features{"showcase"}, expected: true},
+		{name: "multi feature, none of them skipped", features: config.Features{"showcase", "storage"}, skipSetupFeatures: config.Features{"logging"}, expected: false},
+		{name: "multi feature, all of them skipped", features: config.Features{"showcase", "storage"}, skipSetupFeatures: config.Features{"showcase", "storage"}, expected: true},
+		{name: "empty features are never skipped", features: config.Features{}, skipSetupFeatures: config.Features{"storage"}, expected: false},
+		{name: "features against an empty skip set are never skipped", features: config.Features{"storage"}, skipSetupFeatures: config.Features{}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := shouldSkipForFeatures(test.features, test.skipSetupFeatures); result != test.expected {
+				t.Errorf("shouldSkipForFeatures(%v, %v) = %v, expected %v", test.features, test.skipSetupFeatures, result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// imageManifestFilename is the name of the file recording every exported image's tarball and digest, written
+// next to the tarballs in the bundle directory
+const imageManifestFilename = "manifest.json"
+
+// localContainerdStartupTimeout bounds how long ImageBundle waits for its throwaway local containerd to start
+// accepting connections on its socket
+const localContainerdStartupTimeout = 30 * time.Second
+
+// ImageManifestEntry records one exported image's tarball and content digest, so a bundle can be verified as
+// intact - not corrupted or swapped in transit - before being imported onto a node
+type ImageManifestEntry struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Digest string `json:"digest"`
+}
+
+type ImageBundle struct {
+	config            *config.InternalConfig
+	directory         string
+	skipSetupFeatures config.Features
+	images            []Image
+}
+
+// NewImageBundle prepares to pull and export every image BuildImages returns that does not require a feature
+// skipped via skip*Setup, into directory - the same features deploy's --skip-*-setup flags would skip
+func NewImageBundle(_config *config.InternalConfig, directory string, skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup bool) *ImageBundle {
+	return &ImageBundle{
+		config:            _config,
+		directory:         directory,
+		skipSetupFeatures: buildSkipSetupFeatures(skipStorageSetup, skipMonitoringSetup, skipLoggingSetup, skipBackupSetup, skipShowcaseSetup, skipIngressSetup, skipPackagingSetup),
+		images:            BuildImages(_config),
+	}
+}
+
+func (bundle *ImageBundle) Steps() int {
+	return len(bundle.images)
+}
+
+// Export starts a throwaway local containerd instance, rooted entirely under directory, using the containerd/ctr
+// binaries k8s-tew already downloaded for deployment. It pulls every image not excluded by a skipped feature,
+// exports each to its own tarball and records its digest in manifest.json, then stops containerd again. The
+// result can be copied to an air-gapped environment and loaded onto nodes with ImageImport instead of every node
+// needing outbound registry access
+func (bundle *ImageBundle) Export(ctx context.Context) error {
+	if error := utils.CreateDirectoryIfMissing(bundle.directory); error != nil {
+		return error
+	}
+
+	containerd, socket, error := bundle.startLocalContainerd()
+	if error != nil {
+		return error
+	}
+
+	defer bundle.stopLocalContainerd(containerd)
+
+	manifest := []ImageManifestEntry{}
+
+	for _, image := range bundle.images {
+		if error := ctx.Err(); error != nil {
+			return error
+		}
+
+		// Skip the image if it requires any feature that has been skipped, not only if all of them have
+		if image.Features.HasFeatures(bundle.skipSetupFeatures) {
+			utils.IncreaseProgressStep()
+
+			continue
+		}
+
+		entry, error := bundle.exportImage(socket, image.Name)
+		if error != nil {
+			return error
+		}
+
+		manifest = append(manifest, entry)
+
+		utils.IncreaseProgressStep()
+		utils.LogProgress("export-image", "", image.Name)
+	}
+
+	return bundle.writeManifest(manifest)
+}
+
+func (bundle *ImageBundle) exportImage(socket, image string) (ImageManifestEntry, error) {
+	ctr := bundle.config.GetFullLocalAssetFilename(utils.CTR_BINARY)
+	filename := imageBundleFilename(image)
+
+	if error := utils.RunCommand(fmt.Sprintf("%s -a %s -n k8s.io images pull %s", ctr, socket, image)); error != nil {
+		return ImageManifestEntry{}, fmt.Errorf("could not pull image '%s' (%s)", image, error.Error())
+	}
+
+	if error := utils.RunCommand(fmt.Sprintf("%s -a %s -n k8s.io images export %s %s", ctr, socket, path.Join(bundle.directory, filename), image)); error != nil {
+		return ImageManifestEntry{}, fmt.Errorf("could not export image '%s' (%s)", image, error.Error())
+	}
+
+	digest, error := utils.RunCommandWithOutput(fmt.Sprintf("%s -a %s -n k8s.io images ls -q name==%s", ctr, socket, image))
+	if error != nil {
+		return ImageManifestEntry{}, fmt.Errorf("could not determine digest of image '%s' (%s)", image, error.Error())
+	}
+
+	return ImageManifestEntry{Name: image, File: filename, Digest: strings.TrimSpace(digest)}, nil
+}
+
+func (bundle *ImageBundle) writeManifest(manifest []ImageManifestEntry) error {
+	encoded, error := json.MarshalIndent(manifest, "", "  ")
+	if error != nil {
+		return error
+	}
+
+	return ioutil.WriteFile(path.Join(bundle.directory, imageManifestFilename), encoded, 0644)
+}
+
+// ReadImageManifest loads the manifest written by ImageBundle.Export from directory, so deploy can import exactly
+// the images - and verify exactly the digests - a bundle export recorded
+func ReadImageManifest(directory string) ([]ImageManifestEntry, error) {
+	content, error := ioutil.ReadFile(path.Join(directory, imageManifestFilename))
+	if error != nil {
+		return nil, fmt.Errorf("could not read image bundle manifest in '%s' (%s)", directory, error.Error())
+	}
+
+	manifest := []ImageManifestEntry{}
+
+	if error := json.Unmarshal(content, &manifest); error != nil {
+		return nil, fmt.Errorf("could not parse image bundle manifest in '%s' (%s)", directory, error.Error())
+	}
+
+	return manifest, nil
+}
+
+// FindImageManifestEntry returns the manifest entry for image, if any
+func FindImageManifestEntry(manifest []ImageManifestEntry, image string) (ImageManifestEntry, bool) {
+	for _, entry := range manifest {
+		if entry.Name == image {
+			return entry, true
+		}
+	}
+
+	return ImageManifestEntry{}, false
+}
+
+// imageBundleFilename turns an image reference into a filesystem-safe tarball name, e.g.
+// "docker.io/library/pause:3.2" becomes "docker.io_library_pause_3.2.tar"
+func imageBundleFilename(image string) string {
+	replacer := strings.NewReplacer("/", "_", ":", "_", "@", "_")
+
+	return replacer.Replace(image) + ".tar"
+}
+
+// startLocalContainerd starts a containerd instance rooted entirely under the bundle directory so it never
+// touches (or conflicts with) a containerd already installed on this machine, and waits for it to accept
+// connections on its socket before returning
+func (bundle *ImageBundle) startLocalContainerd() (*exec.Cmd, string, error) {
+	rootDirectory := path.Join(bundle.directory, ".containerd-root")
+	stateDirectory := path.Join(bundle.directory, ".containerd-state")
+	socket := path.Join(stateDirectory, "containerd.sock")
+
+	for _, directory := range []string{rootDirectory, stateDirectory} {
+		if error := utils.CreateDirectoryIfMissing(directory); error != nil {
+			return nil, "", error
+		}
+	}
+
+	containerdBinary := bundle.config.GetFullLocalAssetFilename(utils.CONTAINERD_BINARY)
+
+	command := exec.Command(containerdBinary, "--root", rootDirectory, "--state", stateDirectory, "--address", socket)
+
+	if error := command.Start(); error != nil {
+		return nil, "", fmt.Errorf("could not start local containerd (%s)", error.Error())
+	}
+
+	if error := waitForUnixSocket(socket, localContainerdStartupTimeout); error != nil {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+
+		return nil, "", error
+	}
+
+	return command, socket, nil
+}
+
+func (bundle *ImageBundle) stopLocalContainerd(command *exec.Cmd) {
+	if command == nil || command.Process == nil {
+		return
+	}
+
+	_ = command.Process.Kill()
+	_ = command.Wait()
+}
+
+// waitForUnixSocket polls until a unix socket at path accepts connections, or timeout elapses
+func waitForUnixSocket(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if connection, error := net.Dial("unix", path); error == nil {
+			connection.Close()
+
+			return nil
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return fmt.Errorf("local containerd did not become ready on '%s' within %s", path, timeout)
+}
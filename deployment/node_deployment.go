@@ -10,6 +10,8 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/darxkies/k8s-tew/config"
 	"github.com/darxkies/k8s-tew/utils"
@@ -17,6 +19,8 @@ import (
 	"github.com/tmc/scp"
 	"golang.org/x/crypto/ssh"
 	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -24,17 +28,29 @@ import (
 
 const CONCURRENT_SSH_CONNECTIONS_LIMIT = 10
 
+// MIRROR_POD_ANNOTATION marks a pod as a kubelet-managed static pod mirror, which the API server cannot evict
+const MIRROR_POD_ANNOTATION = "kubernetes.io/config.mirror"
+
+// sshKeepAliveInterval is how often a no-op request is sent over the persistent SSH connection so that long
+// deploys don't get dropped by an idle timeout on either end
+const sshKeepAliveInterval = 30 * time.Second
+
 type NodeDeployment struct {
-	identityFile string
-	name         string
-	node         *config.Node
-	config       *config.InternalConfig
-	sshLimiter   *utils.Limiter
-	parallel     bool
+	identityFile      string
+	name              string
+	node              *config.Node
+	config            *config.InternalConfig
+	sshLimiter        *utils.Limiter
+	parallel          bool
+	dryRun            bool
+	pullImageFallback bool
+	sshMutex          sync.Mutex
+	sshClient         *ssh.Client
+	sshDone           chan struct{}
 }
 
-func NewNodeDeployment(identityFile string, name string, node *config.Node, config *config.InternalConfig, parallel bool) *NodeDeployment {
-	return &NodeDeployment{identityFile: identityFile, name: name, node: node, config: config, sshLimiter: utils.NewLimiter(CONCURRENT_SSH_CONNECTIONS_LIMIT), parallel: parallel}
+func NewNodeDeployment(identityFile string, name string, node *config.Node, config *config.InternalConfig, parallel, dryRun, pullImageFallback bool) *NodeDeployment {
+	return &NodeDeployment{identityFile: identityFile, name: name, node: node, config: config, sshLimiter: utils.NewLimiter(CONCURRENT_SSH_CONNECTIONS_LIMIT), parallel: parallel, dryRun: dryRun, pullImageFallback: pullImageFallback}
 }
 
 func (deployment *NodeDeployment) Steps() (result int) {
@@ -77,6 +93,7 @@ func (deployment *NodeDeployment) md5sum(filename string) (result string, error
 
 func (deployment *NodeDeployment) createDirectories() error {
 	defer utils.IncreaseProgressStep()
+	defer utils.LogProgress("create-directories", deployment.name, "Created directories")
 
 	directories := map[string]bool{}
 
@@ -121,7 +138,7 @@ func (deployment *NodeDeployment) getFiles() map[string]string {
 			continue
 		}
 
-		fromFile := deployment.config.GetFullLocalAssetFilename(name)
+		fromFile := deployment.config.GetFullLocalDeployableAssetFilename(name)
 		toFile := deployment.config.GetFullTargetAssetFilename(name)
 
 		files[fromFile] = toFile
@@ -177,6 +194,8 @@ func (deployment *NodeDeployment) getChangedFiles() map[string]string {
 	return files
 }
 
+// UploadFiles uploads every asset file assigned to this node, skipping files whose remote md5sum already
+// matches the local one. forceUpload bypasses the checksum comparison and re-uploads everything
 func (deployment *NodeDeployment) UploadFiles(forceUpload bool) (_error error) {
 	if _error = deployment.createDirectories(); _error != nil {
 		return
@@ -196,11 +215,12 @@ func (deployment *NodeDeployment) UploadFiles(forceUpload bool) (_error error) {
 	}
 
 	utils.IncreaseProgressStep()
+	utils.LogProgress("stop-service", deployment.name, "Stopped service")
 
 	tasks := utils.Tasks{}
 
 	for name, file := range deployment.config.Config.Assets.Files {
-		fromFile := deployment.config.GetFullLocalAssetFilename(name)
+		fromFile := deployment.config.GetFullLocalDeployableAssetFilename(name)
 		toFile := deployment.config.GetFullTargetAssetFilename(name)
 
 		if !config.CompareLabels(deployment.node.Labels, file.Labels) {
@@ -215,16 +235,23 @@ func (deployment *NodeDeployment) UploadFiles(forceUpload bool) (_error error) {
 			continue
 		}
 
+		name := name
+
 		tasks = append(tasks, func() error {
 			defer utils.IncreaseProgressStep()
+			defer utils.LogProgress("upload-file", deployment.name, name)
 
-			return deployment.UploadFile(fromFile, toFile)
+			if error := deployment.UploadFile(fromFile, toFile); error != nil {
+				return fmt.Errorf("file '%s': %s", name, error.Error())
+			}
+
+			return nil
 		})
 	}
 
 	// Upload files
-	if errors := utils.RunParallelTasks(tasks, deployment.parallel); len(errors) > 0 {
-		return errors[0]
+	if errors := utils.RunParallelTasks(tasks, deployment.parallel, 0); len(errors) > 0 {
+		return errors
 	}
 
 	if len(files) > 0 {
@@ -233,11 +260,21 @@ func (deployment *NodeDeployment) UploadFiles(forceUpload bool) (_error error) {
 	}
 
 	utils.IncreaseProgressStep()
+	utils.LogProgress("start-service", deployment.name, "Started service")
 
 	return
 }
 
-func (deployment *NodeDeployment) getSession() (*ssh.Session, error) {
+// getClient returns the persistent SSH client for the node, dialing and starting its keepalive loop the
+// first time it is needed, and reusing it for every subsequent upload/command
+func (deployment *NodeDeployment) getClient() (*ssh.Client, error) {
+	deployment.sshMutex.Lock()
+	defer deployment.sshMutex.Unlock()
+
+	if deployment.sshClient != nil {
+		return deployment.sshClient, nil
+	}
+
 	privateKeyContent, error := ioutil.ReadFile(deployment.identityFile)
 	if error != nil {
 		return nil, error
@@ -259,25 +296,149 @@ func (deployment *NodeDeployment) getSession() (*ssh.Session, error) {
 		return nil, error
 	}
 
-	return client.NewSession()
+	deployment.sshClient = client
+	deployment.sshDone = make(chan struct{})
+
+	go deployment.keepAlive(client, deployment.sshDone)
+
+	return client, nil
+}
+
+// keepAlive periodically pings the connection so it is not dropped for being idle during long deploys. It
+// stops once done is closed or the ping itself fails, in which case the connection is assumed to be dead
+func (deployment *NodeDeployment) keepAlive(client *ssh.Client, done chan struct{}) {
+	ticker := time.NewTicker(sshKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, _, error := client.SendRequest("keepalive@k8s-tew", true, nil); error != nil {
+				deployment.closeClient()
+
+				return
+			}
+
+		case <-done:
+			return
+		}
+	}
+}
+
+// closeClient tears down the persistent SSH client, if one is open, so that the next call to getClient
+// dials a fresh connection instead of reusing a broken one
+func (deployment *NodeDeployment) closeClient() {
+	deployment.sshMutex.Lock()
+	defer deployment.sshMutex.Unlock()
+
+	if deployment.sshClient == nil {
+		return
+	}
+
+	close(deployment.sshDone)
+
+	deployment.sshClient.Close()
+
+	deployment.sshClient = nil
+}
+
+// Close tears down the node's persistent SSH connection, if one was opened. Safe to call even if no
+// connection was ever established
+func (deployment *NodeDeployment) Close() {
+	deployment.closeClient()
+}
+
+func (deployment *NodeDeployment) getSession() (*ssh.Session, error) {
+	client, error := deployment.getClient()
+	if error != nil {
+		return nil, error
+	}
+
+	session, error := client.NewSession()
+	if error != nil {
+		deployment.closeClient()
+
+		return nil, error
+	}
+
+	return session, nil
 }
 
+// pullImage pulls image via crictl, which resolves registry auth from the kubelet's config. If that fails and
+// pullImageFallback is enabled, it retries the pull with containerd's ctr directly into the k8s.io namespace -
+// some registries or proxies are only reachable through one of the two tools - logging which one succeeded
 func (deployment *NodeDeployment) pullImage(image string) error {
 	deployment.sshLimiter.Lock()
 	defer deployment.sshLimiter.Unlock()
 
-	crictl := deployment.config.GetFullTargetAssetFilename(utils.CRICTL_BINARY)
 	containerdSock := deployment.config.GetFullTargetAssetFilename(utils.CONTAINERD_SOCK)
-	command := fmt.Sprintf("CONTAINER_RUNTIME_ENDPOINT=unix://%s %s pull %s", containerdSock, crictl, image)
+	crictl := deployment.config.GetFullTargetAssetFilename(utils.CRICTL_BINARY)
+	crictlCommand := fmt.Sprintf("CONTAINER_RUNTIME_ENDPOINT=unix://%s %s pull %s", containerdSock, crictl, image)
 
-	if _, error := deployment.Execute(fmt.Sprintf("pull-image-%s", image), command); error != nil {
+	if _, error := deployment.Execute(fmt.Sprintf("pull-image-%s", image), crictlCommand); error == nil {
+		return nil
+	} else if !deployment.pullImageFallback {
 		return fmt.Errorf("Failed to pull image %s (%s)", image, error.Error())
+	} else {
+		log.WithFields(log.Fields{"node": deployment.name, "image": image, "error": error}).Warn("crictl pull failed, falling back to ctr")
+	}
+
+	ctr := deployment.config.GetFullTargetAssetFilename(utils.CTR_BINARY)
+	ctrCommand := fmt.Sprintf("%s -a %s -n k8s.io images pull %s", ctr, containerdSock, image)
+
+	if _, error := deployment.Execute(fmt.Sprintf("pull-image-fallback-%s", image), ctrCommand); error != nil {
+		return fmt.Errorf("Failed to pull image %s with crictl and ctr fallback (%s)", image, error.Error())
+	}
+
+	log.WithFields(log.Fields{"node": deployment.name, "image": image}).Info("Pulled image via ctr fallback")
+
+	return nil
+}
+
+// importImage uploads the tarball an ImageBundle export wrote for entry into the node's temporary directory and
+// loads it into containerd's k8s.io namespace with ctr, then checks the loaded digest against entry.Digest so a
+// bundle corrupted or swapped in transit is caught before anything depends on the image being correct. This
+// replaces pullImage's registry round-trip with a local import, for air-gapped nodes with no registry access
+func (deployment *NodeDeployment) importImage(bundleDirectory string, entry ImageManifestEntry) error {
+	deployment.sshLimiter.Lock()
+	defer deployment.sshLimiter.Unlock()
+
+	remoteFile := path.Join(deployment.config.GetFullTargetAssetDirectory(utils.TEMPORARY_DIRECTORY), entry.File)
+
+	if error := deployment.uploadFile(path.Join(bundleDirectory, entry.File), remoteFile); error != nil {
+		return fmt.Errorf("could not upload image bundle file '%s' (%s)", entry.File, error.Error())
+	}
+
+	ctr := deployment.config.GetFullTargetAssetFilename(utils.CTR_BINARY)
+	containerdSock := deployment.config.GetFullTargetAssetFilename(utils.CONTAINERD_SOCK)
+
+	importCommand := fmt.Sprintf("%s -a %s -n k8s.io images import %s && rm -f %s", ctr, containerdSock, remoteFile, remoteFile)
+
+	if _, error := deployment.Execute(fmt.Sprintf("import-image-%s", entry.Name), importCommand); error != nil {
+		return fmt.Errorf("could not import image '%s' (%s)", entry.Name, error.Error())
+	}
+
+	digestCommand := fmt.Sprintf("%s -a %s -n k8s.io images ls -q name==%s", ctr, containerdSock, entry.Name)
+
+	digest, error := deployment.Execute(fmt.Sprintf("verify-image-%s", entry.Name), digestCommand)
+	if error != nil {
+		return fmt.Errorf("could not verify image '%s' (%s)", entry.Name, error.Error())
+	}
+
+	if digest = strings.TrimSpace(digest); len(digest) > 0 && digest != entry.Digest {
+		return fmt.Errorf("image '%s' digest mismatch after import, expected '%s' got '%s'", entry.Name, entry.Digest, digest)
 	}
 
 	return nil
 }
 
 func (deployment *NodeDeployment) Execute(name, command string) (string, error) {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"name": name, "node": deployment.name, "_target": deployment.node.IP, "_command": command}).Info("Would execute remote command")
+
+		return "", nil
+	}
+
 	log.WithFields(log.Fields{"name": name, "node": deployment.name, "_target": deployment.node.IP, "_command": command}).Info("Executing remote command")
 
 	session, error := deployment.getSession()
@@ -300,8 +461,20 @@ func (deployment *NodeDeployment) UploadFile(from, to string) error {
 	deployment.sshLimiter.Lock()
 	defer deployment.sshLimiter.Unlock()
 
+	return deployment.uploadFile(from, to)
+}
+
+// uploadFile is UploadFile's body without the sshLimiter locking, for callers such as importImage that need to
+// hold the lock across the upload and the Execute calls that follow it
+func (deployment *NodeDeployment) uploadFile(from, to string) error {
 	filename := path.Base(to)
 
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"name": filename, "node": deployment.name, "_target": deployment.node.IP, "_source-filename": from, "_destination-filename": to}).Info("Would deploy")
+
+		return nil
+	}
+
 	log.WithFields(log.Fields{"name": filename, "node": deployment.name, "_target": deployment.node.IP, "_source-filename": from, "_destination-filename": to}).Info("Deploying")
 
 	session, error := deployment.getSession()
@@ -318,17 +491,26 @@ func (deployment *NodeDeployment) UploadFile(from, to string) error {
 	return nil
 }
 
-func (deployment *NodeDeployment) configureTaint() error {
+// getClientset creates a client-go client talking to the cluster through the local admin kubeconfig
+func (deployment *NodeDeployment) getClientset() (*kubernetes.Clientset, error) {
 	kubeconfig := deployment.config.GetFullLocalAssetFilename(utils.ADMIN_KUBECONFIG)
 
-	// Configure connection
 	config, error := clientcmd.BuildConfigFromFlags("", kubeconfig)
 	if error != nil {
-		return error
+		return nil, error
 	}
 
-	// Create client
-	clientset, error := kubernetes.NewForConfig(config)
+	return kubernetes.NewForConfig(config)
+}
+
+func (deployment *NodeDeployment) configureTaint() error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"node": deployment.name}).Info("Would configure taint")
+
+		return nil
+	}
+
+	clientset, error := deployment.getClientset()
 	if error != nil {
 		return error
 	}
@@ -341,27 +523,35 @@ func (deployment *NodeDeployment) configureTaint() error {
 
 	changed := false
 
+	controllerTaintKeys := map[string]bool{}
+
+	for _, controllerTaint := range deployment.config.Config.ControllerTaints {
+		controllerTaintKeys[controllerTaint.Key] = true
+	}
+
 	if deployment.node.IsControllerOnly() {
-		found := false
+		for _, controllerTaint := range deployment.config.Config.ControllerTaints {
+			found := false
 
-		for _, taint := range node.Spec.Taints {
-			if taint.Key == CONTROLLER_ONLY_TAINT_KEY {
-				found = true
+			for _, taint := range node.Spec.Taints {
+				if taint.Key == controllerTaint.Key {
+					found = true
 
-				break
+					break
+				}
 			}
-		}
 
-		if !found {
-			node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: CONTROLLER_ONLY_TAINT_KEY, Value: "true", Effect: v1.TaintEffectNoSchedule})
+			if !found {
+				node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: controllerTaint.Key, Value: controllerTaint.Value, Effect: v1.TaintEffect(controllerTaint.Effect)})
 
-			changed = true
+				changed = true
+			}
 		}
 	} else {
 		taints := []v1.Taint{}
 
 		for _, taint := range node.Spec.Taints {
-			if taint.Key == CONTROLLER_ONLY_TAINT_KEY {
+			if controllerTaintKeys[taint.Key] {
 				changed = true
 
 				continue
@@ -381,3 +571,197 @@ func (deployment *NodeDeployment) configureTaint() error {
 
 	return error
 }
+
+// configureLabels idempotently applies the node's kubernetes-labels and kubernetes-annotations to its Node
+// object, on top of whatever labels the kubelet itself already set (e.g. the built-in role labels), so
+// scheduling constraints like disktype=ssd or topology zones can be expressed through config
+func (deployment *NodeDeployment) configureLabels() error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"node": deployment.name}).Info("Would configure labels and annotations")
+
+		return nil
+	}
+
+	if len(deployment.node.KubernetesLabels) == 0 && len(deployment.node.KubernetesAnnotations) == 0 {
+		return nil
+	}
+
+	clientset, error := deployment.getClientset()
+	if error != nil {
+		return error
+	}
+
+	node, error := clientset.CoreV1().Nodes().Get(deployment.name, metav1.GetOptions{})
+	if error != nil {
+		return error
+	}
+
+	changed := false
+
+	if node.ObjectMeta.Labels == nil {
+		node.ObjectMeta.Labels = map[string]string{}
+	}
+
+	for key, value := range deployment.node.KubernetesLabels {
+		if node.ObjectMeta.Labels[key] != value {
+			node.ObjectMeta.Labels[key] = value
+
+			changed = true
+		}
+	}
+
+	if node.ObjectMeta.Annotations == nil {
+		node.ObjectMeta.Annotations = map[string]string{}
+	}
+
+	for key, value := range deployment.node.KubernetesAnnotations {
+		if node.ObjectMeta.Annotations[key] != value {
+			node.ObjectMeta.Annotations[key] = value
+
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	_, error = clientset.CoreV1().Nodes().Update(node)
+
+	return error
+}
+
+// setUnschedulable cordons or uncordons the node depending on unschedulable
+func (deployment *NodeDeployment) setUnschedulable(unschedulable bool) error {
+	clientset, error := deployment.getClientset()
+	if error != nil {
+		return error
+	}
+
+	node, error := clientset.CoreV1().Nodes().Get(deployment.name, metav1.GetOptions{})
+	if error != nil {
+		return error
+	}
+
+	if node.Spec.Unschedulable == unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = unschedulable
+
+	_, error = clientset.CoreV1().Nodes().Update(node)
+
+	return error
+}
+
+// Cordon marks the node unschedulable so the scheduler stops placing new pods on it
+func (deployment *NodeDeployment) Cordon() error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"node": deployment.name}).Info("Would cordon node")
+
+		return nil
+	}
+
+	return deployment.setUnschedulable(true)
+}
+
+// Uncordon marks the node schedulable again
+func (deployment *NodeDeployment) Uncordon() error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"node": deployment.name}).Info("Would uncordon node")
+
+		return nil
+	}
+
+	return deployment.setUnschedulable(false)
+}
+
+// isDaemonSetPod reports whether pod is owned by a DaemonSet, since those are recreated by the kubelet itself and
+// are not meant to move to another node
+func isDaemonSetPod(pod v1.Pod) bool {
+	for _, ownerReference := range pod.OwnerReferences {
+		if ownerReference.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMirrorPod reports whether pod is a static pod mirrored by the kubelet, which cannot be evicted since it is
+// not managed by the API server
+func isMirrorPod(pod v1.Pod) bool {
+	_, ok := pod.Annotations[MIRROR_POD_ANNOTATION]
+
+	return ok
+}
+
+// Drain evicts every pod running on the node - except DaemonSet and static/mirror pods, which stay where they
+// are - through the eviction API so PodDisruptionBudgets are honored, then waits up to timeout for them to
+// terminate. If force is set, pods still running once timeout elapses are deleted with gracePeriodSeconds instead
+// of making the caller wait or fail
+func (deployment *NodeDeployment) Drain(timeout time.Duration, gracePeriodSeconds int64, force bool) error {
+	if deployment.dryRun {
+		log.WithFields(log.Fields{"node": deployment.name}).Info("Would drain node")
+
+		return nil
+	}
+
+	clientset, error := deployment.getClientset()
+	if error != nil {
+		return error
+	}
+
+	pods, error := clientset.CoreV1().Pods("").List(metav1.ListOptions{FieldSelector: fmt.Sprintf("spec.nodeName=%s", deployment.name)})
+	if error != nil {
+		return error
+	}
+
+	evicted := []v1.Pod{}
+
+	for _, pod := range pods.Items {
+		if isDaemonSetPod(pod) || isMirrorPod(pod) {
+			continue
+		}
+
+		eviction := &policy.Eviction{
+			ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+			DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds},
+		}
+
+		if error := clientset.PolicyV1beta1().Evictions(pod.Namespace).Evict(eviction); error != nil {
+			return fmt.Errorf("failed to evict pod '%s/%s' (%s)", pod.Namespace, pod.Name, error.Error())
+		}
+
+		evicted = append(evicted, pod)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for _, pod := range evicted {
+		for {
+			_, error := clientset.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(error) {
+				break
+			}
+
+			if time.Now().Before(deadline) {
+				time.Sleep(time.Second)
+
+				continue
+			}
+
+			if !force {
+				return fmt.Errorf("timed out waiting for pod '%s/%s' to terminate on node '%s'", pod.Namespace, pod.Name, deployment.name)
+			}
+
+			if error := clientset.CoreV1().Pods(pod.Namespace).Delete(pod.Name, metav1.NewDeleteOptions(0)); error != nil && !apierrors.IsNotFound(error) {
+				return fmt.Errorf("failed to force delete pod '%s/%s' (%s)", pod.Namespace, pod.Name, error.Error())
+			}
+
+			break
+		}
+	}
+
+	return nil
+}
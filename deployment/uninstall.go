@@ -0,0 +1,99 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darxkies/k8s-tew/config"
+	"github.com/darxkies/k8s-tew/utils"
+)
+
+// uninstallOwnedDirectories lists the asset directories that belong exclusively to k8s-tew and can be removed
+// wholesale during Uninstall - unlike shared system directories such as systemd's unit directory, removing them
+// does not risk taking anything but k8s-tew's own files with them
+var uninstallOwnedDirectories = []string{utils.CONFIG_DIRECTORY, utils.BINARIES_DIRECTORY, utils.DYNAMIC_DATA_DIRECTORY, utils.LOGGING_DIRECTORY}
+
+type Uninstallation struct {
+	config        *config.InternalConfig
+	nodes         map[string]*NodeDeployment
+	selectedNodes []string
+}
+
+// NewUninstallation sets up one NodeDeployment per node, restricted to the nodes selected via skipNodes/onlyNodes,
+// reusing the same filtering Deploy uses so an operator can decommission the same subset of nodes they deployed to
+func NewUninstallation(_config *config.InternalConfig, identityFile string, skipNodes, onlyNodes []string) *Uninstallation {
+	nodes := map[string]*NodeDeployment{}
+
+	for nodeName, node := range _config.Config.Nodes {
+		nodes[nodeName] = NewNodeDeployment(identityFile, nodeName, node, _config.Clone(nodeName, node), false, false, false)
+	}
+
+	return &Uninstallation{config: _config, nodes: nodes, selectedNodes: SelectNodes(_config, skipNodes, onlyNodes)}
+}
+
+func (uninstallation *Uninstallation) Steps() int {
+	return len(uninstallation.selectedNodes)
+}
+
+// Close tears down the persistent SSH connection opened for every node, if any were opened
+func (uninstallation *Uninstallation) Close() {
+	for _, nodeDeployment := range uninstallation.nodes {
+		nodeDeployment.Close()
+	}
+}
+
+// Uninstall removes k8s-tew from every selected node: it stops and disables the systemd service, removes the
+// CNI's network interfaces and configuration, unmounts anything still mounted under the containerd/kubelet data
+// directories and finally deletes every directory k8s-tew owns outright. ctx is checked between nodes so a
+// cancellation is honored as soon as the node in progress finishes
+func (uninstallation *Uninstallation) Uninstall(ctx context.Context) error {
+	defer uninstallation.Close()
+
+	for _, nodeName := range uninstallation.selectedNodes {
+		if error := ctx.Err(); error != nil {
+			return error
+		}
+
+		if error := uninstallation.uninstallNode(uninstallation.nodes[nodeName]); error != nil {
+			return fmt.Errorf("could not uninstall node '%s' (%s)", nodeName, error.Error())
+		}
+
+		utils.IncreaseProgressStep()
+		utils.LogProgress("uninstall", nodeName, "Uninstalled")
+	}
+
+	return nil
+}
+
+func (uninstallation *Uninstallation) uninstallNode(nodeDeployment *NodeDeployment) error {
+	serviceFile := uninstallation.config.GetFullTargetAssetFilename(utils.SERVICE_CONFIG)
+
+	// Best effort - the service might already be stopped, disabled or removed
+	_, _ = nodeDeployment.Execute("stop-service", fmt.Sprintf("systemctl stop %s; systemctl disable %s; rm -f %s; systemctl daemon-reload", utils.SERVICE_NAME, utils.SERVICE_NAME, serviceFile))
+
+	cniConfigDirectory := uninstallation.config.GetFullTargetAssetDirectory(utils.CNI_CONFIG_DIRECTORY)
+	cniBinariesDirectory := uninstallation.config.GetFullTargetAssetDirectory(utils.CNI_BINARIES_DIRECTORY)
+
+	// Best effort - removes the CNI configuration/binaries and any calico/cilium/weave network interface left
+	// behind by it, ignoring nodes where none of them ever existed
+	_, _ = nodeDeployment.Execute("clean-cni", fmt.Sprintf(`rm -rf %s/* %s/*; for _interface in $(ip -o link show | awk -F ': ' '{print $2}' | grep -E '^(cali|cilium|weave|vxlan)'); do ip link delete "$_interface"; done; true`, cniConfigDirectory, cniBinariesDirectory))
+
+	kubeletDataDirectory := uninstallation.config.GetFullTargetAssetDirectory(utils.KUBELET_DATA_DIRECTORY)
+	containerdDataDirectory := uninstallation.config.GetFullTargetAssetDirectory(utils.CONTAINERD_DATA_DIRECTORY)
+
+	// Best effort - lazily unmounts anything still mounted under the kubelet/containerd data directories (e.g.
+	// volume or overlay mounts) so the directories below can actually be removed
+	_, _ = nodeDeployment.Execute("unmount-data-directories", fmt.Sprintf(`awk '{print $2}' /proc/mounts | grep -E '^(%s|%s)/' | sort -r | xargs --no-run-if-empty umount -l`, kubeletDataDirectory, containerdDataDirectory))
+
+	removeDirectoriesCommand := "rm -rf"
+
+	for _, name := range uninstallOwnedDirectories {
+		removeDirectoriesCommand += " " + uninstallation.config.GetFullTargetAssetDirectory(name)
+	}
+
+	if _, error := nodeDeployment.Execute("remove-files", removeDirectoriesCommand); error != nil {
+		return error
+	}
+
+	return nil
+}
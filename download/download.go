@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/cavaliercoder/grab"
 	"github.com/darxkies/k8s-tew/config"
 	"github.com/darxkies/k8s-tew/utils"
+	"golang.org/x/net/http/httpproxy"
 )
 
 type CompressedFile struct {
@@ -21,54 +23,68 @@ type CompressedFile struct {
 	TargetFile string
 }
 
+// archDownloadTask downloads one artifact for the given architecture
+type archDownloadTask func(arch string) error
+
 type Downloader struct {
-	config          *config.InternalConfig
-	downloaderSteps utils.Tasks
-	forceDownload   bool
-	parallel        bool
+	config                   *config.InternalConfig
+	archDownloadTasks        []archDownloadTask
+	forceDownload            bool
+	parallel                 bool
+	skipChecksumVerification bool
 }
 
-func NewDownloader(config *config.InternalConfig, forceDownload bool, parallel bool) Downloader {
-	downloader := Downloader{config: config, forceDownload: forceDownload, parallel: parallel}
+func NewDownloader(config *config.InternalConfig, forceDownload bool, parallel bool, skipChecksumVerification bool) Downloader {
+	downloader := Downloader{config: config, forceDownload: forceDownload, parallel: parallel, skipChecksumVerification: skipChecksumVerification}
 
-	downloader.downloaderSteps = utils.Tasks{}
-	downloader.addTask(downloader.copyK8STEW)
-	downloader.addTask(downloader.downloadEtcdBinaries)
-	downloader.addTask(downloader.downloadKubernetesBinaries)
-	downloader.addTask(downloader.downloadHelmBinary)
-	downloader.addTask(downloader.downloadContainerdBinaries)
-	downloader.addTask(downloader.downloadRuncBinary)
-	downloader.addTask(downloader.downloadCriCtlBinary)
-	downloader.addTask(downloader.downloadGobetweenBinary)
-	downloader.addTask(downloader.downloadArkBinaries)
+	downloader.archDownloadTasks = []archDownloadTask{
+		downloader.downloadEtcdBinaries,
+		downloader.downloadKubernetesBinaries,
+		downloader.downloadHelmBinary,
+		downloader.downloadContainerdBinaries,
+		downloader.downloadRuncBinary,
+		downloader.downloadCriCtlBinary,
+		downloader.downloadGobetweenBinary,
+		downloader.downloadVeleroBinaries,
+	}
 
 	return downloader
 }
 
-func (downloader *Downloader) addTask(task utils.Task) {
-	downloader.downloaderSteps = append(downloader.downloaderSteps, func() error {
-		defer utils.IncreaseProgressStep()
-
-		return task()
-	})
-}
-
+// Steps returns the number of progress steps DownloadBinaries will report: copying k8s-tew once, plus every
+// architecture-specific download task repeated for every architecture present in the cluster
 func (downloader Downloader) Steps() int {
-	return len(downloader.downloaderSteps)
+	return 1 + len(downloader.archDownloadTasks)*len(downloader.config.GetArchs())
 }
 
-func (downloader Downloader) getURL(url, filename string) (string, error) {
+func (downloader Downloader) getURL(url, filename, arch string) (string, error) {
 	data := struct {
 		Filename string
 		Versions config.Versions
+		Arch     string
 	}{
 		Filename: filename,
 		Versions: downloader.config.Config.Versions,
+		Arch:     arch,
 	}
 
 	return utils.ApplyTemplate(url, url, data, false)
 }
 
+// proxyFunc builds the download client's proxy decision from http-proxy/https-proxy/no-proxy, falling
+// back to no proxy at all (the previous hardcoded behaviour) when none of them are set
+func (downloader Downloader) proxyFunc() func(*http.Request) (*neturl.URL, error) {
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  downloader.config.Config.HTTPProxy,
+		HTTPSProxy: downloader.config.Config.HTTPSProxy,
+		NoProxy:    downloader.config.Config.NoProxy,
+	}
+
+	return func(request *http.Request) (*neturl.URL, error) {
+		return proxyConfig.ProxyFunc()(request.URL)
+	}
+}
+
 func (downloader Downloader) downloadFile(url, filename string) error {
 	utils.LogURL("Downloading", url)
 
@@ -81,10 +97,9 @@ func (downloader Downloader) downloadFile(url, filename string) error {
 	// Set connection timeout
 	client.HTTPClient.Timeout = 10 * time.Second
 
-	// Disable any proxies
 	client.HTTPClient = &http.Client{
 		Transport: &http.Transport{
-			Proxy:           nil,
+			Proxy:           downloader.proxyFunc(),
 			TLSClientConfig: &tls.Config{},
 		},
 	}
@@ -106,12 +121,35 @@ func (downloader Downloader) downloadFile(url, filename string) error {
 	return nil
 }
 
-func (downloader Downloader) downloadExecutable(urlTemplate, remoteFilename, filename string) error {
-	url, error := downloader.getURL(urlTemplate, remoteFilename)
+// verifyChecksum compares the downloaded filename against the pinned checksum for artifactName, if there is one,
+// deleting filename on mismatch so a corrupted or tampered download is never installed
+func (downloader Downloader) verifyChecksum(artifactName, filename string) error {
+	if downloader.skipChecksumVerification {
+		return nil
+	}
+
+	expectedHash, ok := utils.Checksums[artifactName]
+	if !ok {
+		return nil
+	}
+
+	if error := utils.VerifyChecksum(filename, expectedHash); error != nil {
+		os.Remove(filename)
+
+		return error
+	}
+
+	return nil
+}
+
+func (downloader Downloader) downloadExecutable(artifactName, urlTemplate, remoteFilename, assetName, arch string) error {
+	url, error := downloader.getURL(urlTemplate, remoteFilename, arch)
 	if error != nil {
 		return error
 	}
 
+	filename := downloader.config.GetFullLocalArchAssetFilename(assetName, arch)
+
 	if !downloader.forceDownload && utils.FileExists(filename) {
 		utils.LogURL("Skipped downloading", url)
 		utils.LogFilename("Skipped installing", filename)
@@ -130,6 +168,14 @@ func (downloader Downloader) downloadExecutable(urlTemplate, remoteFilename, fil
 		return error
 	}
 
+	if error := downloader.verifyChecksum(artifactName, temporaryFilename); error != nil {
+		return error
+	}
+
+	if error := utils.CreateDirectoryIfMissing(path.Dir(filename)); error != nil {
+		return error
+	}
+
 	// Move target temporary file to target file
 	if error := os.Rename(temporaryFilename, filename); error != nil {
 		return error
@@ -204,7 +250,7 @@ func (downloader Downloader) extractTGZ(filename string, targetDirectory string)
 	return nil
 }
 
-func (downloader Downloader) downloadAndExtractTGZFiles(urlTemplate, baseName string, files []CompressedFile) error {
+func (downloader Downloader) downloadAndExtractTGZFiles(artifactName, urlTemplate, baseName string, files []CompressedFile, arch string) error {
 	// Check if files already exist
 	exist := true
 	temporaryDirectory := downloader.config.GetFullLocalAssetDirectory(utils.TEMPORARY_DIRECTORY)
@@ -223,12 +269,12 @@ func (downloader Downloader) downloadAndExtractTGZFiles(urlTemplate, baseName st
 	}
 
 	// Build base name including the version number
-	baseName, error := downloader.getURL(baseName, "")
+	baseName, error := downloader.getURL(baseName, "", arch)
 	if error != nil {
 		return error
 	}
 
-	url, error := downloader.getURL(urlTemplate, baseName)
+	url, error := downloader.getURL(urlTemplate, baseName, arch)
 	if error != nil {
 		return error
 	}
@@ -257,6 +303,10 @@ func (downloader Downloader) downloadAndExtractTGZFiles(urlTemplate, baseName st
 		_ = os.Remove(temporaryFile)
 	}()
 
+	if error := downloader.verifyChecksum(artifactName, temporaryFile); error != nil {
+		return error
+	}
+
 	// Create temporary directory to extract to
 	temporaryExtractedDirectory := path.Join(temporaryDirectory, baseName)
 
@@ -272,6 +322,10 @@ func (downloader Downloader) downloadAndExtractTGZFiles(urlTemplate, baseName st
 
 	// Move files from temporary directory to target directory
 	for _, compressedFile := range files {
+		if error := utils.CreateDirectoryIfMissing(path.Dir(compressedFile.TargetFile)); error != nil {
+			return error
+		}
+
 		if error := os.Rename(path.Join(temporaryExtractedDirectory, compressedFile.SourceFile), compressedFile.TargetFile); error != nil {
 			return error
 		}
@@ -324,48 +378,48 @@ func (downloader Downloader) copyK8STEW() error {
 	return targetFile.Sync()
 }
 
-func (downloader Downloader) downloadKubectl() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBECTL_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBECTL_BINARY))
+func (downloader Downloader) downloadKubectl(arch string) error {
+	return downloader.downloadExecutable("kubectl", utils.K8S_DOWNLOAD_URL, utils.KUBECTL_BINARY, utils.KUBECTL_BINARY, arch)
 }
 
-func (downloader Downloader) downloadKubeApiServer() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBE_APISERVER_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBE_APISERVER_BINARY))
+func (downloader Downloader) downloadKubeApiServer(arch string) error {
+	return downloader.downloadExecutable("kube-apiserver", utils.K8S_DOWNLOAD_URL, utils.KUBE_APISERVER_BINARY, utils.KUBE_APISERVER_BINARY, arch)
 }
 
-func (downloader Downloader) downloadKubeControllerManager() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBE_CONTROLLER_MANAGER_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBE_CONTROLLER_MANAGER_BINARY))
+func (downloader Downloader) downloadKubeControllerManager(arch string) error {
+	return downloader.downloadExecutable("kube-controller-manager", utils.K8S_DOWNLOAD_URL, utils.KUBE_CONTROLLER_MANAGER_BINARY, utils.KUBE_CONTROLLER_MANAGER_BINARY, arch)
 }
 
-func (downloader Downloader) downloadKubeScheduler() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBE_SCHEDULER_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBE_SCHEDULER_BINARY))
+func (downloader Downloader) downloadKubeScheduler(arch string) error {
+	return downloader.downloadExecutable("kube-scheduler", utils.K8S_DOWNLOAD_URL, utils.KUBE_SCHEDULER_BINARY, utils.KUBE_SCHEDULER_BINARY, arch)
 }
 
-func (downloader Downloader) downloadKubeProxy() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBE_PROXY_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBE_PROXY_BINARY))
+func (downloader Downloader) downloadKubeProxy(arch string) error {
+	return downloader.downloadExecutable("kube-proxy", utils.K8S_DOWNLOAD_URL, utils.KUBE_PROXY_BINARY, utils.KUBE_PROXY_BINARY, arch)
 }
 
-func (downloader Downloader) downloadKubelet() error {
-	return downloader.downloadExecutable(utils.K8S_DOWNLOAD_URL, utils.KUBELET_BINARY, downloader.config.GetFullLocalAssetFilename(utils.KUBELET_BINARY))
+func (downloader Downloader) downloadKubelet(arch string) error {
+	return downloader.downloadExecutable("kubelet", utils.K8S_DOWNLOAD_URL, utils.KUBELET_BINARY, utils.KUBELET_BINARY, arch)
 }
 
-func (downloader Downloader) downloadHelmBinary() error {
+func (downloader Downloader) downloadHelmBinary(arch string) error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
-			SourceFile: path.Join("linux-amd64", utils.HELM_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.HELM_BINARY),
+			SourceFile: path.Join(fmt.Sprintf("linux-%s", arch), utils.HELM_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.HELM_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.HELM_DOWNLOAD_URL, utils.HELM_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("helm", utils.HELM_DOWNLOAD_URL, utils.HELM_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadRuncBinary() error {
-	return downloader.downloadExecutable(utils.RUNC_DOWNLOAD_URL, "", downloader.config.GetFullLocalAssetFilename(utils.RUNC_BINARY))
+func (downloader Downloader) downloadRuncBinary(arch string) error {
+	return downloader.downloadExecutable("runc", utils.RUNC_DOWNLOAD_URL, "", utils.RUNC_BINARY, arch)
 }
 
-func (downloader Downloader) downloadEtcdBinaries() error {
+func (downloader Downloader) downloadEtcdBinaries(arch string) error {
 	// Build base name including the version number
-	baseName, error := downloader.getURL(utils.ETCD_BASE_NAME, "")
+	baseName, error := downloader.getURL(utils.ETCD_BASE_NAME, "", arch)
 	if error != nil {
 		return error
 	}
@@ -373,100 +427,100 @@ func (downloader Downloader) downloadEtcdBinaries() error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
 			SourceFile: path.Join(baseName, utils.ETCD_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.ETCD_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.ETCD_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(baseName, utils.ETCDCTL_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.ETCDCTL_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.ETCDCTL_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.ETCD_DOWNLOAD_URL, utils.ETCD_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("etcd", utils.ETCD_DOWNLOAD_URL, utils.ETCD_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadKubernetesBinaries() error {
+func (downloader Downloader) downloadKubernetesBinaries(arch string) error {
 	kubernetesServerBin := path.Join("kubernetes", "server", "bin")
 
 	compressedFiles := []CompressedFile{
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBE_APISERVER_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBE_APISERVER_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBE_APISERVER_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBE_CONTROLLER_MANAGER_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBE_CONTROLLER_MANAGER_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBE_CONTROLLER_MANAGER_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBE_SCHEDULER_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBE_SCHEDULER_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBE_SCHEDULER_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBE_PROXY_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBE_PROXY_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBE_PROXY_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBELET_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBELET_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBELET_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join(kubernetesServerBin, utils.KUBECTL_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.KUBECTL_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.KUBECTL_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.K8S_DOWNLOAD_URL, utils.K8S_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("kubernetes", utils.K8S_DOWNLOAD_URL, utils.K8S_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadContainerdBinaries() error {
+func (downloader Downloader) downloadContainerdBinaries(arch string) error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
 			SourceFile: path.Join("bin", utils.CONTAINERD_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.CONTAINERD_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.CONTAINERD_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join("bin", utils.CONTAINERD_SHIM_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.CONTAINERD_SHIM_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.CONTAINERD_SHIM_BINARY, arch),
 		},
 		CompressedFile{
 			SourceFile: path.Join("bin", utils.CTR_BINARY),
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.CTR_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.CTR_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.CONTAINERD_DOWNLOAD_URL, utils.CONTAINERD_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("containerd", utils.CONTAINERD_DOWNLOAD_URL, utils.CONTAINERD_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadCriCtlBinary() error {
+func (downloader Downloader) downloadCriCtlBinary(arch string) error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
 			SourceFile: utils.CRICTL_BINARY,
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.CRICTL_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.CRICTL_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.CRICTL_DOWNLOAD_URL, utils.CRICTL_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("crictl", utils.CRICTL_DOWNLOAD_URL, utils.CRICTL_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadGobetweenBinary() error {
+func (downloader Downloader) downloadGobetweenBinary(arch string) error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
 			SourceFile: utils.GOBETWEEN_BINARY,
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.GOBETWEEN_BINARY),
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.GOBETWEEN_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.GOBETWEEN_DOWNLOAD_URL, utils.GOBETWEEN_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("gobetween", utils.GOBETWEEN_DOWNLOAD_URL, utils.GOBETWEEN_BASE_NAME, compressedFiles, arch)
 }
 
-func (downloader Downloader) downloadArkBinaries() error {
+func (downloader Downloader) downloadVeleroBinaries(arch string) error {
 	compressedFiles := []CompressedFile{
 		CompressedFile{
-			SourceFile: utils.ARK_BINARY,
-			TargetFile: downloader.config.GetFullLocalAssetFilename(utils.ARK_BINARY),
+			SourceFile: utils.VELERO_BINARY,
+			TargetFile: downloader.config.GetFullLocalArchAssetFilename(utils.VELERO_BINARY, arch),
 		},
 	}
 
-	return downloader.downloadAndExtractTGZFiles(utils.ARK_DOWNLOAD_URL, utils.ARK_BASE_NAME, compressedFiles)
+	return downloader.downloadAndExtractTGZFiles("velero", utils.VELERO_DOWNLOAD_URL, utils.VELERO_BASE_NAME, compressedFiles, arch)
 }
 
 func (downloader Downloader) createLocalDirectories() error {
@@ -493,9 +547,30 @@ func (downloader Downloader) DownloadBinaries() error {
 		return error
 	}
 
-	errors := utils.RunParallelTasks(downloader.downloaderSteps, downloader.parallel)
-	if len(errors) > 0 {
-		return errors[0]
+	if error := downloader.copyK8STEW(); error != nil {
+		return error
+	}
+
+	utils.IncreaseProgressStep()
+
+	// Downloads run one architecture at a time, so a mixed amd64/arm64 cluster ends up with a full, independent
+	// set of binaries cached per arch instead of the tasks racing over the same temporary files
+	for _, arch := range downloader.config.GetArchs() {
+		tasks := utils.Tasks{}
+
+		for _, downloadTask := range downloader.archDownloadTasks {
+			downloadTask := downloadTask
+
+			tasks = append(tasks, func() error {
+				defer utils.IncreaseProgressStep()
+
+				return downloadTask(arch)
+			})
+		}
+
+		if errors := utils.RunParallelTasks(tasks, downloader.parallel, 0); len(errors) > 0 {
+			return errors
+		}
 	}
 
 	return nil
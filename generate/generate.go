@@ -1,13 +1,18 @@
 package generate
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
 
 	"github.com/darxkies/k8s-tew/config"
 
 	"github.com/darxkies/k8s-tew/pki"
 	"github.com/darxkies/k8s-tew/utils"
 	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 type Generator struct {
@@ -28,6 +33,10 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateGobetweenConfig,
 		// Generate calico setup
 		generator.generateCalicoSetup,
+		// Generate cilium setup
+		generator.generateCiliumSetup,
+		// Generate weave setup
+		generator.generateWeaveSetup,
 		// Generate scheduler config
 		generator.generateKubeSchedulerConfig,
 		// Generate kubelet config
@@ -40,8 +49,14 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateK8SHelmUserConfigFile,
 		// Generate containerd config
 		generator.generateContainerdConfig,
+		// Generate registry credentials secrets
+		generator.generateRegistryCredentialsSetup,
 		// Generate kubernetes security file
 		generator.generateEncryptionFile,
+		// Generate audit policy file
+		generator.generateAuditPolicy,
+		// Generate tracing configuration file
+		generator.generateTracingConfig,
 		// Generate kubeconfig files
 		generator.generateCertificates,
 		// Generate kubeconfig files
@@ -54,6 +69,10 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateCephCSI,
 		// Generate ceph files
 		generator.generateCephFiles,
+		// Generate NFS setup
+		generator.generateNFSSetup,
+		// Generate local-path-provisioner setup
+		generator.generateLocalPathProvisionerSetup,
 		// Generate Let's Encrypt Cluster Issuer
 		generator.generateLetsEncryptClusterIssuer,
 		// Generate CoreDNS setup file
@@ -62,8 +81,10 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateElasticSearchOperatorSetup,
 		// Generate ElasticSearch/Fluent-Bit/Kibana setup file
 		generator.generateEFKSetup,
-		// Generate ark setup file
-		generator.generateARKSetup,
+		// Generate Loki/Promtail setup file
+		generator.generateLokiSetup,
+		// Generate velero setup file
+		generator.generateVeleroSetup,
 		// Generate heapster setup file
 		generator.generateHeapsterSetup,
 		// Generate kubernetes dashboard setup file
@@ -72,6 +93,10 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateCertManagerSetup,
 		// Generate nginx ingress setup file
 		generator.generateNginxIngressSetup,
+		// Generate metallb setup file
+		generator.generateMetalLBSetup,
+		// Generate traefik ingress setup file
+		generator.generateTraefikSetup,
 		// Generate metrics server setup file
 		generator.generateMetricsServerSetup,
 		// Generate prometheus operator setup file
@@ -106,10 +131,14 @@ func NewGenerator(config *config.InternalConfig) *Generator {
 		generator.generateBashCompletionKubectl,
 		// Generate Bash Completion for Helm
 		generator.generateBashCompletionHelm,
-		// Generate Bash Completion for Ark
-		generator.generateBashCompletionArk,
+		// Generate Bash Completion for Velero
+		generator.generateBashCompletionVelero,
 		// Generate Bash Completion for CriCtl
 		generator.generateBashCompletionCriCtl,
+		// Deploy additional trusted CA certificates
+		generator.generateAdditionalTrustedCAs,
+		// Deploy the OIDC provider's CA certificate
+		generator.generateOIDCCA,
 	}
 
 	return generator
@@ -154,25 +183,107 @@ func (generator *Generator) generateGobetweenConfig() error {
 }
 
 func (generator *Generator) generateCalicoSetup() error {
+	if generator.config.Config.CNI != utils.CNI_CALICO {
+		return nil
+	}
+
+	ipv4PoolCIDR := generator.config.ClusterCIDRByFamily("IPv4")
+	ipv6PoolCIDR := generator.config.ClusterCIDRByFamily("IPv6")
+
 	return utils.ApplyTemplateAndSave("calico-setup", utils.TEMPLATE_CALICO_SETUP, struct {
 		CalicoTyphaIP        string
-		ClusterCIDR          string
+		IPv4PoolCIDR         string
+		IPv6PoolCIDR         string
+		IPv6Enabled          bool
 		CNIConfigDirectory   string
 		CNIBinariesDirectory string
 		CalicoTyphaImage     string
 		CalicoNodeImage      string
 		CalicoCNIImage       string
+		NamespaceLabels      map[string]string
 	}{
 		CalicoTyphaIP:        generator.config.Config.CalicoTyphaIP,
-		ClusterCIDR:          generator.config.Config.ClusterCIDR,
+		IPv4PoolCIDR:         ipv4PoolCIDR,
+		IPv6PoolCIDR:         ipv6PoolCIDR,
+		IPv6Enabled:          len(ipv6PoolCIDR) > 0,
 		CNIConfigDirectory:   generator.config.GetFullTargetAssetDirectory(utils.CNI_CONFIG_DIRECTORY),
 		CNIBinariesDirectory: generator.config.GetFullTargetAssetDirectory(utils.CNI_BINARIES_DIRECTORY),
 		CalicoTyphaImage:     generator.config.Config.Versions.CalicoTypha,
 		CalicoNodeImage:      generator.config.Config.Versions.CalicoNode,
 		CalicoCNIImage:       generator.config.Config.Versions.CalicoCNI,
+		NamespaceLabels:      generator.config.GetPodSecurityLabels("networking"),
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_CALICO_SETUP), true, false)
 }
 
+func (generator *Generator) generateCiliumSetup() error {
+	if generator.config.Config.CNI != utils.CNI_CILIUM {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("cilium-setup", utils.TEMPLATE_CILIUM_SETUP, struct {
+		ClusterCIDR          string
+		CNIConfigDirectory   string
+		CNIBinariesDirectory string
+		CiliumImage          string
+		NamespaceLabels      map[string]string
+	}{
+		ClusterCIDR:          generator.config.Config.ClusterCIDR,
+		CNIConfigDirectory:   generator.config.GetFullTargetAssetDirectory(utils.CNI_CONFIG_DIRECTORY),
+		CNIBinariesDirectory: generator.config.GetFullTargetAssetDirectory(utils.CNI_BINARIES_DIRECTORY),
+		CiliumImage:          generator.config.Config.Versions.Cilium,
+		NamespaceLabels:      generator.config.GetPodSecurityLabels("networking"),
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_CILIUM_SETUP), true, false)
+}
+
+// ensureWeaveEncryptionPassword returns the configured Weave Net encryption password, generating and persisting
+// a random one the first time the weave setup is generated if none was set via weave-encryption-password
+func (generator *Generator) ensureWeaveEncryptionPassword() (string, error) {
+	if len(generator.config.Config.WeaveEncryptionPassword) > 0 {
+		return generator.config.Config.WeaveEncryptionPassword, nil
+	}
+
+	password := utils.GenerateRandomPassword()
+
+	generator.config.Config.WeaveEncryptionPassword = password
+
+	if error := generator.config.Save(); error != nil {
+		return "", error
+	}
+
+	log.Warn("Generated Weave Net encryption password")
+
+	return password, nil
+}
+
+func (generator *Generator) generateWeaveSetup() error {
+	if generator.config.Config.CNI != utils.CNI_WEAVE {
+		return nil
+	}
+
+	weaveEncryptionPassword, error := generator.ensureWeaveEncryptionPassword()
+	if error != nil {
+		return error
+	}
+
+	return utils.ApplyTemplateAndSave("weave-setup", utils.TEMPLATE_WEAVE_SETUP, struct {
+		ClusterCIDR                   string
+		CNIConfigDirectory            string
+		CNIBinariesDirectory          string
+		WeaveNetImage                 string
+		WeaveNPCImage                 string
+		WeaveEncryptionPasswordBase64 string
+		NamespaceLabels               map[string]string
+	}{
+		ClusterCIDR:                   generator.config.Config.ClusterCIDR,
+		CNIConfigDirectory:            generator.config.GetFullTargetAssetDirectory(utils.CNI_CONFIG_DIRECTORY),
+		CNIBinariesDirectory:          generator.config.GetFullTargetAssetDirectory(utils.CNI_BINARIES_DIRECTORY),
+		WeaveNetImage:                 generator.config.Config.Versions.WeaveNet,
+		WeaveNPCImage:                 generator.config.Config.Versions.WeaveNPC,
+		WeaveEncryptionPasswordBase64: base64.StdEncoding.EncodeToString([]byte(weaveEncryptionPassword)),
+		NamespaceLabels:               generator.config.GetPodSecurityLabels("networking"),
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_WEAVE_SETUP), true, false)
+}
+
 func (generator *Generator) generateK8SKubeletConfigFile() error {
 	return utils.ApplyTemplateAndSave("kubelet-config", utils.TEMPLATE_KUBELET_SETUP, nil, generator.config.GetFullLocalAssetFilename(utils.K8S_KUBELET_SETUP), true, false)
 }
@@ -197,28 +308,257 @@ func (generator *Generator) generateK8SHelmUserConfigFile() error {
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_HELM_USER_SETUP), true, false)
 }
 
+// encryptionProviderKey is one named secret entry of an aescbc/aesgcm/secretbox provider
+type encryptionProviderKey struct {
+	Name   string `yaml:"name"`
+	Secret string `yaml:"secret"`
+}
+
+// encryptionKMSProvider configures the apiserver to envelope-encrypt secrets through an external KMS plugin
+type encryptionKMSProvider struct {
+	Name      string `yaml:"name"`
+	Endpoint  string `yaml:"endpoint"`
+	CacheSize int    `yaml:"cachesize"`
+	Timeout   string `yaml:"timeout"`
+}
+
+// encryptionProvider is a single entry of resources[].providers - exactly one of its fields is set, mirroring the
+// "one of aescbc/aesgcm/secretbox/kms/identity" shape of Kubernetes' EncryptionConfiguration
+type encryptionProvider struct {
+	AESCBC *struct {
+		Keys []encryptionProviderKey `yaml:"keys"`
+	} `yaml:"aescbc,omitempty"`
+	AESGCM *struct {
+		Keys []encryptionProviderKey `yaml:"keys"`
+	} `yaml:"aesgcm,omitempty"`
+	Secretbox *struct {
+		Keys []encryptionProviderKey `yaml:"keys"`
+	} `yaml:"secretbox,omitempty"`
+	KMS      *encryptionKMSProvider `yaml:"kms,omitempty"`
+	Identity *struct{}              `yaml:"identity,omitempty"`
+}
+
+// providerName returns the non-empty provider keyword of provider (aescbc, aesgcm, secretbox, kms or identity), or
+// "" if provider is the zero value
+func (provider encryptionProvider) providerName() string {
+	switch {
+	case provider.AESCBC != nil:
+		return utils.ENCRYPTION_PROVIDER_AESCBC
+
+	case provider.AESGCM != nil:
+		return utils.ENCRYPTION_PROVIDER_AESGCM
+
+	case provider.Secretbox != nil:
+		return utils.ENCRYPTION_PROVIDER_SECRETBOX
+
+	case provider.KMS != nil:
+		return "kms"
+
+	case provider.Identity != nil:
+		return "identity"
+	}
+
+	return ""
+}
+
+// keys returns the key entries of provider, if it is one of aescbc/aesgcm/secretbox, otherwise nil
+func (provider encryptionProvider) keys() []encryptionProviderKey {
+	switch {
+	case provider.AESCBC != nil:
+		return provider.AESCBC.Keys
+
+	case provider.AESGCM != nil:
+		return provider.AESGCM.Keys
+
+	case provider.Secretbox != nil:
+		return provider.Secretbox.Keys
+	}
+
+	return nil
+}
+
+// newEncryptionProvider builds the provider named name with a single key containing secret
+func newEncryptionProvider(name, secret string) encryptionProvider {
+	keys := []encryptionProviderKey{{Name: "key1", Secret: secret}}
+
+	switch name {
+	case utils.ENCRYPTION_PROVIDER_AESGCM:
+		return encryptionProvider{AESGCM: &struct {
+			Keys []encryptionProviderKey `yaml:"keys"`
+		}{Keys: keys}}
+
+	case utils.ENCRYPTION_PROVIDER_SECRETBOX:
+		return encryptionProvider{Secretbox: &struct {
+			Keys []encryptionProviderKey `yaml:"keys"`
+		}{Keys: keys}}
+
+	default:
+		return encryptionProvider{AESCBC: &struct {
+			Keys []encryptionProviderKey `yaml:"keys"`
+		}{Keys: keys}}
+	}
+}
+
+// encryptionConfig mirrors the resources[0] entry of Kubernetes' EncryptionConfiguration
+type encryptionConfig struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Resources  []struct {
+		Resources []string             `yaml:"resources"`
+		Providers []encryptionProvider `yaml:"providers"`
+	} `yaml:"resources"`
+}
+
+// loadEncryptionProviders reads the providers list of an already generated encryption-config.yaml, or returns an
+// empty list if filename does not exist or cannot be parsed
+func loadEncryptionProviders(filename string) []encryptionProvider {
+	if !utils.FileExists(filename) {
+		return nil
+	}
+
+	content, error := ioutil.ReadFile(filename)
+	if error != nil {
+		return nil
+	}
+
+	existing := encryptionConfig{}
+
+	if error := yaml.Unmarshal(content, &existing); error != nil || len(existing.Resources) == 0 {
+		return nil
+	}
+
+	return existing.Resources[0].Providers
+}
+
+// generateEncryptionFile (re)writes encryption-config.yaml so that config.Config.EncryptionProvider is the first,
+// i.e. the one new secrets get encrypted with. Every provider that was already configured is carried forward right
+// after it, as a fallback the apiserver still tries while decrypting, so switching providers does not brick secrets
+// encrypted under the previous one. The key material of a provider that is kept around, including the active one
+// when it was already first, is reused as is rather than regenerated
 func (generator *Generator) generateEncryptionFile() error {
 	fullEncryptionConfigFilename := generator.config.GetFullLocalAssetFilename(utils.ENCRYPTION_CONFIG)
 
-	if utils.FileExists(fullEncryptionConfigFilename) {
-		utils.LogFilename("skipped", fullEncryptionConfigFilename)
+	existingProviders := loadEncryptionProviders(fullEncryptionConfigFilename)
 
-		return nil
+	activeProviderName := generator.config.Config.EncryptionProvider
+
+	var active *encryptionProvider
+	fallbacks := []encryptionProvider{}
+
+	for _, provider := range existingProviders {
+		name := provider.providerName()
+
+		if name == "" || name == "identity" || name == "kms" {
+			continue
+		}
+
+		if name == activeProviderName && active == nil {
+			provider := provider
+			active = &provider
+
+			continue
+		}
+
+		fallbacks = append(fallbacks, provider)
 	}
 
-	encryptionKey, error := pki.GenerateEncryptionConfig()
+	if active == nil {
+		encryptionKey, error := pki.GenerateEncryptionConfig()
+		if error != nil {
+			return error
+		}
+
+		provider := newEncryptionProvider(activeProviderName, encryptionKey)
+		active = &provider
+	}
+
+	providers := []encryptionProvider{}
+
+	if len(generator.config.Config.EncryptionKMSEndpoint) > 0 {
+		providers = append(providers, encryptionProvider{KMS: &encryptionKMSProvider{Name: "kms-plugin", Endpoint: generator.config.Config.EncryptionKMSEndpoint, CacheSize: 1000, Timeout: "3s"}})
+	}
+
+	providers = append(providers, *active)
+	providers = append(providers, fallbacks...)
+	providers = append(providers, encryptionProvider{Identity: &struct{}{}})
+
+	config := encryptionConfig{APIVersion: "v1", Kind: "EncryptionConfig"}
+	config.Resources = []struct {
+		Resources []string             `yaml:"resources"`
+		Providers []encryptionProvider `yaml:"providers"`
+	}{{Resources: []string{"secrets"}, Providers: providers}}
+
+	content, error := yaml.Marshal(config)
 	if error != nil {
-		return error
+		return fmt.Errorf("Could not marshal '%s' (%s)", fullEncryptionConfigFilename, error.Error())
+	}
+
+	if utils.IsDryRun() {
+		return utils.RecordPlannedContentChange(fullEncryptionConfigFilename, string(content))
+	}
+
+	if error := ioutil.WriteFile(fullEncryptionConfigFilename, content, 0644); error != nil {
+		return fmt.Errorf("Could not write to '%s' (%s)", fullEncryptionConfigFilename, error.Error())
 	}
 
-	return utils.ApplyTemplateAndSave("encryption-config", utils.TEMPLATE_ENCRYPTION_CONFIG, struct {
-		EncryptionKey string
+	utils.LogFilename("Generated", fullEncryptionConfigFilename)
+
+	return nil
+}
+
+func (generator *Generator) generateAuditPolicy() error {
+	return utils.ApplyTemplateAndSave("audit-policy", utils.TEMPLATE_AUDIT_POLICY, struct {
+		Policy string
 	}{
-		EncryptionKey: encryptionKey,
-	}, fullEncryptionConfigFilename, false, false)
+		Policy: generator.config.Config.Audit.Policy,
+	}, generator.config.GetFullLocalAssetFilename(utils.AUDIT_POLICY), true, false)
+}
+
+func (generator *Generator) generateTracingConfig() error {
+	if !generator.config.Config.Tracing.Enabled {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("tracing-config", utils.TEMPLATE_TRACING_CONFIG, struct {
+		OTLPEndpoint string
+	}{
+		OTLPEndpoint: generator.config.Config.Tracing.OTLPEndpoint,
+	}, generator.config.GetFullLocalAssetFilename(utils.TRACING_CONFIG), true, false)
+}
+
+// containerdRegistryAuth carries one registry-auths entry's resolved credentials into the containerd.toml template,
+// since the template itself cannot read environment variables or the config's *Env fields
+type containerdRegistryAuth struct {
+	Registry string
+	Username string
+	Password string
+}
+
+// resolveContainerdRegistryAuths resolves every configured registry-auths entry's credentials once, so a mistake
+// such as a missing environment variable is caught as a single generate error instead of per node. Registry,
+// Username and Password are pre-quoted with strconv.Quote, the same escaping used for the JSON secret in
+// dockerConfigJSON, since the template interpolates them straight into TOML double-quoted string literals
+func resolveContainerdRegistryAuths(auths []config.RegistryAuth) ([]containerdRegistryAuth, error) {
+	resolved := make([]containerdRegistryAuth, len(auths))
+
+	for index, auth := range auths {
+		username, secret, error := auth.Credentials()
+		if error != nil {
+			return nil, error
+		}
+
+		resolved[index] = containerdRegistryAuth{Registry: strconv.Quote(auth.Registry), Username: strconv.Quote(username), Password: strconv.Quote(secret)}
+	}
+
+	return resolved, nil
 }
 
 func (generator *Generator) generateContainerdConfig() error {
+	registryAuths, error := resolveContainerdRegistryAuths(generator.config.Config.RegistryAuths)
+	if error != nil {
+		return error
+	}
+
 	for nodeName, node := range generator.config.Config.Nodes {
 		generator.config.SetNode(nodeName, node)
 
@@ -231,6 +571,9 @@ func (generator *Generator) generateContainerdConfig() error {
 			CRIBinariesDirectory     string
 			IP                       string
 			PauseImage               string
+			SystemdCgroup            bool
+			RuntimeHandlers          []config.ContainerdRuntimeHandler
+			RegistryAuths            []containerdRegistryAuth
 		}{
 			ContainerdRootDirectory:  generator.config.GetFullTargetAssetDirectory(utils.CONTAINERD_DATA_DIRECTORY),
 			ContainerdStateDirectory: generator.config.GetFullTargetAssetDirectory(utils.CONTAINERD_STATE_DIRECTORY),
@@ -240,6 +583,9 @@ func (generator *Generator) generateContainerdConfig() error {
 			CRIBinariesDirectory:     generator.config.GetFullTargetAssetDirectory(utils.CRI_BINARIES_DIRECTORY),
 			IP:                       node.IP,
 			PauseImage:               generator.config.Config.Versions.Pause,
+			SystemdCgroup:            generator.config.Config.ContainerdSystemdCgroup,
+			RuntimeHandlers:          generator.config.Config.ContainerdRuntimeHandlers,
+			RegistryAuths:            registryAuths,
 		}, generator.config.GetFullLocalAssetFilename(utils.CONTAINERD_CONFIG), true, false); error != nil {
 			return error
 		}
@@ -248,11 +594,52 @@ func (generator *Generator) generateContainerdConfig() error {
 	return nil
 }
 
+// registryCredentialsSecret carries one rendered imagePullSecret into the registry-credentials-setup.yaml template
+type registryCredentialsSecret struct {
+	Name             string
+	DockerConfigJSON string
+}
+
+// dockerConfigJSON builds the minimal ~/.docker/config.json content a kubernetes.io/dockerconfigjson Secret expects
+func dockerConfigJSON(registry, username, secret string) string {
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, secret)))
+
+	return fmt.Sprintf(`{"auths":{%s:{"username":%s,"password":%s,"auth":%s}}}`, strconv.Quote(registry), strconv.Quote(username), strconv.Quote(secret), strconv.Quote(auth))
+}
+
+// generateRegistryCredentialsSetup renders one imagePullSecret per registry-auths entry. Since a Secret is
+// namespaced, the rendered manifest leaves metadata.namespace unset on purpose - registry-credentials-setup,
+// together with the default service account patch, applies it to every namespace instead of a fixed one
+func (generator *Generator) generateRegistryCredentialsSetup() error {
+	if len(generator.config.Config.RegistryAuths) == 0 {
+		return nil
+	}
+
+	secrets := make([]registryCredentialsSecret, len(generator.config.Config.RegistryAuths))
+
+	for index, auth := range generator.config.Config.RegistryAuths {
+		username, secret, error := auth.Credentials()
+		if error != nil {
+			return error
+		}
+
+		secrets[index] = registryCredentialsSecret{Name: auth.SecretName(), DockerConfigJSON: dockerConfigJSON(auth.Registry, username, secret)}
+	}
+
+	return utils.ApplyTemplateAndSave("registry-credentials-setup", utils.TEMPLATE_REGISTRY_CREDENTIALS_SETUP, struct {
+		Secrets []registryCredentialsSecret
+	}{
+		Secrets: secrets,
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_REGISTRY_CREDENTIALS_SETUP), true, false)
+}
+
 func (generator *Generator) generateKubeSchedulerConfig() error {
 	return utils.ApplyTemplateAndSave("kube-scheduler-config", utils.TEMPLATE_KUBE_SCHEDULER_CONFIGURATION, struct {
 		KubeConfig string
+		Profiles   []config.SchedulerProfile
 	}{
 		KubeConfig: generator.config.GetFullTargetAssetFilename(utils.SCHEDULER_KUBECONFIG),
+		Profiles:   generator.config.Config.SchedulerProfiles,
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_KUBE_SCHEDULER_CONFIG), true, false)
 }
 
@@ -260,23 +647,27 @@ func (generator *Generator) generateKubeletConfig() error {
 	for nodeName, node := range generator.config.Config.Nodes {
 		generator.config.SetNode(nodeName, node)
 
-		if error := utils.ApplyTemplateAndSave("kubelet-configuration", utils.TEMPLATE_KUBELET_CONFIGURATION, struct {
-			CA                  string
-			CertificateFilename string
-			KeyFilename         string
-			ClusterDomain       string
-			ClusterDNSIP        string
-			PODCIDR             string
-			StaticPodPath       string
+		if error := utils.ApplyTemplateAndSaveMerged("kubelet-configuration", utils.TEMPLATE_KUBELET_CONFIGURATION, struct {
+			CA                          string
+			CertificateFilename         string
+			KeyFilename                 string
+			ClusterDomain               string
+			ClusterDNSIP                string
+			PODCIDR                     string
+			StaticPodPath               string
+			ImageGCHighThresholdPercent uint8
+			ImageGCLowThresholdPercent  uint8
 		}{
-			CA:                  generator.config.GetFullTargetAssetFilename(utils.CA_PEM),
-			CertificateFilename: generator.config.GetFullTargetAssetFilename(utils.KUBELET_PEM),
-			KeyFilename:         generator.config.GetFullTargetAssetFilename(utils.KUBELET_KEY_PEM),
-			ClusterDomain:       generator.config.Config.ClusterDomain,
-			ClusterDNSIP:        generator.config.Config.ClusterDNSIP,
-			PODCIDR:             generator.config.Config.ClusterCIDR,
-			StaticPodPath:       generator.config.GetFullTargetAssetDirectory(utils.K8S_MANIFESTS_DIRECTORY),
-		}, generator.config.GetFullLocalAssetFilename(utils.K8S_KUBELET_CONFIG), true, false); error != nil {
+			CA:                          generator.config.GetFullTargetAssetFilename(utils.CA_PEM),
+			CertificateFilename:         generator.config.GetFullTargetAssetFilename(utils.KUBELET_PEM),
+			KeyFilename:                 generator.config.GetFullTargetAssetFilename(utils.KUBELET_KEY_PEM),
+			ClusterDomain:               generator.config.Config.ClusterDomain,
+			ClusterDNSIP:                generator.config.Config.ClusterDNSIP,
+			PODCIDR:                     generator.config.Config.ClusterCIDR,
+			StaticPodPath:               generator.config.GetFullTargetAssetDirectory(utils.K8S_MANIFESTS_DIRECTORY),
+			ImageGCHighThresholdPercent: generator.config.Config.ImageGCHighThresholdPercent,
+			ImageGCLowThresholdPercent:  generator.config.Config.ImageGCLowThresholdPercent,
+		}, generator.config.GetFullLocalAssetFilename(utils.K8S_KUBELET_CONFIG), true, false, node.KubeletConfig); error != nil {
 			return error
 		}
 	}
@@ -284,29 +675,42 @@ func (generator *Generator) generateKubeletConfig() error {
 	return nil
 }
 
-func (generator *Generator) generateCertificates() error {
-	var error error
+// keyAlgorithm builds the pki.KeyAlgorithm used to generate the CA and every leaf certificate, based on the
+// configured certificate algorithm
+func (generator *Generator) keyAlgorithm() pki.KeyAlgorithm {
+	return pki.KeyAlgorithm{Algorithm: generator.config.Config.CertificateAlgorithm, RSASize: generator.config.Config.RSASize, ECDSACurve: generator.config.Config.CertificateECDSACurve}
+}
 
+func (generator *Generator) generateCertificates() error {
 	fullCAFilename := generator.config.GetFullLocalAssetFilename(utils.CA_PEM)
 	fullCAKeyFilename := generator.config.GetFullLocalAssetFilename(utils.CA_KEY_PEM)
 
 	// Generate CA if not done already
-	if error := pki.GenerateCA(generator.config.Config.RSASize, generator.config.Config.CAValidityPeriod, "Kubernetes", "Kubernetes", fullCAFilename, fullCAKeyFilename); error != nil {
+	if error := pki.GenerateCA(generator.keyAlgorithm(), generator.config.Config.CAValidityPeriod, "Kubernetes", "Kubernetes", fullCAFilename, fullCAKeyFilename); error != nil {
 		return error
 	}
 
 	// Load ca certificate and private key
-	generator.ca, error = pki.LoadCertificateAndPrivateKey(fullCAFilename, fullCAKeyFilename)
+	ca, error := pki.LoadCertificateAndPrivateKey(fullCAFilename, fullCAKeyFilename)
 	if error != nil {
 		return error
 	}
 
+	generator.ca = ca
+
+	return generator.generateLeafCertificates(false)
+}
+
+// generateLeafCertificates (re-)issues every leaf certificate (admin, kubernetes, aggregator, service-accounts,
+// controller-manager, scheduler, proxy, kubelet-*) against generator.ca. force is passed through to
+// pki.GenerateClient, so a certificate that already exists on disk is only regenerated when force is true
+func (generator *Generator) generateLeafCertificates(force bool) error {
 	// Collect dns names and ip addresses
 	kubernetesDNSNames := []string{"kubernetes", "kubenetes.default", "kubenetes.default.svc", "kubenetes.default.svc.cluster.local", "localhost"}
 	kubernetesIPAddresses := []string{"127.0.0.1", "10.32.0.1"}
 
-	if len(generator.config.Config.ControllerVirtualIP) > 0 {
-		kubernetesIPAddresses = append(kubernetesIPAddresses, generator.config.Config.ControllerVirtualIP)
+	for _, virtualIP := range generator.config.Config.ControllerVirtualIPs {
+		kubernetesIPAddresses = append(kubernetesIPAddresses, virtualIP.IP)
 	}
 
 	for nodeName, node := range generator.config.Config.Nodes {
@@ -314,45 +718,48 @@ func (generator *Generator) generateCertificates() error {
 		kubernetesIPAddresses = append(kubernetesIPAddresses, node.IP)
 	}
 
+	kubernetesDNSNames = append(kubernetesDNSNames, generator.config.Config.APIServerExtraDNSNames...)
+	kubernetesIPAddresses = append(kubernetesIPAddresses, generator.config.Config.APIServerExtraIPs...)
+
 	// Generate admin certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, utils.CN_ADMIN, "system:masters", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.ADMIN_PEM), generator.config.GetFullLocalAssetFilename(utils.ADMIN_KEY_PEM), false); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, utils.CN_ADMIN, "system:masters", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.ADMIN_PEM), generator.config.GetFullLocalAssetFilename(utils.ADMIN_KEY_PEM), force); error != nil {
 		return error
 	}
 
 	// Generate kuberentes certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, "kubernetes", "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.KUBERNETES_PEM), generator.config.GetFullLocalAssetFilename(utils.KUBERNETES_KEY_PEM), true); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, "kubernetes", "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.KUBERNETES_PEM), generator.config.GetFullLocalAssetFilename(utils.KUBERNETES_KEY_PEM), true); error != nil {
 		return error
 	}
 
 	// Generate aggregator certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, utils.CN_AGGREGATOR, "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.AGGREGATOR_PEM), generator.config.GetFullLocalAssetFilename(utils.AGGREGATOR_KEY_PEM), true); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, utils.CN_AGGREGATOR, "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.AGGREGATOR_PEM), generator.config.GetFullLocalAssetFilename(utils.AGGREGATOR_KEY_PEM), true); error != nil {
 		return error
 	}
 
 	// Generate service accounts certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, "service-accounts", "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.SERVICE_ACCOUNT_PEM), generator.config.GetFullLocalAssetFilename(utils.SERVICE_ACCOUNT_KEY_PEM), false); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, "service-accounts", "Kubernetes", kubernetesDNSNames, kubernetesIPAddresses, generator.config.GetFullLocalAssetFilename(utils.SERVICE_ACCOUNT_PEM), generator.config.GetFullLocalAssetFilename(utils.SERVICE_ACCOUNT_KEY_PEM), force); error != nil {
 		return error
 	}
 
 	// Generate controller manager certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_CONTROLLER_MANAGER, "system:node-controller-manager", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.CONTROLLER_MANAGER_PEM), generator.config.GetFullLocalAssetFilename(utils.CONTROLLER_MANAGER_KEY_PEM), false); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_CONTROLLER_MANAGER, "system:node-controller-manager", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.CONTROLLER_MANAGER_PEM), generator.config.GetFullLocalAssetFilename(utils.CONTROLLER_MANAGER_KEY_PEM), force); error != nil {
 		return error
 	}
 
 	// Generate scheduler certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_SCHEDULER, "system:kube-scheduler", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.SCHEDULER_PEM), generator.config.GetFullLocalAssetFilename(utils.SCHEDULER_KEY_PEM), false); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_SCHEDULER, "system:kube-scheduler", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.SCHEDULER_PEM), generator.config.GetFullLocalAssetFilename(utils.SCHEDULER_KEY_PEM), force); error != nil {
 		return error
 	}
 
 	// Generate proxy certificate
-	if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_PROXY, "system:node-proxier", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.PROXY_PEM), generator.config.GetFullLocalAssetFilename(utils.PROXY_KEY_PEM), false); error != nil {
+	if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, utils.CN_SYSTEM_KUBE_PROXY, "system:node-proxier", []string{}, []string{}, generator.config.GetFullLocalAssetFilename(utils.PROXY_PEM), generator.config.GetFullLocalAssetFilename(utils.PROXY_KEY_PEM), force); error != nil {
 		return error
 	}
 
 	for nodeName, node := range generator.config.Config.Nodes {
 		generator.config.SetNode(nodeName, node)
 
-		if error := pki.GenerateClient(generator.ca, generator.config.Config.RSASize, generator.config.Config.ClientValidityPeriod, fmt.Sprintf(utils.CN_SYSTEM_NODE_PREFIX, nodeName), "system:nodes", []string{nodeName}, []string{node.IP}, generator.config.GetFullLocalAssetFilename(utils.KUBELET_PEM), generator.config.GetFullLocalAssetFilename(utils.KUBELET_KEY_PEM), false); error != nil {
+		if error := pki.GenerateClient(generator.ca, generator.keyAlgorithm(), generator.config.Config.ClientValidityPeriod, fmt.Sprintf(utils.CN_SYSTEM_NODE_PREFIX, nodeName), "system:nodes", []string{nodeName}, []string{node.IP}, generator.config.GetFullLocalAssetFilename(utils.KUBELET_PEM), generator.config.GetFullLocalAssetFilename(utils.KUBELET_KEY_PEM), force); error != nil {
 			return error
 		}
 	}
@@ -360,6 +767,51 @@ func (generator *Generator) generateCertificates() error {
 	return nil
 }
 
+// RotateCertificates re-issues every leaf certificate against the existing CA, without touching the CA itself
+// unless rotateCA is set. With rotateCA, the CA is regenerated first, which in turn requires every leaf
+// certificate to be re-signed against it regardless of how recently it was issued
+func (generator *Generator) RotateCertificates(rotateCA bool) error {
+	fullCAFilename := generator.config.GetFullLocalAssetFilename(utils.CA_PEM)
+	fullCAKeyFilename := generator.config.GetFullLocalAssetFilename(utils.CA_KEY_PEM)
+
+	if rotateCA {
+		if error := os.Remove(fullCAFilename); error != nil && !os.IsNotExist(error) {
+			return error
+		}
+
+		if error := os.Remove(fullCAKeyFilename); error != nil && !os.IsNotExist(error) {
+			return error
+		}
+
+		if error := pki.GenerateCA(generator.keyAlgorithm(), generator.config.Config.CAValidityPeriod, "Kubernetes", "Kubernetes", fullCAFilename, fullCAKeyFilename); error != nil {
+			return error
+		}
+	}
+
+	ca, error := pki.LoadCertificateAndPrivateKey(fullCAFilename, fullCAKeyFilename)
+	if error != nil {
+		return error
+	}
+
+	generator.ca = ca
+
+	return generator.generateLeafCertificates(true)
+}
+
+// RegenerateKubeConfigs reloads the existing CA and rewrites every kubeconfig (admin, controller-manager,
+// scheduler, proxy, kubelet-*) against the current configuration, e.g. after the load balancer vip or the
+// apiserver port changed. Unlike RotateCertificates it does not touch the CA or any leaf certificate
+func (generator *Generator) RegenerateKubeConfigs() error {
+	ca, error := pki.LoadCertificateAndPrivateKey(generator.config.GetFullLocalAssetFilename(utils.CA_PEM), generator.config.GetFullLocalAssetFilename(utils.CA_KEY_PEM))
+	if error != nil {
+		return error
+	}
+
+	generator.ca = ca
+
+	return generator.generateKubeConfigs()
+}
+
 func (generator *Generator) generateConfigKubeConfig(kubeConfigFilename, caFilename, user, apiServers, certificateFilename, keyFilename string, force bool) error {
 	if utils.FileExists(kubeConfigFilename) && !force {
 		utils.LogFilename("skipped", kubeConfigFilename)
@@ -389,6 +841,7 @@ func (generator *Generator) generateConfigKubeConfig(kubeConfigFilename, caFilen
 		Name            string
 		User            string
 		APIServer       string
+		ClusterName     string
 		CAData          string
 		CertificateData string
 		KeyData         string
@@ -396,6 +849,7 @@ func (generator *Generator) generateConfigKubeConfig(kubeConfigFilename, caFilen
 		Name:            user,
 		User:            user,
 		APIServer:       apiServers,
+		ClusterName:     generator.config.Config.ClusterName,
 		CAData:          base64CA,
 		CertificateData: base64Certificate,
 		KeyData:         base64Key,
@@ -442,6 +896,10 @@ func (generator *Generator) generateKubeConfigs() error {
 }
 
 func (generator *Generator) generateCephConfig() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_CEPH {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("ceph-config", utils.TEMPLATE_CEPH_CONFIG, struct {
 		ClusterID          string
 		PublicNetwork      string
@@ -458,6 +916,10 @@ func (generator *Generator) generateCephConfig() error {
 }
 
 func (generator *Generator) generateCephSetup() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_CEPH {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("ceph-setup", utils.TEMPLATE_CEPH_SETUP, struct {
 		CephRBDPoolName      string
 		CephFSPoolName       string
@@ -470,6 +932,10 @@ func (generator *Generator) generateCephSetup() error {
 		CephImage            string
 		CephManagerPort      uint16
 		CephRadosGatewayPort uint16
+		StorageClass         config.StorageClassConfig
+		CephRBDPool          config.CephPoolConfig
+		CephFSDataPool       config.CephPoolConfig
+		CephFSMetadataPool   config.CephPoolConfig
 	}{
 		CephRBDPoolName:      utils.CEPH_RBD_POOL_NAME,
 		CephFSPoolName:       utils.CEPH_FS_POOL_NAME,
@@ -482,10 +948,18 @@ func (generator *Generator) generateCephSetup() error {
 		CephImage:            generator.config.Config.Versions.Ceph,
 		CephManagerPort:      utils.PORT_CEPH_MANAGER,
 		CephRadosGatewayPort: utils.PORT_CEPH_RADOS_GATEWAY,
+		StorageClass:         generator.config.GetStorageClass("ceph"),
+		CephRBDPool:          generator.config.GetCephPool("rbd"),
+		CephFSDataPool:       generator.config.GetCephPool("cephfs-data"),
+		CephFSMetadataPool:   generator.config.GetCephPool("cephfs-metadata"),
 	}, generator.config.GetFullLocalAssetFilename(utils.CEPH_SETUP), true, false)
 }
 
 func (generator *Generator) generateCephCSI() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_CEPH {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("ceph-csi", utils.TEMPLATE_CEPH_CSI, struct {
 		PodsDirectory           string
 		PluginsDirectory        string
@@ -499,6 +973,8 @@ func (generator *Generator) generateCephCSI() error {
 		CSIDriverRegistrarImage string
 		CSICephRBDPluginImage   string
 		CSICephFSPluginImage    string
+		RBDStorageClass         config.StorageClassConfig
+		CephFSStorageClass      config.StorageClassConfig
 	}{
 		PodsDirectory:           generator.config.GetFullTargetAssetDirectory(utils.PODS_DATA_DIRECTORY),
 		PluginsDirectory:        generator.config.GetFullTargetAssetDirectory(utils.KUBELET_PLUGINS_DIRECTORY),
@@ -512,10 +988,16 @@ func (generator *Generator) generateCephCSI() error {
 		CSIDriverRegistrarImage: generator.config.Config.Versions.CSIDriverRegistrar,
 		CSICephRBDPluginImage:   generator.config.Config.Versions.CSICephRBDPlugin,
 		CSICephFSPluginImage:    generator.config.Config.Versions.CSICephFSPlugin,
+		RBDStorageClass:         generator.config.GetStorageClass("csi-rbd"),
+		CephFSStorageClass:      generator.config.GetStorageClass("csi-cephfs"),
 	}, generator.config.GetFullLocalAssetFilename(utils.CEPH_CSI), true, false)
 }
 
 func (generator *Generator) generateCephFiles() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_CEPH {
+		return nil
+	}
+
 	if utils.FileExists(generator.config.GetFullLocalAssetFilename(utils.CEPH_MONITOR_KEYRING)) {
 		return nil
 	}
@@ -601,8 +1083,10 @@ func (generator *Generator) generateCephFiles() error {
 	if error := utils.ApplyTemplateAndSave("ceph-secrets", utils.TEMPLATE_CEPH_SECRETS, struct {
 		ClientAdminKey  string
 		ClientK8STEWKey string
+		NamespaceLabels map[string]string
 	}{
 		ClientAdminKey:  clientAdminKey,
+		NamespaceLabels: generator.config.GetPodSecurityLabels("storage"),
 		ClientK8STEWKey: clientK8STEWKey,
 	}, generator.config.GetFullLocalAssetFilename(utils.CEPH_SECRETS), false, false); error != nil {
 		return error
@@ -611,35 +1095,89 @@ func (generator *Generator) generateCephFiles() error {
 	return nil
 }
 
+func (generator *Generator) generateNFSSetup() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_NFS {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("nfs-setup", utils.TEMPLATE_NFS_SETUP, struct {
+		NFSServer                 string
+		NFSPath                   string
+		NFSSubdirProvisionerImage string
+		NamespaceLabels           map[string]string
+		StorageClass              config.StorageClassConfig
+	}{
+		NFSServer:                 generator.config.Config.NFSServer,
+		NFSPath:                   generator.config.Config.NFSPath,
+		NFSSubdirProvisionerImage: generator.config.Config.Versions.NFSSubdirProvisioner,
+		NamespaceLabels:           generator.config.GetPodSecurityLabels("storage"),
+		StorageClass:              generator.config.GetStorageClass("nfs"),
+	}, generator.config.GetFullLocalAssetFilename(utils.NFS_SETUP), true, false)
+}
+
+func (generator *Generator) generateLocalPathProvisionerSetup() error {
+	if generator.config.Config.StorageBackend != utils.STORAGE_BACKEND_LOCAL_PATH {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("local-path-setup", utils.TEMPLATE_LOCAL_PATH_SETUP, struct {
+		LocalPathProvisionerImage string
+		PodsDirectory             string
+		NamespaceLabels           map[string]string
+		StorageClass              config.StorageClassConfig
+	}{
+		LocalPathProvisionerImage: generator.config.Config.Versions.LocalPathProvisioner,
+		PodsDirectory:             generator.config.GetFullTargetAssetDirectory(utils.PODS_DATA_DIRECTORY),
+		NamespaceLabels:           generator.config.GetPodSecurityLabels("local-path-storage"),
+		StorageClass:              generator.config.GetStorageClass("local-path"),
+	}, generator.config.GetFullLocalAssetFilename(utils.LOCAL_PATH_SETUP), true, false)
+}
+
 func (generator *Generator) generateLetsEncryptClusterIssuer() error {
 	return utils.ApplyTemplateAndSave("lets-encrypt-cluster-issuer", utils.TEMPLATE_LETSENCRYPT_CLUSTER_ISSUER_SETUP, struct {
 		Email string
+		Hosts []string
 	}{
 		Email: generator.config.Config.Email,
+		Hosts: generator.config.Config.IngressHosts,
 	}, generator.config.GetFullLocalAssetFilename(utils.LETSENCRYPT_CLUSTER_ISSUER), true, false)
 }
 
 func (generator *Generator) generateCoreDNSSetup() error {
 	return utils.ApplyTemplateAndSave("core-dns", utils.TEMPLATE_COREDNS_SETUP, struct {
-		ClusterDomain string
-		ClusterDNSIP  string
-		CoreDNSImage  string
+		ClusterDomain    string
+		ClusterDNSIP     string
+		CoreDNSImage     string
+		StubDomains      []config.StubDomain
+		UpstreamForwards []string
 	}{
-		ClusterDomain: generator.config.Config.ClusterDomain,
-		ClusterDNSIP:  generator.config.Config.ClusterDNSIP,
-		CoreDNSImage:  generator.config.Config.Versions.CoreDNS,
+		ClusterDomain:    generator.config.Config.ClusterDomain,
+		ClusterDNSIP:     generator.config.Config.ClusterDNSIP,
+		CoreDNSImage:     generator.config.Config.Versions.CoreDNS,
+		StubDomains:      generator.config.Config.CoreDNSStubDomains,
+		UpstreamForwards: generator.config.Config.CoreDNSUpstreamForwards,
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_COREDNS_SETUP), true, false)
 }
 
 func (generator *Generator) generateElasticSearchOperatorSetup() error {
+	if generator.config.Config.LoggingBackend != utils.LOGGING_BACKEND_EFK {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("elasticsearch-operator", utils.TEMPLATE_ELASTICSEARCH_OPERATOR_SETUP, struct {
 		ElasticsearchOperatorImage string
+		NamespaceLabels            map[string]string
 	}{
 		ElasticsearchOperatorImage: generator.config.Config.Versions.ElasticsearchOperator,
+		NamespaceLabels:            generator.config.GetPodSecurityLabels("logging"),
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_ELASTICSEARCH_OPERATOR_SETUP), true, false)
 }
 
 func (generator *Generator) generateEFKSetup() error {
+	if generator.config.Config.LoggingBackend != utils.LOGGING_BACKEND_EFK {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("efk", utils.TEMPLATE_EFK_SETUP, struct {
 		ElasticsearchImage     string
 		ElasticsearchCronImage string
@@ -655,20 +1193,43 @@ func (generator *Generator) generateEFKSetup() error {
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_EFK_SETUP), true, false)
 }
 
-func (generator *Generator) generateARKSetup() error {
-	return utils.ApplyTemplateAndSave("ark-setup", utils.TEMPLATE_ARK_SETUP, struct {
-		ArkImage         string
+// generateLokiSetup generates the Loki+Promtail manifests, the lightweight logging alternative to EFK selected
+// via logging-backend. It reuses the Grafana deployed by the monitoring feature for visualization instead of
+// bringing its own dashboard, the same way kube-prometheus's datasource setup does for Prometheus
+func (generator *Generator) generateLokiSetup() error {
+	if generator.config.Config.LoggingBackend != utils.LOGGING_BACKEND_LOKI {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("loki", utils.TEMPLATE_LOKI_SETUP, struct {
+		LokiImage       string
+		PromtailImage   string
+		NamespaceLabels map[string]string
+	}{
+		LokiImage:       generator.config.Config.Versions.Loki,
+		PromtailImage:   generator.config.Config.Versions.Promtail,
+		NamespaceLabels: generator.config.GetPodSecurityLabels("logging"),
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_LOKI_SETUP), true, false)
+}
+
+func (generator *Generator) generateVeleroSetup() error {
+	return utils.ApplyTemplateAndSave("velero-setup", utils.TEMPLATE_VELERO_SETUP, struct {
+		VeleroImage      string
 		MinioServerImage string
 		MinioClientImage string
 		PodsDirectory    string
 		MinioPort        uint16
+		NamespaceLabels  map[string]string
+		BackupSchedules  []config.BackupSchedule
 	}{
-		ArkImage:         generator.config.Config.Versions.Ark,
+		VeleroImage:      generator.config.Config.Versions.Velero,
 		MinioServerImage: generator.config.Config.Versions.MinioServer,
 		MinioClientImage: generator.config.Config.Versions.MinioClient,
 		PodsDirectory:    generator.config.GetFullTargetAssetDirectory(utils.PODS_DATA_DIRECTORY),
 		MinioPort:        utils.PORT_MINIO,
-	}, generator.config.GetFullLocalAssetFilename(utils.K8S_ARK_SETUP), true, false)
+		NamespaceLabels:  generator.config.GetPodSecurityLabels("backup"),
+		BackupSchedules:  generator.config.Config.BackupSchedules,
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_VELERO_SETUP), true, false)
 }
 
 func (generator *Generator) generateHeapsterSetup() error {
@@ -696,12 +1257,22 @@ func (generator *Generator) generateKubernetesDashboardSetup() error {
 func (generator *Generator) generateCertManagerSetup() error {
 	return utils.ApplyTemplateAndSave("cert-manager", utils.TEMPLATE_CERT_MANAGER_SETUP, struct {
 		CertManagerControllerImage string
+		Hosts                      []string
+		IngressClass               string
+		DefaultBackendService      string
 	}{
 		CertManagerControllerImage: generator.config.Config.Versions.CertManagerController,
+		Hosts:                      generator.config.Config.IngressHosts,
+		IngressClass:               generator.config.IngressClassName(),
+		DefaultBackendService:      generator.config.IngressDefaultBackendService(),
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_CERT_MANAGER_SETUP), true, false)
 }
 
 func (generator *Generator) generateNginxIngressSetup() error {
+	if generator.config.Config.IngressController != utils.INGRESS_CONTROLLER_NGINX {
+		return nil
+	}
+
 	return utils.ApplyTemplateAndSave("nginx-ingress", utils.TEMPLATE_NGINX_INGRESS_SETUP, struct {
 		NginxIngressControllerImage     string
 		NginxIngressDefaultBackendImage string
@@ -711,11 +1282,49 @@ func (generator *Generator) generateNginxIngressSetup() error {
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_NGINX_INGRESS_SETUP), true, false)
 }
 
+func (generator *Generator) generateMetalLBSetup() error {
+	if !generator.config.Config.MetalLB.Enabled {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("metallb", utils.TEMPLATE_METALLB_SETUP, struct {
+		MetalLBControllerImage string
+		MetalLBSpeakerImage    string
+		AddressPoolRange       string
+		NamespaceLabels        map[string]string
+	}{
+		MetalLBControllerImage: generator.config.Config.Versions.MetalLBController,
+		MetalLBSpeakerImage:    generator.config.Config.Versions.MetalLBSpeaker,
+		AddressPoolRange:       generator.config.Config.MetalLB.AddressRange(),
+		NamespaceLabels:        generator.config.GetPodSecurityLabels("networking"),
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_METALLB_SETUP), true, false)
+}
+
+func (generator *Generator) generateTraefikSetup() error {
+	if generator.config.Config.IngressController != utils.INGRESS_CONTROLLER_TRAEFIK {
+		return nil
+	}
+
+	return utils.ApplyTemplateAndSave("traefik", utils.TEMPLATE_TRAEFIK_SETUP, struct {
+		TraefikImage        string
+		DefaultBackendImage string
+	}{
+		TraefikImage:        generator.config.Config.Versions.Traefik,
+		DefaultBackendImage: generator.config.Config.Versions.NginxIngressDefaultBackend,
+	}, generator.config.GetFullLocalAssetFilename(utils.K8S_TRAEFIK_SETUP), true, false)
+}
+
 func (generator *Generator) generateMetricsServerSetup() error {
 	return utils.ApplyTemplateAndSave("metrics-server", utils.TEMPLATE_METRICS_SERVER_SETUP, struct {
-		MetricsServerImage string
+		MetricsServerImage           string
+		KubeletInsecureTLS           bool
+		KubeletPreferredAddressTypes string
+		NamespaceLabels              map[string]string
 	}{
-		MetricsServerImage: generator.config.Config.Versions.MetricsServer,
+		MetricsServerImage:           generator.config.Config.Versions.MetricsServer,
+		KubeletInsecureTLS:           generator.config.Config.MetricsServerKubeletInsecureTLS,
+		KubeletPreferredAddressTypes: generator.config.Config.MetricsServerKubeletPreferredAddressTypes,
+		NamespaceLabels:              generator.config.GetPodSecurityLabels("monitoring"),
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_METRICS_SERVER_SETUP), true, false)
 }
 
@@ -731,7 +1340,170 @@ func (generator *Generator) generatePrometheusOperatorSetup() error {
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_PROMETHEUS_OPERATOR_SETUP), true, false)
 }
 
+// ensureGrafanaAdminPassword returns the configured Grafana admin password, generating and persisting a
+// random one the first time the monitoring setup is generated if none was set via grafana-admin-password
+func (generator *Generator) ensureGrafanaAdminPassword() (string, error) {
+	if len(generator.config.Config.GrafanaAdminPassword) > 0 {
+		return generator.config.Config.GrafanaAdminPassword, nil
+	}
+
+	password := utils.GenerateRandomPassword()
+
+	generator.config.Config.GrafanaAdminPassword = password
+
+	if error := generator.config.Save(); error != nil {
+		return "", error
+	}
+
+	log.WithFields(log.Fields{"username": generator.config.Config.GrafanaAdminUsername, "password": password}).Warn("Generated Grafana admin password")
+
+	return password, nil
+}
+
+// alertmanagerSecretMountPath is where the prometheus-operator mounts every secret listed in the
+// Alertmanager CRD's spec.secrets, one directory per secret name, keys as files within it
+const alertmanagerSecretMountPath = "/etc/alertmanager/secrets"
+
+type alertmanagerSlackConfig struct {
+	Channel    string `yaml:"channel"`
+	APIURLFile string `yaml:"api_url_file"`
+}
+
+type alertmanagerEmailConfig struct {
+	To               string `yaml:"to"`
+	AuthPasswordFile string `yaml:"auth_password_file,omitempty"`
+}
+
+type alertmanagerPagerdutyConfig struct {
+	ServiceKeyFile string `yaml:"service_key_file"`
+}
+
+type alertmanagerReceiverConfig struct {
+	Name             string                        `yaml:"name"`
+	SlackConfigs     []alertmanagerSlackConfig     `yaml:"slack_configs,omitempty"`
+	EmailConfigs     []alertmanagerEmailConfig     `yaml:"email_configs,omitempty"`
+	PagerdutyConfigs []alertmanagerPagerdutyConfig `yaml:"pagerduty_configs,omitempty"`
+}
+
+type alertmanagerSubRouteConfig struct {
+	Receiver string            `yaml:"receiver"`
+	Match    map[string]string `yaml:"match,omitempty"`
+	Continue bool              `yaml:"continue,omitempty"`
+}
+
+type alertmanagerRouteConfig struct {
+	GroupBy        []string                     `yaml:"group_by"`
+	GroupWait      string                       `yaml:"group_wait"`
+	GroupInterval  string                       `yaml:"group_interval"`
+	Receiver       string                       `yaml:"receiver"`
+	RepeatInterval string                       `yaml:"repeat_interval"`
+	Routes         []alertmanagerSubRouteConfig `yaml:"routes,omitempty"`
+}
+
+type alertmanagerGlobalConfig struct {
+	ResolveTimeout string `yaml:"resolve_timeout"`
+}
+
+type alertmanagerConfig struct {
+	Global    alertmanagerGlobalConfig     `yaml:"global"`
+	Receivers []alertmanagerReceiverConfig `yaml:"receivers"`
+	Route     alertmanagerRouteConfig      `yaml:"route"`
+}
+
+// alertmanagerSecretFile turns a secret reference into the path the secret's key ends up at once
+// prometheus-operator has mounted it, for use in Alertmanager config fields like api_url_file
+func alertmanagerSecretFile(secretRef *config.AlertmanagerSecretRef) string {
+	return fmt.Sprintf("%s/%s/%s", alertmanagerSecretMountPath, secretRef.Name, secretRef.Key)
+}
+
+// buildAlertmanagerConfig renders config.Config's alertmanager-receivers and alertmanager-routes into
+// Alertmanager's native configuration format, keeping the existing DeadMansSwitch no-op route and "null"
+// receiver as the default so dead-man's-switch style health checks keep working, and collects the distinct
+// secret names referenced so they can be added to the Alertmanager CRD's spec.secrets
+func buildAlertmanagerConfig(receivers []config.AlertmanagerReceiver, routes []config.AlertmanagerRoute) (string, []string, error) {
+	secretNames := []string{}
+	seenSecretNames := map[string]bool{}
+
+	addSecretName := func(secretRef *config.AlertmanagerSecretRef) {
+		if secretRef == nil || seenSecretNames[secretRef.Name] {
+			return
+		}
+
+		seenSecretNames[secretRef.Name] = true
+
+		secretNames = append(secretNames, secretRef.Name)
+	}
+
+	receiverConfigs := []alertmanagerReceiverConfig{{Name: "null"}}
+
+	for _, receiver := range receivers {
+		receiverConfig := alertmanagerReceiverConfig{Name: receiver.Name}
+
+		if len(receiver.SlackChannel) > 0 {
+			addSecretName(receiver.SlackWebhookSecretRef)
+
+			receiverConfig.SlackConfigs = []alertmanagerSlackConfig{{Channel: receiver.SlackChannel, APIURLFile: alertmanagerSecretFile(receiver.SlackWebhookSecretRef)}}
+		}
+
+		if len(receiver.EmailTo) > 0 {
+			emailConfig := alertmanagerEmailConfig{To: receiver.EmailTo}
+
+			if receiver.EmailPasswordSecretRef != nil {
+				addSecretName(receiver.EmailPasswordSecretRef)
+
+				emailConfig.AuthPasswordFile = alertmanagerSecretFile(receiver.EmailPasswordSecretRef)
+			}
+
+			receiverConfig.EmailConfigs = []alertmanagerEmailConfig{emailConfig}
+		}
+
+		if receiver.PagerDutyServiceKeySecretRef != nil {
+			addSecretName(receiver.PagerDutyServiceKeySecretRef)
+
+			receiverConfig.PagerdutyConfigs = []alertmanagerPagerdutyConfig{{ServiceKeyFile: alertmanagerSecretFile(receiver.PagerDutyServiceKeySecretRef)}}
+		}
+
+		receiverConfigs = append(receiverConfigs, receiverConfig)
+	}
+
+	subRoutes := []alertmanagerSubRouteConfig{{Match: map[string]string{"alertname": "DeadMansSwitch"}, Receiver: "null"}}
+
+	for _, route := range routes {
+		subRoutes = append(subRoutes, alertmanagerSubRouteConfig{Receiver: route.Receiver, Match: route.Match, Continue: route.Continue})
+	}
+
+	alertmanagerConfig := alertmanagerConfig{
+		Global:    alertmanagerGlobalConfig{ResolveTimeout: "5m"},
+		Receivers: receiverConfigs,
+		Route: alertmanagerRouteConfig{
+			GroupBy:        []string{"job"},
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "12h",
+			Receiver:       "null",
+			Routes:         subRoutes,
+		},
+	}
+
+	content, error := yaml.Marshal(alertmanagerConfig)
+	if error != nil {
+		return "", nil, error
+	}
+
+	return string(content), secretNames, nil
+}
+
 func (generator *Generator) generateKubePrometheusSetup() error {
+	grafanaAdminPassword, error := generator.ensureGrafanaAdminPassword()
+	if error != nil {
+		return error
+	}
+
+	alertmanagerConfigYAML, alertmanagerSecretNames, error := buildAlertmanagerConfig(generator.config.Config.AlertmanagerReceivers, generator.config.Config.AlertmanagerRoutes)
+	if error != nil {
+		return error
+	}
+
 	return utils.ApplyTemplateAndSave("kube-prometheus", utils.TEMPLATE_KUBE_PROMETHEUS_SETUP, struct {
 		AddonResizerImage           string
 		KubeStateMetricsImage       string
@@ -741,6 +1513,14 @@ func (generator *Generator) generateKubePrometheusSetup() error {
 		PrometheusNodeExporterImage string
 		PrometheusAlertManagerImage string
 		GrafanaPort                 uint16
+		GrafanaAdminUsernameBase64  string
+		GrafanaAdminPasswordBase64  string
+		LokiEnabled                 bool
+		PrometheusRetention         string
+		PrometheusStorageSize       string
+		AlertManagerStorageSize     string
+		AlertmanagerConfigBase64    string
+		AlertmanagerSecretNames     []string
 	}{
 		AddonResizerImage:           generator.config.Config.Versions.AddonResizer,
 		KubeStateMetricsImage:       generator.config.Config.Versions.KubeStateMetrics,
@@ -750,6 +1530,14 @@ func (generator *Generator) generateKubePrometheusSetup() error {
 		PrometheusNodeExporterImage: generator.config.Config.Versions.PrometheusNodeExporter,
 		PrometheusAlertManagerImage: generator.config.Config.Versions.PrometheusAlertManager,
 		GrafanaPort:                 utils.PORT_GRAFANA,
+		GrafanaAdminUsernameBase64:  base64.StdEncoding.EncodeToString([]byte(generator.config.Config.GrafanaAdminUsername)),
+		GrafanaAdminPasswordBase64:  base64.StdEncoding.EncodeToString([]byte(grafanaAdminPassword)),
+		LokiEnabled:                 generator.config.Config.LoggingBackend == utils.LOGGING_BACKEND_LOKI,
+		PrometheusRetention:         generator.config.Config.PrometheusRetention,
+		PrometheusStorageSize:       generator.config.Config.PrometheusStorageSize,
+		AlertManagerStorageSize:     generator.config.Config.AlertManagerStorageSize,
+		AlertmanagerConfigBase64:    base64.StdEncoding.EncodeToString([]byte(alertmanagerConfigYAML)),
+		AlertmanagerSecretNames:     alertmanagerSecretNames,
 	}, generator.config.GetFullLocalAssetFilename(utils.K8S_KUBE_PROMETHEUS_SETUP), true, true)
 }
 
@@ -799,11 +1587,15 @@ func (generator *Generator) generateWordpressSetup() error {
 		MySQLImage             string
 		WordPressImage         string
 		WordPressPort          uint16
+		NamespaceLabels        map[string]string
+		IngressClass           string
 	}{
 		WordPressIngressDomain: fmt.Sprintf("%s.%s", utils.INGRESS_SUBDOMAIN_WORDPRESS, generator.config.Config.IngressDomain),
 		MySQLImage:             generator.config.Config.Versions.MySQL,
 		WordPressImage:         generator.config.Config.Versions.WordPress,
 		WordPressPort:          utils.PORT_WORDPRESS,
+		NamespaceLabels:        generator.config.GetPodSecurityLabels("showcase"),
+		IngressClass:           generator.config.IngressClassName(),
 	}, generator.config.GetFullLocalAssetFilename(utils.WORDPRESS_SETUP), true, false)
 }
 
@@ -830,14 +1622,34 @@ func (generator *Generator) generateBashCompletionHelm() error {
 	return generator.generateBashCompletion(utils.HELM_BINARY, utils.BASH_COMPLETION_HELM)
 }
 
-func (generator *Generator) generateBashCompletionArk() error {
-	return generator.generateBashCompletion(utils.ARK_BINARY, utils.BASH_COMPLETION_ARK)
+func (generator *Generator) generateBashCompletionVelero() error {
+	return generator.generateBashCompletion(utils.VELERO_BINARY, utils.BASH_COMPLETION_VELERO)
 }
 
 func (generator *Generator) generateBashCompletionCriCtl() error {
 	return generator.generateBashCompletion(utils.CRICTL_BINARY, utils.BASH_COMPLETION_CRICTL)
 }
 
+func (generator *Generator) generateOIDCCA() error {
+	if !generator.config.Config.OIDC.Enabled || len(generator.config.Config.OIDC.CAFilename) == 0 {
+		return nil
+	}
+
+	return utils.CopyFile(generator.config.Config.OIDC.CAFilename, generator.config.GetFullLocalAssetFilename(utils.OIDC_CA_PEM))
+}
+
+func (generator *Generator) generateAdditionalTrustedCAs() error {
+	for index, caFilename := range generator.config.Config.AdditionalTrustedCAs {
+		assetName := generator.config.GetAdditionalTrustedCAAssetName(index)
+
+		if error := utils.CopyFile(caFilename, generator.config.GetFullLocalAssetFilename(assetName)); error != nil {
+			return fmt.Errorf("could not copy additional trusted CA '%s' (%s)", caFilename, error.Error())
+		}
+	}
+
+	return nil
+}
+
 func (generator *Generator) GenerateFiles() error {
 	for _, step := range generator.generatorSteps {
 		if error := step(); error != nil {
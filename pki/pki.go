@@ -1,6 +1,9 @@
 package pki
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -52,7 +55,62 @@ type CertificateAndPrivateKey struct {
 	CertificateFilename string
 	PrivateKeyFilename  string
 	Certificate         *x509.Certificate
-	PrivateKey          *rsa.PrivateKey
+	PrivateKey          crypto.Signer
+}
+
+// KeyAlgorithm selects how GenerateCA/GenerateClient create their private key. Algorithm is either
+// utils.CERTIFICATE_ALGORITHM_RSA (using RSASize) or utils.CERTIFICATE_ALGORITHM_ECDSA (using ECDSACurve); a
+// cluster uses one algorithm for the CA and every leaf certificate
+type KeyAlgorithm struct {
+	Algorithm  string
+	RSASize    uint16
+	ECDSACurve string
+}
+
+func ecdsaCurve(name string) (elliptic.Curve, error) {
+	switch name {
+	case utils.ECDSA_CURVE_P224:
+		return elliptic.P224(), nil
+	case utils.ECDSA_CURVE_P256:
+		return elliptic.P256(), nil
+	case utils.ECDSA_CURVE_P384:
+		return elliptic.P384(), nil
+	case utils.ECDSA_CURVE_P521:
+		return elliptic.P521(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported ecdsa curve '%s'", name)
+}
+
+func generateKey(algorithm KeyAlgorithm) (crypto.Signer, error) {
+	if algorithm.Algorithm == utils.CERTIFICATE_ALGORITHM_ECDSA {
+		curve, error := ecdsaCurve(algorithm.ECDSACurve)
+		if error != nil {
+			return nil, error
+		}
+
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
+
+	return rsa.GenerateKey(rand.Reader, int(algorithm.RSASize))
+}
+
+// encodePrivateKeyPEM marshals privateKey into the PEM block format matching its type
+func encodePrivateKeyPEM(privateKey crypto.Signer) (*pem.Block, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}, nil
+
+	case *ecdsa.PrivateKey:
+		bytes, error := x509.MarshalECPrivateKey(key)
+		if error != nil {
+			return nil, error
+		}
+
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: bytes}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported private key type %T", privateKey)
 }
 
 func loadPEMBlock(filename string) (*pem.Block, error) {
@@ -73,6 +131,20 @@ func loadPEMBlock(filename string) (*pem.Block, error) {
 	return block, nil
 }
 
+// LoadCertificate parses just the certificate out of a PEM file, without requiring its private key
+func LoadCertificate(certificateFilename string) (*x509.Certificate, error) {
+	block, error := loadPEMBlock(certificateFilename)
+	if error != nil {
+		return nil, error
+	}
+
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("wrong certificate format in '%s'", certificateFilename)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
 func LoadCertificateAndPrivateKey(certificateFilename, privateKeyFilename string) (*CertificateAndPrivateKey, error) {
 	result := &CertificateAndPrivateKey{CertificateFilename: certificateFilename, PrivateKeyFilename: privateKeyFilename}
 
@@ -95,11 +167,21 @@ func LoadCertificateAndPrivateKey(certificateFilename, privateKeyFilename string
 		return nil, error
 	}
 
-	if block == nil || block.Type != "RSA PRIVATE KEY" {
+	if block == nil {
+		return nil, fmt.Errorf("wrong private key format in '%s'", privateKeyFilename)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		result.PrivateKey, error = x509.ParsePKCS1PrivateKey(block.Bytes)
+
+	case "EC PRIVATE KEY":
+		result.PrivateKey, error = x509.ParseECPrivateKey(block.Bytes)
+
+	default:
 		return nil, fmt.Errorf("wrong private key format in '%s'", privateKeyFilename)
 	}
 
-	result.PrivateKey, error = x509.ParsePKCS1PrivateKey(block.Bytes)
 	if error != nil {
 		return nil, error
 	}
@@ -134,10 +216,8 @@ func newTemplate(validityPeriod int, commonName, organization string) (*x509.Cer
 	return template, nil
 }
 
-func createAndSaveCertificate(signer *CertificateAndPrivateKey, template *x509.Certificate, rsaSize int, certificateFilename, privateKeyFilename string) error {
-	var error error
-
-	privateKey, error := rsa.GenerateKey(rand.Reader, rsaSize)
+func createAndSaveCertificate(signer *CertificateAndPrivateKey, template *x509.Certificate, algorithm KeyAlgorithm, certificateFilename, privateKeyFilename string) error {
+	privateKey, error := generateKey(algorithm)
 	if error != nil {
 		return error
 	}
@@ -146,7 +226,7 @@ func createAndSaveCertificate(signer *CertificateAndPrivateKey, template *x509.C
 		signer = &CertificateAndPrivateKey{Certificate: template, PrivateKey: privateKey}
 	}
 
-	certificateData, error := x509.CreateCertificate(rand.Reader, template, signer.Certificate, &privateKey.PublicKey, signer.PrivateKey)
+	certificateData, error := x509.CreateCertificate(rand.Reader, template, signer.Certificate, privateKey.Public(), signer.PrivateKey)
 	if error != nil {
 		return error
 	}
@@ -157,7 +237,12 @@ func createAndSaveCertificate(signer *CertificateAndPrivateKey, template *x509.C
 		return error
 	}
 
-	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	privateKeyBlock, error := encodePrivateKeyPEM(privateKey)
+	if error != nil {
+		return error
+	}
+
+	privateKeyPEM := pem.EncodeToMemory(privateKeyBlock)
 
 	if error := ioutil.WriteFile(privateKeyFilename, privateKeyPEM, 0644); error != nil {
 		return error
@@ -169,10 +254,22 @@ func createAndSaveCertificate(signer *CertificateAndPrivateKey, template *x509.C
 	return nil
 }
 
-func GenerateCA(rsaSize uint16, validityPeriod uint, commonName, organization, certificateFilename, privateKeyFilename string) error {
+func GenerateCA(algorithm KeyAlgorithm, validityPeriod uint, commonName, organization, certificateFilename, privateKeyFilename string) error {
 	if utils.FileExists(certificateFilename) && utils.FileExists(privateKeyFilename) {
-		utils.LogFilename("Skipped", certificateFilename)
-		utils.LogFilename("Skipped", privateKeyFilename)
+		if utils.IsDryRun() {
+			utils.RecordPlannedFileChange(certificateFilename, "skip", "", "")
+			utils.RecordPlannedFileChange(privateKeyFilename, "skip", "", "")
+		} else {
+			utils.LogFilename("Skipped", certificateFilename)
+			utils.LogFilename("Skipped", privateKeyFilename)
+		}
+
+		return nil
+	}
+
+	if utils.IsDryRun() {
+		utils.RecordPlannedFileChange(certificateFilename, "create", "", "")
+		utils.RecordPlannedFileChange(privateKeyFilename, "create", "", "")
 
 		return nil
 	}
@@ -187,13 +284,31 @@ func GenerateCA(rsaSize uint16, validityPeriod uint, commonName, organization, c
 	template.IsCA = true
 	template.MaxPathLen = 2
 
-	return createAndSaveCertificate(nil, template, int(rsaSize), certificateFilename, privateKeyFilename)
+	return createAndSaveCertificate(nil, template, algorithm, certificateFilename, privateKeyFilename)
 }
 
-func GenerateClient(signer *CertificateAndPrivateKey, rsaSize uint16, validityPeriod uint, commonName, organization string, dnsNames []string, ipAddresses []string, certificateFilename, privateKeyFilename string, force bool) error {
+func GenerateClient(signer *CertificateAndPrivateKey, algorithm KeyAlgorithm, validityPeriod uint, commonName, organization string, dnsNames []string, ipAddresses []string, certificateFilename, privateKeyFilename string, force bool) error {
 	if utils.FileExists(certificateFilename) && utils.FileExists(privateKeyFilename) && !force {
-		utils.LogFilename("Skipped", certificateFilename)
-		utils.LogFilename("Skipped", privateKeyFilename)
+		if utils.IsDryRun() {
+			utils.RecordPlannedFileChange(certificateFilename, "skip", "", "")
+			utils.RecordPlannedFileChange(privateKeyFilename, "skip", "", "")
+		} else {
+			utils.LogFilename("Skipped", certificateFilename)
+			utils.LogFilename("Skipped", privateKeyFilename)
+		}
+
+		return nil
+	}
+
+	if utils.IsDryRun() {
+		action := "create"
+
+		if utils.FileExists(certificateFilename) && utils.FileExists(privateKeyFilename) {
+			action = "update"
+		}
+
+		utils.RecordPlannedFileChange(certificateFilename, action, "", "")
+		utils.RecordPlannedFileChange(privateKeyFilename, action, "", "")
 
 		return nil
 	}
@@ -220,5 +335,5 @@ func GenerateClient(signer *CertificateAndPrivateKey, rsaSize uint16, validityPe
 
 	template.DNSNames = dnsNames
 
-	return createAndSaveCertificate(signer, template, int(rsaSize), certificateFilename, privateKeyFilename)
+	return createAndSaveCertificate(signer, template, algorithm, certificateFilename, privateKeyFilename)
 }
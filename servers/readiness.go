@@ -0,0 +1,78 @@
+package servers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultReadinessProbeTimeout bounds a single probe attempt when a server entry enables a readiness
+// probe without setting its own timeout
+const defaultReadinessProbeTimeout = 5 * time.Second
+
+// readinessProbe is the resolved, per-server readiness check collected from Config.Servers while adding
+// servers in Servers.Run
+type readinessProbe struct {
+	name     string
+	endpoint string
+	timeout  time.Duration
+}
+
+// probeReady checks whether endpoint is reachable within timeout. A "http://" or "https://" endpoint is
+// probed with a GET expecting anything but a 5xx response, anything else is treated as a "host:port" TCP
+// dial target
+func probeReady(endpoint string, timeout time.Duration) bool {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		client := &http.Client{Timeout: timeout}
+
+		response, error := client.Get(endpoint)
+		if error != nil {
+			return false
+		}
+
+		defer response.Body.Close()
+
+		return response.StatusCode < 500
+	}
+
+	connection, error := net.DialTimeout("tcp", endpoint, timeout)
+	if error != nil {
+		return false
+	}
+
+	connection.Close()
+
+	return true
+}
+
+// waitForReadiness blocks until every collected probe succeeds at least once, or globalTimeout elapses,
+// whichever comes first
+func (servers *Servers) waitForReadiness(globalTimeout time.Duration) error {
+	if len(servers.readinessProbes) == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(globalTimeout)
+
+	for _, probe := range servers.readinessProbes {
+		for !probeReady(probe.endpoint, probe.timeout) {
+			if servers.stop {
+				return fmt.Errorf("stopped while waiting for '%s' to become ready", probe.name)
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("server '%s' did not become ready on '%s' within %s", probe.name, probe.endpoint, globalTimeout)
+			}
+
+			time.Sleep(time.Second)
+		}
+
+		log.WithFields(log.Fields{"name": probe.name, "endpoint": probe.endpoint}).Info("Server is ready")
+	}
+
+	return nil
+}
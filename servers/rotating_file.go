@@ -0,0 +1,84 @@
+package servers
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// rotatingFile is an io.WriteCloser that rotates the underlying log file to "<filename>.1" once it
+// grows past maxSizeBytes, keeping a single previous generation around. This keeps a long-running
+// server like etcd from filling the disk with its own log output. A maxSizeBytes of 0 disables rotation.
+// Writes are optionally teed to an additional writer, e.g. the process' own stdout.
+type rotatingFile struct {
+	filename     string
+	maxSizeBytes int64
+	tee          io.Writer
+	lock         sync.Mutex
+	file         *os.File
+	size         int64
+}
+
+func newRotatingFile(filename string, maxSizeBytes int64, tee io.Writer) (*rotatingFile, error) {
+	file, error := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if error != nil {
+		return nil, error
+	}
+
+	info, error := file.Stat()
+	if error != nil {
+		file.Close()
+
+		return nil, error
+	}
+
+	return &rotatingFile{filename: filename, maxSizeBytes: maxSizeBytes, tee: tee, file: file, size: info.Size()}, nil
+}
+
+func (rotatingFile *rotatingFile) Write(data []byte) (int, error) {
+	rotatingFile.lock.Lock()
+	defer rotatingFile.lock.Unlock()
+
+	if rotatingFile.maxSizeBytes > 0 && rotatingFile.size+int64(len(data)) > rotatingFile.maxSizeBytes {
+		if error := rotatingFile.rotate(); error != nil {
+			return 0, error
+		}
+	}
+
+	count, error := rotatingFile.file.Write(data)
+	rotatingFile.size += int64(count)
+
+	if rotatingFile.tee != nil {
+		rotatingFile.tee.Write(data)
+	}
+
+	return count, error
+}
+
+func (rotatingFile *rotatingFile) rotate() error {
+	if error := rotatingFile.file.Close(); error != nil {
+		return error
+	}
+
+	rotatedFilename := rotatingFile.filename + ".1"
+
+	os.Remove(rotatedFilename)
+	os.Rename(rotatingFile.filename, rotatedFilename)
+
+	file, error := os.OpenFile(rotatingFile.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if error != nil {
+		return error
+	}
+
+	rotatingFile.file = file
+	rotatingFile.size = 0
+
+	return nil
+}
+
+func (rotatingFile *rotatingFile) Close() error {
+	rotatingFile.lock.Lock()
+	defer rotatingFile.lock.Unlock()
+
+	return rotatingFile.file.Close()
+}
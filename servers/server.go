@@ -4,4 +4,5 @@ type Server interface {
 	Start() error
 	Stop()
 	Name() string
+	IsRunning() bool
 }
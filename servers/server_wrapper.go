@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,16 +16,51 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// restartBackoffInitial/restartBackoffMax bound the delay between restarts of a crashing server, growing
+// exponentially so a server stuck in a crash loop does not spin the CPU, while still retrying quickly for
+// a one-off crash
+const restartBackoffInitial = time.Second
+const restartBackoffMax = 30 * time.Second
+
+// restartMinUptime is how long a server has to stay up for its exit to not count towards the
+// consecutive-failure streak that eventually gives up on it
+const restartMinUptime = 10 * time.Second
+
+// maxConsecutiveFailures is how many rapid restarts in a row are tolerated before the supervisor gives
+// up on a server instead of thrashing forever
+const maxConsecutiveFailures = 5
+
+// restartBackoffInterval returns the delay before the given restart attempt (0-based), growing
+// exponentially from restartBackoffInitial up to restartBackoffMax
+func restartBackoffInterval(attempt int) time.Duration {
+	interval := restartBackoffInitial
+
+	for i := 0; i < attempt && interval < restartBackoffMax; i++ {
+		interval *= 2
+	}
+
+	if interval > restartBackoffMax {
+		interval = restartBackoffMax
+	}
+
+	return interval
+}
+
 type ServerWrapper struct {
 	stop            bool
+	running         bool
+	runningLock     sync.Mutex
 	name            string
 	baseDirectory   string
 	command         []string
 	logger          config.LoggerConfig
+	maxLogSizeBytes int64
+	restartPolicy   string
 	pathEnvironment string
+	environment     map[string]string
 }
 
-func NewServerWrapper(_config config.InternalConfig, name string, serverConfig config.ServerConfig, pathEnvironment string) (Server, error) {
+func NewServerWrapper(_config config.InternalConfig, name string, serverConfig config.ServerConfig, pathEnvironment string, logToFile bool, logDirectory string, maxLogSizeBytes int64) (Server, error) {
 	var error error
 
 	serverConfig.Command, error = _config.ApplyTemplate("command", serverConfig.Command)
@@ -33,13 +69,29 @@ func NewServerWrapper(_config config.InternalConfig, name string, serverConfig c
 		return nil, error
 	}
 
-	server := &ServerWrapper{name: name, baseDirectory: _config.BaseDirectory, command: []string{serverConfig.Command}, logger: serverConfig.Logger, pathEnvironment: pathEnvironment}
+	restartPolicy := serverConfig.RestartPolicy
+
+	if len(restartPolicy) == 0 {
+		restartPolicy = utils.RESTART_POLICY_ALWAYS
+	}
+
+	server := &ServerWrapper{name: name, baseDirectory: _config.BaseDirectory, command: []string{serverConfig.Command}, logger: serverConfig.Logger, maxLogSizeBytes: maxLogSizeBytes, restartPolicy: restartPolicy, pathEnvironment: pathEnvironment, environment: serverConfig.Environment}
 
 	server.logger.Filename, error = _config.ApplyTemplate("LoggingDirectory", server.logger.Filename)
 	if error != nil {
 		return nil, error
 	}
 
+	// logToFile/logDirectory, set by the run command, override the per-server logger settings coming
+	// from the config file so every server ends up with its own log file under the same directory
+	if !logToFile {
+		server.logger.Enabled = false
+
+	} else if len(logDirectory) > 0 {
+		server.logger.Enabled = true
+		server.logger.Filename = filepath.Join(logDirectory, name+".log")
+	}
+
 	for key, value := range serverConfig.Arguments {
 		if len(value) == 0 {
 			server.command = append(server.command, fmt.Sprintf("--%s", key))
@@ -57,6 +109,65 @@ func NewServerWrapper(_config config.InternalConfig, name string, serverConfig c
 	return server, nil
 }
 
+// run executes the server command once, blocking until it exits, and reports whether it exited cleanly
+func (server *ServerWrapper) run() error {
+	command := exec.Command(server.command[0], server.command[1:]...)
+	command.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+		Pgid:    0,
+	}
+
+	command.Env = os.Environ()
+	command.Env = append(command.Env, server.pathEnvironment)
+
+	for key, value := range server.environment {
+		command.Env = append(command.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	var logFile *rotatingFile
+	var error error
+
+	if server.logger.Enabled {
+		logFile, error = newRotatingFile(server.logger.Filename, server.maxLogSizeBytes, os.Stdout)
+
+		if error != nil {
+			return error
+		}
+
+		defer logFile.Close()
+
+		command.Stdout = logFile
+		command.Stderr = logFile
+
+	} else {
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+	}
+
+	server.setRunning(true)
+
+	error = command.Run()
+
+	server.setRunning(false)
+
+	return error
+}
+
+// shouldRestart applies RestartPolicy to the outcome of the last run: never restarts, on-failure only
+// restarts after a non-zero exit, always restarts regardless of how the server exited
+func (server *ServerWrapper) shouldRestart(exitedCleanly bool) bool {
+	switch server.restartPolicy {
+	case utils.RESTART_POLICY_NEVER:
+		return false
+
+	case utils.RESTART_POLICY_ON_FAILURE:
+		return !exitedCleanly
+
+	default:
+		return true
+	}
+}
+
 func (server *ServerWrapper) Start() error {
 	server.stop = false
 
@@ -71,45 +182,40 @@ func (server *ServerWrapper) Start() error {
 	log.WithFields(log.Fields{"name": server.Name(), "_command": strings.Join(server.command, " ")}).Info("Starting server")
 
 	go func() {
-		for !server.stop {
-			command := exec.Command(server.command[0], server.command[1:]...)
-			command.SysProcAttr = &syscall.SysProcAttr{
-				Setpgid: true,
-				Pgid:    0,
-			}
+		consecutiveFailures := 0
 
-			command.Env = os.Environ()
-			command.Env = append(command.Env, server.pathEnvironment)
+		for !server.stop {
+			startedAt := time.Now()
 
-			var logFile *os.File
-			var error error
+			error := server.run()
 
-			if server.logger.Enabled {
-				logFile, error = os.OpenFile(server.logger.Filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+			if server.stop {
+				break
+			}
 
-				if error != nil {
-					log.WithFields(log.Fields{"filename": logFile, "error": error}).Error("Could not open file")
+			exitedCleanly := error == nil
+			ranLongEnough := time.Since(startedAt) >= restartMinUptime
 
-					continue
-				}
+			if exitedCleanly || ranLongEnough {
+				consecutiveFailures = 0
 
-				command.Stdout = logFile
-				command.Stderr = logFile
+			} else {
+				consecutiveFailures++
 			}
 
-			defer func() {
-				if logFile != nil {
-					logFile.Close()
-				}
-			}()
-
-			command.Run()
+			if !server.shouldRestart(exitedCleanly) {
+				log.WithFields(log.Fields{"name": server.name, "error": error}).Info("Server stopped, not restarting it")
 
-			time.Sleep(time.Second)
+				break
+			}
 
-			if !server.stop {
-				log.WithFields(log.Fields{"name": server.name, "_command": strings.Join(server.command, " ")}).Error("Restarting server")
+			if consecutiveFailures > maxConsecutiveFailures {
+				log.WithFields(log.Fields{"name": server.name, "_command": strings.Join(server.command, " "), "consecutive_failures": consecutiveFailures}).Fatal("Server is crash-looping, giving up")
 			}
+
+			log.WithFields(log.Fields{"name": server.name, "_command": strings.Join(server.command, " "), "error": error}).Error("Restarting server")
+
+			time.Sleep(restartBackoffInterval(consecutiveFailures))
 		}
 	}()
 
@@ -118,8 +224,24 @@ func (server *ServerWrapper) Start() error {
 
 func (server *ServerWrapper) Stop() {
 	server.stop = true
+
+	server.setRunning(false)
 }
 
 func (server *ServerWrapper) Name() string {
 	return server.name
 }
+
+func (server *ServerWrapper) setRunning(running bool) {
+	server.runningLock.Lock()
+	defer server.runningLock.Unlock()
+
+	server.running = running
+}
+
+func (server *ServerWrapper) IsRunning() bool {
+	server.runningLock.Lock()
+	defer server.runningLock.Unlock()
+
+	return server.running
+}
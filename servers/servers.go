@@ -1,11 +1,16 @@
 package servers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
+	"sort"
+	"sync"
 	"syscall"
 	"time"
 
@@ -16,22 +21,64 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// vipHolder is implemented by servers that can report whether this node currently holds a virtual ip
+type vipHolder interface {
+	HasVIP() bool
+}
+
+// vipStatusReporter is implemented by servers that can report detailed VIPManager status for the VIP status endpoint
+type vipStatusReporter interface {
+	Status() Status
+}
+
+// serverStartupTier orders known servers into dependency tiers (etcd/containerd before kubelet/apiserver,
+// which in turn come before the components that talk to them) so startup does not race dependents ahead of
+// their dependencies. Servers without an entry start in the first tier.
+var serverStartupTier = map[string]int{
+	"etcd":                    0,
+	"containerd":              0,
+	"gobetween":               0,
+	"kube-apiserver":          1,
+	"kubelet":                 1,
+	"kube-controller-manager": 2,
+	"kube-scheduler":          2,
+	"kube-proxy":              2,
+}
+
 type Servers struct {
-	config      *config.InternalConfig
-	servers     []Server
-	stop        bool
-	killTimeout uint
+	config           *config.InternalConfig
+	servers          []Server
+	readinessProbes  []readinessProbe
+	stop             bool
+	killTimeout      uint
+	startupWait      uint
+	healthAddress    string
+	vipStatusAddress string
+	logToFile        bool
+	logDirectory     string
+	maxLogSizeBytes  int64
+	readinessTimeout time.Duration
 }
 
-func NewServers(_config *config.InternalConfig, killTimeout uint) *Servers {
-	return &Servers{config: _config, servers: []Server{}, stop: false, killTimeout: killTimeout}
+func NewServers(_config *config.InternalConfig, killTimeout, startupWait uint, healthAddress, vipStatusAddress string, logToFile bool, logDirectory string, maxLogSizeMegaBytes uint, readinessTimeout uint) *Servers {
+	return &Servers{config: _config, servers: []Server{}, stop: false, killTimeout: killTimeout, startupWait: startupWait, healthAddress: healthAddress, vipStatusAddress: vipStatusAddress, logToFile: logToFile, logDirectory: logDirectory, maxLogSizeBytes: int64(maxLogSizeMegaBytes) * 1024 * 1024, readinessTimeout: time.Duration(readinessTimeout) * time.Second}
+}
+
+// sortServersByStartupTier orders servers so every dependency tier starts before the tier that depends on it
+func (servers *Servers) sortServersByStartupTier() {
+	sort.SliceStable(servers.servers, func(i, j int) bool {
+		return serverStartupTier[servers.servers[i].Name()] < serverStartupTier[servers.servers[j].Name()]
+	})
 }
 
 func (servers *Servers) add(server Server) {
 	servers.servers = append(servers.servers, server)
 }
 
-func (servers *Servers) runCommand(command *config.Command, commandRetries uint, step, count int) error {
+// runCommand retries command up to commandRetries times, each attempt killed after commandTimeout if it hasn't
+// finished by then, and gives up early once retriesBudget has elapsed across all attempts even if retries remain
+// (retriesBudget of 0 disables the budget and only commandRetries bounds the loop)
+func (servers *Servers) runCommand(command *config.Command, commandRetries uint, commandTimeout, retriesBudget time.Duration) error {
 	newCommand, error := servers.config.ApplyTemplate(command.Name, command.Command)
 	if error != nil {
 		return error
@@ -39,13 +86,24 @@ func (servers *Servers) runCommand(command *config.Command, commandRetries uint,
 
 	log.WithFields(log.Fields{"name": command.Name, "_command": newCommand}).Info("Executing command")
 
+	start := time.Now()
+	attempts := uint(0)
+
 	for retries := uint(0); retries < commandRetries; retries++ {
 		if servers.stop {
 			break
 		}
 
+		if retriesBudget > 0 && time.Since(start) >= retriesBudget {
+			error = fmt.Errorf("retry budget of %s exhausted (%s)", retriesBudget, error)
+
+			break
+		}
+
+		attempts++
+
 		// Run command
-		if error = utils.RunCommand(newCommand); error == nil {
+		if error = utils.RunCommandTimeoutContext(context.Background(), newCommand, commandTimeout); error == nil {
 			break
 		}
 
@@ -55,7 +113,7 @@ func (servers *Servers) runCommand(command *config.Command, commandRetries uint,
 	if error != nil {
 		log.WithFields(log.Fields{"name": command.Name, "command": newCommand, "error": error}).Error("Command failed")
 
-		return error
+		return fmt.Errorf("command '%s' failed after %d attempt(s) over %s (%s)", command.Name, attempts, time.Since(start).Round(time.Second), error.Error())
 	}
 
 	return nil
@@ -65,17 +123,22 @@ func (servers *Servers) Steps() int {
 	return len(servers.config.Config.Servers) + len(servers.config.Config.Commands) + 1
 }
 
-func (servers *Servers) addVIPManager(enabled bool, virtualIP, virtualIPInterface, nodeName, nodeIP, nodeRole string, raftPort uint16) {
+// addVIPManager, if enabled and at least one virtual ip is configured for the role, starts a single VIPManager
+// owning every one of those virtual ips. They all share the same raft election, so failover moves the whole set
+// together instead of each virtual ip electing its own leader independently.
+func (servers *Servers) addVIPManager(enabled bool, virtualIPConfigs []config.VirtualIP, nodeName, nodeIP, nodeRole string, raftPort uint16) {
 	if !enabled {
 		return
 	}
 
-	if len(virtualIP) == 0 {
+	if len(virtualIPConfigs) == 0 {
 		return
 	}
 
-	if len(virtualIPInterface) == 0 {
-		return
+	virtualIPs := []VIP{}
+
+	for _, virtualIPConfig := range virtualIPConfigs {
+		virtualIPs = append(virtualIPs, VIP{IP: virtualIPConfig.IP, Interface: virtualIPConfig.Interface})
 	}
 
 	peers := Peers{}
@@ -90,45 +153,188 @@ func (servers *Servers) addVIPManager(enabled bool, virtualIP, virtualIPInterfac
 
 	logger := Logger{}
 
-	servers.add(NewVIPManager(nodeRole, nodeName, fmt.Sprintf("%s:%d", nodeIP, raftPort), virtualIP, peers, logger, virtualIPInterface))
+	servers.add(NewVIPManager(nodeRole, nodeName, fmt.Sprintf("%s:%d", nodeIP, raftPort), virtualIPs, peers, logger))
 }
 
-func (servers *Servers) extractEmbeddedFiles() error {
-	utils.GetEmbeddedFiles(func(filename string, in io.ReadCloser) error {
-		log.WithFields(log.Fields{"filename": filename}).Info("Extracting embedded file")
+// healthHandler reports 200 as long as every supervised server is still running, 503 otherwise. The
+// response also carries whether this node currently holds its virtual ip, which is informational only
+// since not holding the virtual ip is expected on every node but the current raft leader
+func (servers *Servers) healthHandler(writer http.ResponseWriter, request *http.Request) {
+	holdsVIP := false
+	healthy := true
 
-		hostDirectory := servers.config.GetFullLocalAssetDirectory(utils.HOST_BINARIES_DIRECTORY)
-		outFilename := path.Join(hostDirectory, filename)
+	for _, server := range servers.servers {
+		if holder, ok := server.(vipHolder); ok && holder.HasVIP() {
+			holdsVIP = true
+		}
 
-		if error := utils.CreateDirectoryIfMissing(path.Dir(outFilename)); error != nil {
-			return error
+		if !server.IsRunning() {
+			healthy = false
 		}
+	}
 
-		// Defer source file closing
-		defer in.Close()
+	writer.Header().Set("Content-Type", "application/json")
 
-		// Open target file
-		out, error := os.OpenFile(outFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-		if error != nil {
-			return error
+	if !healthy {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	fmt.Fprintf(writer, `{"healthy": %t, "holds_vip": %t}`, healthy, holdsVIP)
+}
+
+func (servers *Servers) startHealthEndpoint() {
+	if len(servers.healthAddress) == 0 {
+		return
+	}
+
+	go func() {
+		log.WithFields(log.Fields{"address": servers.healthAddress}).Info("Starting health endpoint")
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", servers.healthHandler)
+
+		if error := http.ListenAndServe(servers.healthAddress, mux); error != nil {
+			log.WithFields(log.Fields{"address": servers.healthAddress, "error": error}).Error("Health endpoint failed")
 		}
+	}()
+}
 
-		// Defer target file closing
-		defer out.Close()
+// vipStatusHandler reports, as JSON, every VIPManager's leadership state, owned virtual ips, raft peer
+// health and last election time, so the Prometheus feature (or anything else) can scrape VIP ownership
+// instead of having to SSH into nodes to find out which one currently holds a role's virtual ips
+func (servers *Servers) vipStatusHandler(writer http.ResponseWriter, request *http.Request) {
+	statuses := []Status{}
 
-		// Copy file content
-		if _, error = io.Copy(out, in); error != nil {
-			return error
+	for _, server := range servers.servers {
+		if reporter, ok := server.(vipStatusReporter); ok {
+			statuses = append(statuses, reporter.Status())
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+
+	if error := json.NewEncoder(writer).Encode(statuses); error != nil {
+		log.WithFields(log.Fields{"error": error}).Error("Could not encode vip status")
+	}
+}
+
+func (servers *Servers) startVIPStatusEndpoint() {
+	if len(servers.vipStatusAddress) == 0 {
+		return
+	}
+
+	go func() {
+		log.WithFields(log.Fields{"address": servers.vipStatusAddress}).Info("Starting vip status endpoint")
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/vip-status", servers.vipStatusHandler)
+
+		if error := http.ListenAndServe(servers.vipStatusAddress, mux); error != nil {
+			log.WithFields(log.Fields{"address": servers.vipStatusAddress, "error": error}).Error("VIP status endpoint failed")
 		}
+	}()
+}
+
+// embeddedFileExtractionWorkers bounds how many embedded files are extracted concurrently, so startup does not
+// spawn one goroutine per embedded host binary when there are many/large ones
+const embeddedFileExtractionWorkers = 8
+
+// getEmbeddedFiles is a seam for tests to inject a synthetic walk without touching the real embedded box
+var getEmbeddedFiles = utils.GetEmbeddedFiles
+
+// extractEmbeddedFile extracts a single embedded host binary to its target location, skipping the extraction
+// when the target already exists with a matching size, so re-running Run does not rewrite every binary
+func (servers *Servers) extractEmbeddedFile(filename string, size int64, in io.ReadCloser) error {
+	defer in.Close()
+
+	hostDirectory := servers.config.GetFullLocalAssetDirectory(utils.HOST_BINARIES_DIRECTORY)
+	outFilename := path.Join(hostDirectory, filename)
+
+	if info, error := os.Stat(outFilename); error == nil && info.Size() == size {
+		return nil
+	}
 
-		// Sync content to storage
-		return out.Sync()
+	log.WithFields(log.Fields{"filename": filename}).Info("Extracting embedded file")
+
+	if error := utils.CreateDirectoryIfMissing(path.Dir(outFilename)); error != nil {
+		return error
+	}
+
+	// Open target file
+	out, error := os.OpenFile(outFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if error != nil {
+		return error
+	}
+
+	// Defer target file closing
+	defer out.Close()
+
+	// Copy file content
+	if _, error = io.Copy(out, in); error != nil {
+		return error
+	}
+
+	// Sync content to storage
+	return out.Sync()
+}
+
+// extractEmbeddedFiles extracts every embedded host binary, bounding concurrency to
+// embeddedFileExtractionWorkers. As soon as any extraction fails, its error is captured, no further files are
+// scheduled and the walk itself is stopped by returning that error from the callback, so a disk-full or
+// permission error aborts startup instead of silently continuing with missing binaries
+func (servers *Servers) extractEmbeddedFiles() error {
+	limiter := utils.NewLimiter(embeddedFileExtractionWorkers)
+	waitGroup := sync.WaitGroup{}
+	errorMutex := sync.Mutex{}
+
+	var firstError error
+
+	walkError := getEmbeddedFiles(func(filename string, size int64, in io.ReadCloser) error {
+		errorMutex.Lock()
+		stop := firstError
+		errorMutex.Unlock()
+
+		if stop != nil {
+			in.Close()
+
+			return stop
+		}
+
+		waitGroup.Add(1)
+
+		limiter.Lock()
+
+		go func() {
+			defer waitGroup.Done()
+			defer limiter.Unlock()
+
+			if error := servers.extractEmbeddedFile(filename, size, in); error != nil {
+				errorMutex.Lock()
+
+				if firstError == nil {
+					firstError = error
+				}
+
+				errorMutex.Unlock()
+			}
+		}()
+
+		return nil
 	})
 
-	return nil
+	waitGroup.Wait()
+
+	errorMutex.Lock()
+	defer errorMutex.Unlock()
+
+	if firstError == nil {
+		firstError = walkError
+	}
+
+	return firstError
 }
 
-func (servers *Servers) Run(commandRetries uint) error {
+func (servers *Servers) Run(commandRetries uint, commandTimeout, retriesBudget time.Duration) error {
 	// Make sure the embedded dependencies are in place before the servers are started
 	if error := servers.extractEmbeddedFiles(); error != nil {
 		return error
@@ -147,21 +353,49 @@ func (servers *Servers) Run(commandRetries uint) error {
 			continue
 		}
 
-		server, error := NewServerWrapper(*servers.config, serverConfig.Name, serverConfig, pathEnvironment)
+		server, error := NewServerWrapper(*servers.config, serverConfig.Name, serverConfig, pathEnvironment, servers.logToFile, servers.logDirectory, servers.maxLogSizeBytes)
 
 		if error != nil {
 			return error
 		}
 
 		servers.add(server)
+
+		if serverConfig.ReadinessProbe.Enabled {
+			endpoint, error := servers.config.ApplyTemplate(serverConfig.Name+".readiness-probe", serverConfig.ReadinessProbe.Endpoint)
+			if error != nil {
+				return error
+			}
+
+			timeout := time.Duration(serverConfig.ReadinessProbe.Timeout) * time.Second
+
+			if timeout == 0 {
+				timeout = defaultReadinessProbeTimeout
+			}
+
+			servers.readinessProbes = append(servers.readinessProbes, readinessProbe{name: serverConfig.Name, endpoint: endpoint, timeout: timeout})
+		}
 	}
 
 	// Add Controllers/Workers VIP servers
-	servers.addVIPManager(servers.config.Node.IsController(), servers.config.Config.ControllerVirtualIP, servers.config.Config.ControllerVirtualIPInterface, servers.config.Name, servers.config.Node.IP, utils.NODE_CONTROLLER, servers.config.Config.VIPRaftControllerPort)
-	servers.addVIPManager(servers.config.Node.IsWorker(), servers.config.Config.WorkerVirtualIP, servers.config.Config.WorkerVirtualIPInterface, servers.config.Name, servers.config.Node.IP, utils.NODE_WORKER, servers.config.Config.VIPRaftWorkerPort)
+	servers.addVIPManager(servers.config.Node.IsController(), servers.config.Config.ControllerVirtualIPs, servers.config.Name, servers.config.Node.IP, utils.NODE_CONTROLLER, servers.config.Config.VIPRaftControllerPort)
+	servers.addVIPManager(servers.config.Node.IsWorker(), servers.config.Config.WorkerVirtualIPs, servers.config.Name, servers.config.Node.IP, utils.NODE_WORKER, servers.config.Config.VIPRaftWorkerPort)
+
+	// Start servers in dependency order, waiting between tiers so e.g. etcd/containerd are up before
+	// kube-apiserver/kubelet start, and those are up before the components that depend on them
+	servers.sortServersByStartupTier()
+
+	previousTier := -1
 
-	// Start servers
 	for _, server := range servers.servers {
+		tier := serverStartupTier[server.Name()]
+
+		if previousTier != -1 && tier != previousTier && servers.startupWait > 0 {
+			time.Sleep(time.Duration(servers.startupWait) * time.Second)
+		}
+
+		previousTier = tier
+
 		if error := server.Start(); error != nil {
 			log.WithFields(log.Fields{"name": server.Name(), "error": error}).Error("Server start failed")
 
@@ -169,8 +403,12 @@ func (servers *Servers) Run(commandRetries uint) error {
 		}
 
 		utils.IncreaseProgressStep()
+		utils.LogProgress("start-server", servers.config.Name, server.Name())
 	}
 
+	servers.startHealthEndpoint()
+	servers.startVIPStatusEndpoint()
+
 	// Register servers' stop
 	defer func() {
 		for _, server := range servers.servers {
@@ -185,25 +423,43 @@ func (servers *Servers) Run(commandRetries uint) error {
 	}()
 
 	go func() {
-		// Register commands based on labels to be executed asynchronously
-		for index, command := range servers.config.Config.Commands {
-			if !config.CompareLabels(servers.config.Node.Labels, command.Labels) {
-				utils.IncreaseProgressStep()
+		// Wait for every server with a readiness probe to actually accept connections before running
+		// commands against it, e.g. "kubectl apply" against a kube-apiserver that is still starting up
+		if error := servers.waitForReadiness(servers.readinessTimeout); error != nil {
+			log.WithFields(log.Fields{"error": error}).Fatal("Servers did not become ready")
+		}
 
-				continue
+		// Register commands based on labels to be executed asynchronously, respecting depends-on so
+		// independent commands run concurrently instead of being serialized by file order
+		shouldRun := func(command *config.Command) bool {
+			if !config.CompareLabels(servers.config.Node.Labels, command.Labels) {
+				return false
 			}
 
 			if !utils.HasOS(command.OS) {
-				utils.IncreaseProgressStep()
-
-				continue
+				return false
 			}
 
-			if error := servers.runCommand(command, commandRetries, index+1, len(servers.config.Config.Commands)); error != nil {
-				log.WithFields(log.Fields{"error": error}).Fatal("Cluster setup failed")
+			return true
+		}
+
+		onSkip := func(command *config.Command) {
+			utils.IncreaseProgressStep()
+		}
+
+		run := func(command *config.Command) error {
+			if error := servers.runCommand(command, commandRetries, commandTimeout, retriesBudget); error != nil {
+				return error
 			}
 
 			utils.IncreaseProgressStep()
+			utils.LogProgress("run-command", servers.config.Name, command.Name)
+
+			return nil
+		}
+
+		if error := servers.config.Config.Commands.RunDAG(shouldRun, onSkip, run, true); error != nil {
+			log.WithFields(log.Fields{"error": error}).Fatal("Cluster setup failed")
 		}
 
 		log.Info("Cluster setup finished")
@@ -0,0 +1,43 @@
+package servers
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/darxkies/k8s-tew/config"
+)
+
+// erroringReadCloser fails every Read, simulating an embedded file whose content cannot be copied out
+type erroringReadCloser struct{}
+
+func (erroringReadCloser) Read([]byte) (int, error) {
+	return 0, errors.New("simulated read failure")
+}
+
+func (erroringReadCloser) Close() error {
+	return nil
+}
+
+// TestRunSurfacesEmbeddedFileExtractionFailure makes sure a failure while extracting an embedded host binary
+// aborts Run instead of being silently swallowed
+func TestRunSurfacesEmbeddedFileExtractionFailure(t *testing.T) {
+	originalGetEmbeddedFiles := getEmbeddedFiles
+	defer func() { getEmbeddedFiles = originalGetEmbeddedFiles }()
+
+	getEmbeddedFiles = func(callback func(path string, size int64, in io.ReadCloser) error) error {
+		return callback("broken-binary", 1, erroringReadCloser{})
+	}
+
+	internalConfig := config.NewInternalConfig(t.TempDir())
+	internalConfig.Generate()
+
+	servers := &Servers{config: internalConfig}
+
+	error := servers.Run(1, time.Second, time.Second)
+
+	if error == nil {
+		t.Fatal("expected Run to surface the embedded file extraction failure, got nil error")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/darxkies/k8s-tew/utils"
@@ -50,31 +52,53 @@ func (snapshot Snapshot) Persist(sink raft.SnapshotSink) error {
 func (snapshot Snapshot) Release() {
 }
 
+// VIP is one virtual ip owned by a VIPManager, together with the network interface it gets assigned to
+type VIP struct {
+	IP        string
+	Interface string
+}
+
 type VIPManager struct {
-	_type      string
-	id         string
-	bind       string
-	virtualIP  string
-	fsm        FSM
-	peers      Peers
-	logger     Logger
-	_interface string
-	stop       chan bool
+	_type        string
+	id           string
+	bind         string
+	virtualIPs   []VIP
+	fsm          FSM
+	peers        Peers
+	logger       Logger
+	stop         chan bool
+	running      bool
+	hasVIP       bool
+	raftServer   *raft.Raft
+	lastElection time.Time
+	stateLock    sync.Mutex
+}
+
+// Status reports a VIPManager's raft/VIP state for the optional HTTP status endpoint
+type Status struct {
+	Type         string    `json:"type"`
+	ID           string    `json:"id"`
+	IsLeader     bool      `json:"is_leader"`
+	VirtualIPs   []string  `json:"virtual-ips"`
+	RaftLeader   string    `json:"raft-leader,omitempty"`
+	RaftState    string    `json:"raft-state,omitempty"`
+	NumPeers     int       `json:"num-peers"`
+	LastElection time.Time `json:"last-election,omitempty"`
 }
 
-func NewVIPManager(_type, id, bind string, virtualIP string, peers Peers, logger Logger, _interface string) *VIPManager {
-	return &VIPManager{_type: _type, id: id, peers: peers, bind: bind, virtualIP: virtualIP, fsm: FSM{}, logger: logger, _interface: _interface}
+func NewVIPManager(_type, id, bind string, virtualIPs []VIP, peers Peers, logger Logger) *VIPManager {
+	return &VIPManager{_type: _type, id: id, peers: peers, bind: bind, virtualIPs: virtualIPs, fsm: FSM{}, logger: logger}
 }
 
 func (manager *VIPManager) Name() string {
 	return "vip-manager-" + manager._type
 }
 
-func (manager *VIPManager) updateNetworkConfiguration(action string) error {
-	command := fmt.Sprintf("ip addr %s %s/32 dev %s", action, manager.virtualIP, manager._interface)
+func (manager *VIPManager) updateNetworkConfiguration(action string, virtualIP VIP) error {
+	command := fmt.Sprintf("ip addr %s %s/32 dev %s", action, virtualIP.IP, virtualIP.Interface)
 
 	if error := utils.RunCommand(command); error != nil {
-		log.WithFields(log.Fields{"action": action, "name": manager.Name(), "error": error}).Error("Network update failed")
+		log.WithFields(log.Fields{"action": action, "name": manager.Name(), "virtual-ip": virtualIP.IP, "error": error}).Error("Network update failed")
 
 		return error
 	}
@@ -82,16 +106,86 @@ func (manager *VIPManager) updateNetworkConfiguration(action string) error {
 	return nil
 }
 
+// addIP assigns every virtual ip owned by this manager, so a newly elected leader picks up the whole set at once
+// instead of leaving some unassigned if one of the earlier ones failed
 func (manager *VIPManager) addIP() error {
-	log.WithFields(log.Fields{"name": manager.Name()}).Info("Add virtual ip")
+	log.WithFields(log.Fields{"name": manager.Name()}).Info("Add virtual ips")
+
+	manager.setHasVIP(true)
+
+	var lastError error
 
-	return manager.updateNetworkConfiguration("add")
+	for _, virtualIP := range manager.virtualIPs {
+		if error := manager.updateNetworkConfiguration("add", virtualIP); error != nil {
+			lastError = error
+		}
+	}
+
+	return lastError
 }
 
+// deleteIP removes every virtual ip owned by this manager, so a leader stepping down releases the whole set at once
 func (manager *VIPManager) deleteIP() error {
-	log.WithFields(log.Fields{"name": manager.Name()}).Info("Delete virtual ip")
+	log.WithFields(log.Fields{"name": manager.Name()}).Info("Delete virtual ips")
+
+	manager.setHasVIP(false)
+
+	var lastError error
 
-	return manager.updateNetworkConfiguration("delete")
+	for _, virtualIP := range manager.virtualIPs {
+		if error := manager.updateNetworkConfiguration("delete", virtualIP); error != nil {
+			lastError = error
+		}
+	}
+
+	return lastError
+}
+
+func (manager *VIPManager) setLastElection(lastElection time.Time) {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	manager.lastElection = lastElection
+}
+
+func (manager *VIPManager) setHasVIP(hasVIP bool) {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	manager.hasVIP = hasVIP
+}
+
+// HasVIP reports whether this node currently holds the virtual ip, i.e. is the raft leader
+func (manager *VIPManager) HasVIP() bool {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	return manager.hasVIP
+}
+
+// Status reports this manager's current leadership, virtual ips and raft peer health for the VIP status endpoint
+func (manager *VIPManager) Status() Status {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	status := Status{Type: manager._type, ID: manager.id, IsLeader: manager.hasVIP, LastElection: manager.lastElection}
+
+	for _, virtualIP := range manager.virtualIPs {
+		status.VirtualIPs = append(status.VirtualIPs, virtualIP.IP)
+	}
+
+	if manager.raftServer != nil {
+		status.RaftLeader = string(manager.raftServer.Leader())
+
+		stats := manager.raftServer.Stats()
+		status.RaftState = stats["state"]
+
+		if numPeers, error := strconv.Atoi(stats["num_peers"]); error == nil {
+			status.NumPeers = numPeers
+		}
+	}
+
+	return status
 }
 
 func (manager *VIPManager) Start() error {
@@ -136,6 +230,9 @@ func (manager *VIPManager) Start() error {
 	}
 
 	manager.stop = make(chan bool, 1)
+	manager.raftServer = raftServer
+
+	manager.setRunning(true)
 
 	manager.deleteIP()
 
@@ -143,6 +240,8 @@ func (manager *VIPManager) Start() error {
 		for {
 			select {
 			case leader := <-raftServer.LeaderCh():
+				manager.setLastElection(time.Now())
+
 				if leader {
 					manager.addIP()
 				} else {
@@ -159,5 +258,22 @@ func (manager *VIPManager) Start() error {
 }
 
 func (manager *VIPManager) Stop() {
+	manager.setRunning(false)
+
 	close(manager.stop)
 }
+
+func (manager *VIPManager) setRunning(running bool) {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	manager.running = running
+}
+
+// IsRunning reports whether the raft-backed vip manager has been started and not yet stopped
+func (manager *VIPManager) IsRunning() bool {
+	manager.stateLock.Lock()
+	defer manager.stateLock.Unlock()
+
+	return manager.running
+}
@@ -9,7 +9,7 @@ const VERSION_RUNC = "1.0.0-rc5"
 const VERSION_CRICTL = "1.11.1"
 const VERSION_GOBETWEEN = "0.6.0"
 const VERSION_HELM = "2.9.1"
-const VERSION_ARK = "gcr.io/heptio-images/ark:v0.9.6"
+const VERSION_VELERO = "gcr.io/heptio-images/velero:v0.11.0"
 const VERSION_MINIO_SERVER = "docker.io/minio/minio:RELEASE.2018-08-18T03-49-57Z"
 const VERSION_MINIO_CLIENT = "docker.io/minio/mc:RELEASE.2018-08-18T02-13-04Z"
 const VERSION_PAUSE = "k8s.gcr.io/pause:3.1"
@@ -20,10 +20,17 @@ const VERSION_ELASTICSEARCH_OPERATOR = "docker.io/upmcenterprises/elasticsearch-
 const VERSION_KIBANA = "docker.elastic.co/kibana/kibana-oss:6.1.3"
 const VERSION_CEREBRO = "docker.io/upmcenterprises/cerebro:0.6.8"
 const VERSION_FLUENT_BIT = "docker.io/fluent/fluent-bit:0.13.0"
+const VERSION_LOKI = "docker.io/grafana/loki:1.0.0"
+const VERSION_PROMTAIL = "docker.io/grafana/promtail:1.0.0"
 const VERSION_CALICO_TYPHA = "quay.io/calico/typha:v0.7.4"
 const VERSION_CALICO_NODE = "quay.io/calico/node:v3.1.3"
 const VERSION_CALICO_CNI = "quay.io/calico/cni:v3.1.3"
+const VERSION_CILIUM = "docker.io/cilium/cilium:v1.4.0"
+const VERSION_WEAVE_NET = "docker.io/weaveworks/weave-kube:2.6.0"
+const VERSION_WEAVE_NPC = "docker.io/weaveworks/weave-npc:2.6.0"
 const VERSION_RBD_PROVISIONER = "quay.io/external_storage/rbd-provisioner:v2.1.1-k8s1.11"
+const VERSION_NFS_SUBDIR_PROVISIONER = "quay.io/external_storage/nfs-client-provisioner:v3.1.0-k8s1.11"
+const VERSION_LOCAL_PATH_PROVISIONER = "rancher/local-path-provisioner:v0.0.11"
 const VERSION_CEPH = "docker.io/ceph/daemon:v3.0.7-stable-3.0-mimic-centos-7-x86_64"
 const VERSION_HEAPSTER = "k8s.gcr.io/heapster:v1.3.0"
 const VERSION_ADDON_RESIZER = "k8s.gcr.io/addon-resizer:1.7"
@@ -31,6 +38,7 @@ const VERSION_KUBERNETES_DASHBOARD = "k8s.gcr.io/kubernetes-dashboard-amd64:v1.1
 const VERSION_CERT_MANAGER_CONTROLLER = "quay.io/jetstack/cert-manager-controller:v0.4.1"
 const VERSION_NGINX_INGRESS_DEFAULT_BACKEND = "k8s.gcr.io/defaultbackend:1.4"
 const VERSION_NGINX_INGRESS_CONTROLLER = "quay.io/kubernetes-ingress-controller/nginx-ingress-controller:0.18.0"
+const VERSION_TRAEFIK = "traefik:1.7.26"
 const VERSION_METRICS_SERVER = "gcr.io/google_containers/metrics-server-amd64:v0.2.1"
 const VERSION_PROMETHEUS_OPERATOR = "quay.io/coreos/prometheus-operator:v0.20.0"
 const VERSION_PROMETHEUS_CONFIG_RELOADER = "quay.io/coreos/prometheus-config-reloader:v0.20.0"
@@ -48,17 +56,100 @@ const VERSION_CSI_CEPH_RBD_PLUGIN = "quay.io/cephcsi/rbdplugin:v0.3.0"
 const VERSION_CSI_CEPH_FS_PLUGIN = "quay.io/cephcsi/cephfsplugin:v0.3.0"
 const VERSION_MYSQL = "docker.io/library/mysql:5.6"
 const VERSION_WORDPRESS = "docker.io/library/wordpress:4.8-apache"
+const VERSION_METALLB_CONTROLLER = "docker.io/metallb/controller:v0.7.3"
+const VERSION_METALLB_SPEAKER = "docker.io/metallb/speaker:v0.7.3"
+
+// Checksums holds the expected SHA256 of each downloaded artifact, keyed by the artifact name used when pulling
+// it (e.g. "etcd", "kubernetes", "helm"). It has to be kept in sync with the version constants above whenever one
+// of them is bumped. Artifacts with no entry here are downloaded without verification
+var Checksums = map[string]string{}
 
 // Settings
 const PROJECT_TITLE = "Kubernetes - The Easier Way"
 const CLUSTER_NAME = "k8s-tew"
 const RSA_SIZE = 2048
+const CERTIFICATE_ALGORITHM_RSA = "rsa"
+const CERTIFICATE_ALGORITHM_ECDSA = "ecdsa"
+
+var SupportedCertificateAlgorithms = map[string]bool{
+	CERTIFICATE_ALGORITHM_RSA:   true,
+	CERTIFICATE_ALGORITHM_ECDSA: true,
+}
+
+const ECDSA_CURVE_P224 = "P224"
+const ECDSA_CURVE_P256 = "P256"
+const ECDSA_CURVE_P384 = "P384"
+const ECDSA_CURVE_P521 = "P521"
+
+var SupportedECDSACurves = map[string]bool{
+	ECDSA_CURVE_P224: true,
+	ECDSA_CURVE_P256: true,
+	ECDSA_CURVE_P384: true,
+	ECDSA_CURVE_P521: true,
+}
+
 const CA_VALIDITY_PERIOD = 20
 const CLIENT_VALIDITY_PERIOD = 15
+const IMAGE_GC_HIGH_THRESHOLD_PERCENT = 85
+const IMAGE_GC_LOW_THRESHOLD_PERCENT = 80
+const IMAGE_PULL_PROGRESS_DEADLINE = "2m"
+const RETRY_INITIAL_INTERVAL_SECONDS = 1
+const RETRY_MAX_INTERVAL_SECONDS = 30
+const AUDIT_LOG_MAX_AGE = 30
+const AUDIT_LOG_MAX_BACKUP = 3
+const AUDIT_LOG_MAX_SIZE = 100
+const NODE_CIDR_MASK_SIZE = 24
+const MAX_PODS = 110
 const BASE_DIRECTORY = "assets"
 const CLUSTER_DOMAIN = "cluster.local"
 const CLUSTER_IP_RANGE = "10.32.0.0/24"
 const CALICO_TYPHA_IP = "10.32.0.5"
+const CNI_CALICO = "calico"
+const CNI_CILIUM = "cilium"
+const CNI_WEAVE = "weave"
+
+var SupportedCNIs = map[string]bool{
+	CNI_CALICO: true,
+	CNI_CILIUM: true,
+	CNI_WEAVE:  true,
+}
+
+const INGRESS_CONTROLLER_NGINX = "nginx"
+const INGRESS_CONTROLLER_TRAEFIK = "traefik"
+
+var SupportedIngressControllers = map[string]bool{
+	INGRESS_CONTROLLER_NGINX:   true,
+	INGRESS_CONTROLLER_TRAEFIK: true,
+}
+
+const ENCRYPTION_PROVIDER_AESCBC = "aescbc"
+const ENCRYPTION_PROVIDER_AESGCM = "aesgcm"
+const ENCRYPTION_PROVIDER_SECRETBOX = "secretbox"
+
+var SupportedEncryptionProviders = map[string]bool{
+	ENCRYPTION_PROVIDER_AESCBC:    true,
+	ENCRYPTION_PROVIDER_AESGCM:    true,
+	ENCRYPTION_PROVIDER_SECRETBOX: true,
+}
+
+const STORAGE_BACKEND_CEPH = "ceph"
+const STORAGE_BACKEND_NFS = "nfs"
+const STORAGE_BACKEND_LOCAL_PATH = "local-path"
+
+const LOGGING_BACKEND_EFK = "efk"
+const LOGGING_BACKEND_LOKI = "loki"
+
+var SupportedLoggingBackends = map[string]bool{
+	LOGGING_BACKEND_EFK:  true,
+	LOGGING_BACKEND_LOKI: true,
+}
+
+var SupportedStorageBackends = map[string]bool{
+	STORAGE_BACKEND_CEPH:       true,
+	STORAGE_BACKEND_NFS:        true,
+	STORAGE_BACKEND_LOCAL_PATH: true,
+}
+
 const CLUSTER_DNS_IP = "10.32.0.10"
 const CLUSTER_CIDR = "10.200.0.0/16"
 const RESOLV_CONF = "/etc/resolv.conf"
@@ -68,6 +159,11 @@ const EMAIL = "k8s-tew@gmail.com"
 const DEPLOYMENT_DIRECTORY = "/"
 const INGRESS_DOMAIN = "k8s-tew.net"
 const INGRESS_SUBDOMAIN_WORDPRESS = "wordpress"
+const APPLY_MODE_CLIENT_SIDE = "client-side"
+const APPLY_MODE_SERVER_SIDE = "server-side"
+const RESTART_POLICY_NEVER = "never"
+const RESTART_POLICY_ON_FAILURE = "on-failure"
+const RESTART_POLICY_ALWAYS = "always"
 
 // Ports
 const PORT_VIP_RAFT_CONTROLLER uint16 = 16277
@@ -79,30 +175,34 @@ const PORT_CEPH_MANAGER uint16 = 30700
 const PORT_CEPH_RADOS_GATEWAY uint16 = 30750
 const PORT_MINIO uint16 = 30800
 const PORT_GRAFANA uint16 = 30900
+const GRAFANA_ADMIN_USERNAME = "admin"
+const PROMETHEUS_RETENTION = "24h"
+const PROMETHEUS_STORAGE_SIZE = "10Gi"
+const ALERTMANAGER_STORAGE_SIZE = "2Gi"
 const PORT_KIBANA uint16 = 30980
 const PORT_CEREBRO uint16 = 30990
 const PORT_WORDPRESS uint16 = 30100
 
 // URLs
 
-const K8S_BASE_NAME = "kubernetes-server-linux-amd64"
+const K8S_BASE_NAME = "kubernetes-server-linux-{{.Arch}}"
 const K8S_DOWNLOAD_URL = "https://storage.googleapis.com/kubernetes-release/release/v{{.Versions.K8S}}/{{.Filename}}.tar.gz"
-const ETCD_BASE_NAME = "etcd-v{{.Versions.Etcd}}-linux-amd64"
+const ETCD_BASE_NAME = "etcd-v{{.Versions.Etcd}}-linux-{{.Arch}}"
 const ETCD_DOWNLOAD_URL = "https://github.com/coreos/etcd/releases/download/v{{.Versions.Etcd}}/{{.Filename}}.tar.gz"
-const FLANNELD_DOWNLOAD_URL = "https://github.com/coreos/flannel/releases/download/v{{.Versions.Flanneld}}/flanneld-amd64"
-const CNI_BASE_NAME = "cni-plugins-amd64-v{{.Versions.CNI}}"
+const FLANNELD_DOWNLOAD_URL = "https://github.com/coreos/flannel/releases/download/v{{.Versions.Flanneld}}/flanneld-{{.Arch}}"
+const CNI_BASE_NAME = "cni-plugins-{{.Arch}}-v{{.Versions.CNI}}"
 const CNI_DOWNLOAD_URL = "https://github.com/containernetworking/plugins/releases/download/v{{.Versions.CNI}}/{{.Filename}}.tgz"
-const CONTAINERD_BASE_NAME = "containerd-{{.Versions.Containerd}}.linux-amd64"
+const CONTAINERD_BASE_NAME = "containerd-{{.Versions.Containerd}}.linux-{{.Arch}}"
 const CONTAINERD_DOWNLOAD_URL = "https://github.com/containerd/containerd/releases/download/v{{.Versions.Containerd}}/{{.Filename}}.tar.gz"
-const RUNC_DOWNLOAD_URL = "https://github.com/opencontainers/runc/releases/download/v{{.Versions.Runc}}/runc.amd64"
-const CRICTL_BASE_NAME = "crictl-v{{.Versions.CriCtl}}-linux-amd64"
+const RUNC_DOWNLOAD_URL = "https://github.com/opencontainers/runc/releases/download/v{{.Versions.Runc}}/runc.{{.Arch}}"
+const CRICTL_BASE_NAME = "crictl-v{{.Versions.CriCtl}}-linux-{{.Arch}}"
 const CRICTL_DOWNLOAD_URL = "https://github.com/kubernetes-incubator/cri-tools/releases/download/v{{.Versions.CriCtl}}/{{.Filename}}.tar.gz"
-const GOBETWEEN_BASE_NAME = "gobetween_{{.Versions.Gobetween}}_linux_amd64"
+const GOBETWEEN_BASE_NAME = "gobetween_{{.Versions.Gobetween}}_linux_{{.Arch}}"
 const GOBETWEEN_DOWNLOAD_URL = "https://github.com/yyyar/gobetween/releases/download/{{.Versions.Gobetween}}/{{.Filename}}.tar.gz"
-const HELM_BASE_NAME = "helm-v{{.Versions.Helm}}-linux-amd64"
+const HELM_BASE_NAME = "helm-v{{.Versions.Helm}}-linux-{{.Arch}}"
 const HELM_DOWNLOAD_URL = "https://storage.googleapis.com/kubernetes-helm/{{.Filename}}.tar.gz"
-const ARK_BASE_NAME = "ark-{{.Versions.Ark | image_tag}}-linux-amd64"
-const ARK_DOWNLOAD_URL = "https://github.com/heptio/ark/releases/download/{{.Versions.Ark | image_tag}}/{{.Filename}}.tar.gz"
+const VELERO_BASE_NAME = "velero-{{.Versions.Velero | image_tag}}-linux-{{.Arch}}"
+const VELERO_DOWNLOAD_URL = "https://github.com/heptio/velero/releases/download/{{.Versions.Velero | image_tag}}/{{.Filename}}.tar.gz"
 
 // Config
 const CONFIG_FILENAME = "config.yaml"
@@ -113,6 +213,26 @@ const NODE_CONTROLLER = "controller"
 const NODE_WORKER = "worker"
 const NODE_STORAGE = "storage"
 
+// Taints
+const CONTROLLER_ONLY_TAINT_KEY = "node-role.kubernetes.io/master"
+const CONTROLLER_ONLY_TAINT_VALUE = "true"
+const CONTROLLER_ONLY_TAINT_EFFECT = "NoSchedule"
+
+var SupportedTaintEffects = map[string]bool{
+	"NoSchedule":       true,
+	"PreferNoSchedule": true,
+	"NoExecute":        true,
+}
+
+// Architectures
+const DEFAULT_ARCH = "amd64"
+const ARCH_ARM64 = "arm64"
+
+var SupportedArchs = map[string]bool{
+	DEFAULT_ARCH: true,
+	ARCH_ARM64:   true,
+}
+
 // Features
 const FEATURE_STORAGE = "storage"
 const FEATURE_MONITORING = "monitoring"
@@ -121,12 +241,18 @@ const FEATURE_BACKUP = "backup"
 const FEATURE_SHOWCASE = "showcase"
 const FEATURE_INGRESS = "ingress"
 const FEATURE_PACKAGING = "packaging"
+const FEATURE_METALLB = "metallb"
 
 // OS
 const OS_UBUNTU = "ubuntu"
 const OS_UBUNTU_18_04 = "ubuntu/18.04"
 const OS_CENTOS = "centos"
 const OS_CENTOS_7_5 = "centos/7.5"
+const OS_CENTOS_8 = "centos/8"
+const OS_ROCKY = "rocky"
+const OS_ROCKY_8 = "rocky/8"
+const OS_ALMA = "alma"
+const OS_ALMA_8 = "alma/8"
 
 // Sub-Directories
 const TEMPORARY_SUBDIRECTORY = "tmp"
@@ -160,16 +286,18 @@ const CEPH_BOOTSTRAP_MDS_SUBDIRECTORY = "bootstrap-mds"
 const CEPH_BOOTSTRAP_OSD_SUBDIRECTORY = "bootstrap-osd"
 const CEPH_BOOTSTRAP_RBD_SUBDIRECTORY = "bootstrap-rbd"
 const CEPH_BOOTSTRAP_RGW_SUBDIRECTORY = "bootstrap-rgw"
-const ARK_SUBDIRECTORY = "ark"
+const VELERO_SUBDIRECTORY = "velero"
 const BASH_COMPLETION_SUBDIRECTORY = "bash_completion.d"
 const HOST_SUBDIRECTORY = "host"
 const PLUGINS_SUBDIRECTORY = "plugins"
 const CSI_CEPHFS_PLUGIN = "csi-cephfsplugin"
 const CSI_RBD_PLUGIN = "csi-rbdplugin"
+const ADDITIONAL_CA_SUBDIRECTORY = "additional-ca"
 
 // Directories
 const CONFIG_DIRECTORY = "config"
 const CERTIFICATES_DIRECTORY = "certificates"
+const ADDITIONAL_CA_DIRECTORY = "additional-ca-certificates"
 const CNI_CONFIG_DIRECTORY = "cni-config"
 const CRI_CONFIG_DIRECTORY = "cri-config"
 const K8S_SECURITY_CONFIG_DIRECTORY = "security-config"
@@ -183,6 +311,7 @@ const CNI_BINARIES_DIRECTORY = "cni-binaries"
 const CRI_BINARIES_DIRECTORY = "cri-binaries"
 const DYNAMIC_DATA_DIRECTORY = "dynamic-data"
 const ETCD_DATA_DIRECTORY = "etcd-data"
+const ETCD_SNAPSHOTS_DIRECTORY = "etcd-snapshots"
 const CONTAINERD_DATA_DIRECTORY = "containerd-data"
 const LOGGING_DIRECTORY = "logging"
 const SERVICE_DIRECTORY = "service"
@@ -203,7 +332,7 @@ const CEPH_BOOTSTRAP_MDS_DIRECTORY = "bootstrap-mds"
 const CEPH_BOOTSTRAP_OSD_DIRECTORY = "bootstrap-osd"
 const CEPH_BOOTSTRAP_RBD_DIRECTORY = "bootstrap-rbd"
 const CEPH_BOOTSTRAP_RGW_DIRECTORY = "bootstrap-rgw"
-const ARK_BINARIES_DIRECTORY = "ark"
+const VELERO_BINARIES_DIRECTORY = "velero"
 const BASH_COMPLETION_DIRECTORY = "bash-completion"
 const HOST_BINARIES_DIRECTORY = "host-binaries"
 const CEPH_RBD_PLUGIN_DIRECTORY = "ceph-rbd-plugin"
@@ -238,8 +367,29 @@ const KUBE_SCHEDULER_BINARY = "kube-scheduler"
 // Gobeween Binary
 const GOBETWEEN_BINARY = "gobetween"
 
-// Ark Binaries
-const ARK_BINARY = "ark"
+// Velero Binaries
+const VELERO_BINARY = "velero"
+
+// ArchBinaries lists the downloaded binaries whose local cache is partitioned by CPU architecture. Everything else
+// (certificates, kubeconfigs, manifests, k8s-tew itself) is architecture-independent and stays at its plain path
+var ArchBinaries = map[string]bool{
+	HELM_BINARY:                    true,
+	CONTAINERD_BINARY:              true,
+	CONTAINERD_SHIM_BINARY:         true,
+	CTR_BINARY:                     true,
+	RUNC_BINARY:                    true,
+	CRICTL_BINARY:                  true,
+	ETCD_BINARY:                    true,
+	ETCDCTL_BINARY:                 true,
+	KUBECTL_BINARY:                 true,
+	KUBE_APISERVER_BINARY:          true,
+	KUBE_CONTROLLER_MANAGER_BINARY: true,
+	KUBELET_BINARY:                 true,
+	KUBE_PROXY_BINARY:              true,
+	KUBE_SCHEDULER_BINARY:          true,
+	GOBETWEEN_BINARY:               true,
+	VELERO_BINARY:                  true,
+}
 
 // Certificates
 const CA_PEM = "ca.pem"
@@ -274,6 +424,9 @@ const KUBELET_KUBECONFIG = "kubelet-{{.Name}}.kubeconfig"
 
 // Security
 const ENCRYPTION_CONFIG = "encryption-config.yaml"
+const TRACING_CONFIG = "tracing-config.yaml"
+const AUDIT_POLICY = "audit-policy.yaml"
+const OIDC_CA_PEM = "oidc-ca.pem"
 
 // Containerd
 const CONTAINERD_CONFIG = "config-{{.Name}}.toml"
@@ -287,13 +440,18 @@ const K8S_KUBE_SCHEDULER_CONFIG = "kube-scheduler-config.yaml"
 const K8S_KUBELET_CONFIG = "kubelet-{{.Name}}-config.yaml"
 const K8S_COREDNS_SETUP = "coredns-setup.yaml"
 const K8S_CALICO_SETUP = "calico-setup.yaml"
+const K8S_CILIUM_SETUP = "cilium-setup.yaml"
+const K8S_WEAVE_SETUP = "weave-setup.yaml"
 const K8S_ELASTICSEARCH_OPERATOR_SETUP = "elasticsearch-operator-setup.yaml"
 const K8S_EFK_SETUP = "efk-setup.yaml"
-const K8S_ARK_SETUP = "ark-setup.yaml"
+const K8S_LOKI_SETUP = "loki-setup.yaml"
+const K8S_VELERO_SETUP = "velero-setup.yaml"
 const K8S_HEAPSTER_SETUP = "heapster-setup.yaml"
 const K8S_KUBERNETES_DASHBOARD_SETUP = "kubernetes-dashboard-setup.yaml"
 const K8S_CERT_MANAGER_SETUP = "cert-manager-setup.yaml"
 const K8S_NGINX_INGRESS_SETUP = "nginx-ingress-setup.yaml"
+const K8S_METALLB_SETUP = "metallb-setup.yaml"
+const K8S_TRAEFIK_SETUP = "traefik-setup.yaml"
 const K8S_METRICS_SERVER_SETUP = "metrics-server-setup.yaml"
 const K8S_PROMETHEUS_OPERATOR_SETUP = "prometheus-operator-setup.yaml"
 const K8S_KUBE_PROMETHEUS_SETUP = "kube-prometheus-setup.yaml"
@@ -308,6 +466,7 @@ const K8S_KUBE_PROMETHEUS_KUBERNETES_RESOURCE_REQUESTS_DASHBOARD_SETUP = "kube-p
 const K8S_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_HEALTH_DASHBOARD_SETUP = "kube-prometheus-kubernetes-cluster-health-dashboard-setup.yaml"
 const K8S_KUBE_PROMETHEUS_NODES_DASHBOARD_SETUP = "kube-prometheus-nodes-dashboard-setup.yaml"
 const WORDPRESS_SETUP = "wordpress-setup.yaml"
+const K8S_REGISTRY_CREDENTIALS_SETUP = "registry-credentials-setup.yaml"
 
 // Gobetween Config
 const GOBETWEEN_CONFIG = "config.toml"
@@ -319,7 +478,7 @@ const K8S_TEW_PROFILE = "k8s-tew.sh"
 const BASH_COMPLETION_K8S_TEW = "k8s-tew.bash-completion"
 const BASH_COMPLETION_KUBECTL = "kubectl.bash-completion"
 const BASH_COMPLETION_HELM = "helm.bash-completion"
-const BASH_COMPLETION_ARK = "ark.bash-completion"
+const BASH_COMPLETION_VELERO = "velero.bash-completion"
 const BASH_COMPLETION_CRICTL = "crictl.bash-completion"
 
 // Logging
@@ -347,6 +506,12 @@ const CEPH_SECRETS = "ceph-secrets.yaml"
 const CEPH_SETUP = "ceph-setup.yaml"
 const CEPH_CSI = "ceph-csi.yaml"
 
+// NFS
+const NFS_SETUP = "nfs-setup.yaml"
+
+// Local Path Provisioner
+const LOCAL_PATH_SETUP = "local-path-setup.yaml"
+
 // Cluster Issuer
 const LETSENCRYPT_CLUSTER_ISSUER = "letsencrypt-cluster-issuer.yaml"
 
@@ -374,7 +539,8 @@ const TEMPLATE_ENVIRONMENT = "system/environment.sh"
 const TEMPLATE_GOBETWEEN_TOML = "k8s/lb/gobetween.toml"
 const TEMPLATE_KUBE_SCHEDULER_CONFIGURATION = "k8s/kube-scheduler-configuration.yaml"
 const TEMPLATE_KUBELET_CONFIGURATION = "k8s/kubelet-configuration.yaml"
-const TEMPLATE_ENCRYPTION_CONFIG = "k8s/encryption-config.yaml"
+const TEMPLATE_TRACING_CONFIG = "k8s/tracing-config.yaml"
+const TEMPLATE_AUDIT_POLICY = "k8s/audit-policy.yaml"
 const TEMPLATE_KUBECONFIG = "k8s/kubeconfig.yaml"
 const TEMPLATE_SERVICE_ACCOUNT = "k8s/service-account.yaml"
 const TEMPLATE_KUBELET_SETUP = "k8s/setup/kubelet-setup.yaml"
@@ -385,16 +551,23 @@ const TEMPLATE_CEPH_CONFIG = "ceph/ceph.conf"
 const TEMPLATE_CEPH_SECRETS = "k8s/setup/storage/ceph-secrets.yaml"
 const TEMPLATE_CEPH_SETUP = "k8s/setup/storage/ceph-setup.yaml"
 const TEMPLATE_CEPH_CSI = "k8s/setup/storage/ceph-csi.yaml"
+const TEMPLATE_NFS_SETUP = "k8s/setup/storage/nfs-setup.yaml"
+const TEMPLATE_LOCAL_PATH_SETUP = "k8s/setup/storage/local-path-setup.yaml"
 const TEMPLATE_LETSENCRYPT_CLUSTER_ISSUER_SETUP = "k8s/setup/ingress/letsencrypt-cluster-issuer.yaml"
 const TEMPLATE_COREDNS_SETUP = "k8s/setup/dns/coredns.yaml"
 const TEMPLATE_CALICO_SETUP = "k8s/setup/networking/calico.yaml"
+const TEMPLATE_CILIUM_SETUP = "k8s/setup/networking/cilium.yaml"
+const TEMPLATE_WEAVE_SETUP = "k8s/setup/networking/weave.yaml"
 const TEMPLATE_ELASTICSEARCH_OPERATOR_SETUP = "k8s/setup/logging/elasticsearch-operator.yaml"
 const TEMPLATE_EFK_SETUP = "k8s/setup/logging/efk.yaml"
-const TEMPLATE_ARK_SETUP = "k8s/setup/backup/ark.yaml"
+const TEMPLATE_LOKI_SETUP = "k8s/setup/logging/loki.yaml"
+const TEMPLATE_VELERO_SETUP = "k8s/setup/backup/velero.yaml"
 const TEMPLATE_HEAPSTER_SETUP = "k8s/setup/monitoring/heapster.yaml"
 const TEMPLATE_KUBERNETES_DASHBOARD_SETUP = "k8s/setup/management/kubernetes-dashboard.yaml"
 const TEMPLATE_CERT_MANAGER_SETUP = "k8s/setup/networking/cert-manager.yaml"
 const TEMPLATE_NGINX_INGRESS_SETUP = "k8s/setup/networking/nginx-ingress.yaml"
+const TEMPLATE_TRAEFIK_SETUP = "k8s/setup/networking/traefik.yaml"
+const TEMPLATE_METALLB_SETUP = "k8s/setup/networking/metallb.yaml"
 const TEMPLATE_METRICS_SERVER_SETUP = "k8s/setup/monitoring/metrics-server.yaml"
 const TEMPLATE_PROMETHEUS_OPERATOR_SETUP = "k8s/setup/monitoring/prometheus-operator.yaml"
 const TEMPLATE_KUBE_PROMETHEUS_SETUP = "k8s/setup/monitoring/kube-prometheus.yaml"
@@ -409,3 +582,4 @@ const TEMPLATE_KUBE_PROMETHEUS_KUBERNETES_RESOURCE_REQUESTS_DASHBOARD_SETUP = "k
 const TEMPLATE_KUBE_PROMETHEUS_KUBERNETES_CLUSTER_HEALTH_DASHBOARD_SETUP = "k8s/setup/monitoring/kube-prometheus-kubernetes-cluster-health-dashboard.yaml"
 const TEMPLATE_KUBE_PROMETHEUS_NODES_DASHBOARD_SETUP = "k8s/setup/monitoring/kube-prometheus-nodes-dashboard.yaml"
 const TEMPLATE_WORDPRESS_SETUP = "k8s/setup/miscellaneous/wordpress.yaml"
+const TEMPLATE_REGISTRY_CREDENTIALS_SETUP = "k8s/setup/miscellaneous/registry-credentials.yaml"
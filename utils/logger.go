@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"path"
 
 	log "github.com/sirupsen/logrus"
@@ -8,17 +9,49 @@ import (
 
 var debug bool
 
+// SetDebug forces debug level logging when enabled, overriding whatever SetLogLevel set. It leaves the level
+// untouched when disabled, so a --log-level flag set without --debug is not reset back to info
 func SetDebug(_debug bool) {
 	debug = _debug
 
-	// Turn logging debug info on/off
 	if debug {
 		log.SetLevel(log.DebugLevel)
-	} else {
-		log.SetLevel(log.InfoLevel)
 	}
 }
 
+// SetLogLevel parses levelName (debug, info, warn, error, fatal or panic) and applies it as the global log level
+func SetLogLevel(levelName string) error {
+	level, error := log.ParseLevel(levelName)
+	if error != nil {
+		return fmt.Errorf("invalid log level '%s' (%s)", levelName, error.Error())
+	}
+
+	log.SetLevel(level)
+
+	return nil
+}
+
+// SetLogFormat switches the global log formatter between the default human-readable text output and JSON, so the
+// fields already attached to every log entry (node, name, command, error, ...) become queryable once shipped into
+// a log aggregator. JSON format also suppresses the interactive progress spinner, the same way SetProgressJSON
+// does, since both would otherwise interleave on the same terminal
+func SetLogFormat(format string) error {
+	switch format {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+
+		SupressProgress(true)
+
+	default:
+		return fmt.Errorf("invalid log format '%s', expected 'text' or 'json'", format)
+	}
+
+	return nil
+}
+
 type logrusHook struct{}
 
 func (hook logrusHook) Fire(entry *log.Entry) error {
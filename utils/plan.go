@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// PlannedFileChange describes what ApplyTemplateAndSave would do to a single file while dry-running, instead of
+// actually doing it
+type PlannedFileChange struct {
+	Filename string `json:"filename"`
+	Action   string `json:"action"` // create, update, unchanged or skip
+	OldHash  string `json:"old-hash,omitempty"`
+	NewHash  string `json:"new-hash,omitempty"`
+}
+
+// PlannedCommand describes a command that would run on a node while dry-running, instead of actually running it
+type PlannedCommand struct {
+	Node    string `json:"node"`
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+var dryRun bool
+var plannedFileChanges []PlannedFileChange
+var plannedCommands []PlannedCommand
+
+// SetDryRun turns dry-run mode on or off and resets whatever was recorded so far
+func SetDryRun(value bool) {
+	dryRun = value
+	plannedFileChanges = nil
+	plannedCommands = nil
+}
+
+func IsDryRun() bool {
+	return dryRun
+}
+
+func RecordPlannedFileChange(filename, action, oldHash, newHash string) {
+	plannedFileChanges = append(plannedFileChanges, PlannedFileChange{Filename: filename, Action: action, OldHash: oldHash, NewHash: newHash})
+}
+
+func GetPlannedFileChanges() []PlannedFileChange {
+	return plannedFileChanges
+}
+
+func RecordPlannedCommand(node, name, command string) {
+	plannedCommands = append(plannedCommands, PlannedCommand{Node: node, Name: name, Command: command})
+}
+
+func GetPlannedCommands() []PlannedCommand {
+	return plannedCommands
+}
+
+func GetSHA256(content []byte) string {
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:])
+}
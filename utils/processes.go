@@ -143,19 +143,29 @@ func (children *Children) sendSignal(pid int, signal os.Signal) error {
 	return process.Signal(signal)
 }
 
-func (children *Children) Kill(killTimeout uint) {
+func (children *Children) isAlive(pid int) bool {
+	return children.sendSignal(pid, syscall.Signal(0)) == nil
+}
+
+// Kill gracefully stops all children with SIGTERM, waits up to gracePeriod for them to exit and
+// then forcefully SIGKILLs whoever is still running, logging which children had to be force-killed
+func (children *Children) Kill(gracePeriod uint) {
 	log.Info("Cleaning up children")
 
 	for _, child := range *children {
 		log.WithFields(log.Fields{"name": child.Name, "pid": child.ProcessID}).Debug("Stopping process")
 
-		_ = children.sendSignal(child.ProcessID, syscall.SIGINT)
+		_ = children.sendSignal(child.ProcessID, syscall.SIGTERM)
 	}
 
-	time.Sleep(time.Duration(killTimeout) * time.Second)
+	time.Sleep(time.Duration(gracePeriod) * time.Second)
 
 	for _, child := range *children {
-		log.WithFields(log.Fields{"name": child.Name, "pid": child.ProcessID}).Debug("Killing process")
+		if !children.isAlive(child.ProcessID) {
+			continue
+		}
+
+		log.WithFields(log.Fields{"name": child.Name, "pid": child.ProcessID}).Warn("Force-killing process")
 
 		_ = children.sendSignal(child.ProcessID, syscall.SIGKILL)
 	}
@@ -163,10 +173,10 @@ func (children *Children) Kill(killTimeout uint) {
 	log.Info("Cleaned up children")
 }
 
-func KillProcessChildren(pid int, timeout uint) {
+func KillProcessChildren(pid int, gracePeriod uint) {
 	processes := NewProcesses()
 
 	children := processes.GetAllChildrenByParent(os.Getpid())
 
-	children.Kill(timeout)
+	children.Kill(gracePeriod)
 }
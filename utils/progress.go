@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"time"
 
@@ -14,6 +17,8 @@ var _progressStep int
 var _progressShow bool
 var _mutex sync.Mutex
 var _supressProgress bool
+var _progressJSON bool
+var _progressWriter io.Writer = os.Stdout
 
 func init() {
 	_mutex = sync.Mutex{}
@@ -25,8 +30,49 @@ func SupressProgress(hide bool) {
 	_supressProgress = hide
 }
 
+// SetProgressJSON switches progress reporting from the interactive spinner to one JSON line per step, written
+// via LogProgress. CI systems without a TTY can parse these lines to tell which node/command is currently
+// running instead of a percentage that means nothing in a log file
+func SetProgressJSON(enabled bool) {
+	_progressJSON = enabled
+}
+
+// SetProgressWriter redirects the JSON progress events enabled by SetProgressJSON to writer instead of stdout
+func SetProgressWriter(writer io.Writer) {
+	_progressWriter = writer
+}
+
+// ProgressEvent is one JSON-encoded progress line emitted by LogProgress when JSON progress reporting is enabled
+type ProgressEvent struct {
+	Step    int    `json:"step"`
+	Steps   int    `json:"steps"`
+	Phase   string `json:"phase,omitempty"`
+	Node    string `json:"node,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// LogProgress emits a ProgressEvent carrying the current step/steps counters when JSON progress reporting is
+// enabled. It is a no-op otherwise, since the interactive spinner started by ShowProgress already reflects the
+// overall percentage
+func LogProgress(phase, node, message string) {
+	if !_progressJSON {
+		return
+	}
+
+	_mutex.Lock()
+	event := ProgressEvent{Step: _progressStep, Steps: _progressSteps, Phase: phase, Node: node, Message: message}
+	_mutex.Unlock()
+
+	encoded, error := json.Marshal(event)
+	if error != nil {
+		return
+	}
+
+	fmt.Fprintln(_progressWriter, string(encoded))
+}
+
 func ShowProgress() {
-	if _supressProgress {
+	if _supressProgress || _progressJSON {
 		return
 	}
 
@@ -1,12 +1,33 @@
 package utils
 
-import "sync"
+import (
+	"strings"
+	"sync"
+)
 
 type Task func() error
 type Tasks []Task
+
+// Errors aggregates every error a batch of parallel tasks returned, instead of just the first one, so a caller
+// can report every node/image/command that failed in a single run
 type Errors []error
 
-func RunParallelTasks(tasks Tasks, parallel bool) (errors Errors) {
+// Error joins every collected error's message with "; ", so Errors satisfies the error interface and a caller
+// can return the whole batch - e.g. `return errors` instead of `return errors[0]` - without losing anything
+func (errors Errors) Error() string {
+	messages := make([]string, len(errors))
+
+	for index, error := range errors {
+		messages[index] = error.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// RunParallelTasks runs tasks, serially if parallel is false, otherwise all at once unless maxConcurrency is
+// greater than 0, in which case at most maxConcurrency of them run at a time. Errors from every task are
+// collected and returned, not just the first one encountered
+func RunParallelTasks(tasks Tasks, parallel bool, maxConcurrency uint) (errors Errors) {
 	if !parallel {
 		for _, task := range tasks {
 			if error := task(); error != nil {
@@ -23,38 +44,34 @@ func RunParallelTasks(tasks Tasks, parallel bool) (errors Errors) {
 
 	waitGroup.Add(len(tasks))
 
-	errorChannel := make(chan error, 1)
-	finishedChannel := make(chan bool, 1)
+	var mutex sync.Mutex
+
+	var semaphore chan bool
+
+	if maxConcurrency > 0 {
+		semaphore = make(chan bool, maxConcurrency)
+	}
 
 	// Schedule tasks to be executed
 	for _, task := range tasks {
 		go func(_task Task) {
-			if error := _task(); error != nil {
-				errorChannel <- error
+			defer waitGroup.Done()
+
+			if semaphore != nil {
+				semaphore <- true
+
+				defer func() { <-semaphore }()
 			}
 
-			waitGroup.Done()
+			if error := _task(); error != nil {
+				mutex.Lock()
+				errors = append(errors, error)
+				mutex.Unlock()
+			}
 		}(task)
 	}
 
-	// Wait for all tasks to be done and send notification
-	go func() {
-		waitGroup.Wait()
-
-		close(finishedChannel)
-	}()
-
-	done := false
-
-	// Collect errors and wait for all tasks to be done
-	for !done {
-		select {
-		case <-finishedChannel:
-			done = true
-		case error := <-errorChannel:
-			errors = append(errors, error)
-		}
-	}
+	waitGroup.Wait()
 
 	return
 }
@@ -25,10 +25,16 @@ func GetTemplate(name string) string {
 	return content
 }
 
-func GetEmbeddedFiles(callback func(path string, readCloser io.ReadCloser) error) error {
-	embeddedBox.Walk(func(path string, file packr.File) error {
-		return callback(path, file)
+// GetEmbeddedFiles walks every embedded host binary, calling callback with its size - so callers can decide
+// whether to skip re-extracting an up to date file without having to read it first - and its content. The
+// first error returned by callback or by the walk itself aborts the walk and is returned
+func GetEmbeddedFiles(callback func(path string, size int64, readCloser io.ReadCloser) error) error {
+	return embeddedBox.Walk(func(path string, file packr.File) error {
+		info, error := file.FileInfo()
+		if error != nil {
+			return error
+		}
+
+		return callback(path, info.Size(), file)
 	})
-
-	return nil
 }
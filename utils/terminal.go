@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// IsTerminal tells whether stdout is attached to an interactive terminal, used to pick a sensible default
+// between the interactive progress spinner and JSON progress events
+func IsTerminal() bool {
+	return isatty.IsTerminal(os.Stdout.Fd())
+}
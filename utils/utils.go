@@ -18,6 +18,7 @@ import (
 
 	oslib "github.com/redpois0n/goslib"
 	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
 )
 
 const COMMAND_TIMEOUT = 60 // In seconds
@@ -64,8 +65,26 @@ func FileExists(filename string) bool {
 	return !os.IsNotExist(error)
 }
 
-func RunCommandWithOutput(command string) (string, error) {
-	_context, cancel := context.WithTimeout(context.Background(), COMMAND_TIMEOUT*time.Second)
+func CopyFile(from, to string) error {
+	content, error := ioutil.ReadFile(from)
+
+	if error != nil {
+		return error
+	}
+
+	directoryName := filepath.Dir(to)
+
+	if error := CreateDirectoryIfMissing(directoryName); error != nil {
+		return error
+	}
+
+	return ioutil.WriteFile(to, content, 0644)
+}
+
+// runCommandWithOutputTimeout runs command in a shell, killing it if ctx is cancelled or timeout elapses,
+// whichever comes first
+func runCommandWithOutputTimeout(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	_context, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	log.WithFields(log.Fields{"command": command}).Debug("Command started")
@@ -84,12 +103,36 @@ func RunCommandWithOutput(command string) (string, error) {
 	return string(output), nil
 }
 
-func RunCommand(command string) error {
-	_, error := RunCommandWithOutput(command)
+// RunCommandWithOutputContext runs command in a shell, killing it if ctx is cancelled or COMMAND_TIMEOUT elapses,
+// whichever comes first
+func RunCommandWithOutputContext(ctx context.Context, command string) (string, error) {
+	return runCommandWithOutputTimeout(ctx, command, COMMAND_TIMEOUT*time.Second)
+}
+
+func RunCommandWithOutput(command string) (string, error) {
+	return RunCommandWithOutputContext(context.Background(), command)
+}
+
+// RunCommandContext runs command in a shell, killing it if ctx is cancelled or COMMAND_TIMEOUT elapses, whichever
+// comes first
+func RunCommandContext(ctx context.Context, command string) error {
+	_, error := RunCommandWithOutputContext(ctx, command)
+
+	return error
+}
+
+// RunCommandTimeoutContext runs command in a shell, killing it if ctx is cancelled or timeout elapses, whichever
+// comes first - unlike RunCommandContext, timeout is caller-supplied instead of the fixed COMMAND_TIMEOUT
+func RunCommandTimeoutContext(ctx context.Context, command string, timeout time.Duration) error {
+	_, error := runCommandWithOutputTimeout(ctx, command, timeout)
 
 	return error
 }
 
+func RunCommand(command string) error {
+	return RunCommandContext(context.Background(), command)
+}
+
 func RunSSHClient(ip string) {
 	command := fmt.Sprintf("ssh -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no -t ubuntu@%s \"sudo su -\"", ip)
 
@@ -190,10 +233,21 @@ func ApplyTemplate(label, content string, data interface{}, alternativeDelimiter
 }
 
 func ApplyTemplateAndSave(label, templateName string, data interface{}, filename string, force bool, extendedDelimiters bool) error {
+	return ApplyTemplateAndSaveMerged(label, templateName, data, filename, force, extendedDelimiters, nil)
+}
+
+// ApplyTemplateAndSaveMerged behaves like ApplyTemplateAndSave, but when overrides is non-empty the rendered YAML
+// is parsed and deep-merged with overrides - override values win, and only the branches they touch are replaced, so
+// fields left unspecified keep the value the template rendered
+func ApplyTemplateAndSaveMerged(label, templateName string, data interface{}, filename string, force bool, extendedDelimiters bool, overrides map[string]interface{}) error {
 	content := GetTemplate(templateName)
 
 	if FileExists(filename) && !force {
-		LogFilename("Skipped", filename)
+		if IsDryRun() {
+			RecordPlannedFileChange(filename, "skip", "", "")
+		} else {
+			LogFilename("Skipped", filename)
+		}
 
 		return nil
 	}
@@ -203,6 +257,17 @@ func ApplyTemplateAndSave(label, templateName string, data interface{}, filename
 		return error
 	}
 
+	if len(overrides) > 0 {
+		content, error = mergeYAMLOverrides(label, content, overrides)
+		if error != nil {
+			return error
+		}
+	}
+
+	if IsDryRun() {
+		return RecordPlannedContentChange(filename, content)
+	}
+
 	if error := ioutil.WriteFile(filename, []byte(content), 0644); error != nil {
 		return fmt.Errorf("Could not write to '%s' (%s)", filename, error.Error())
 	}
@@ -212,6 +277,114 @@ func ApplyTemplateAndSave(label, templateName string, data interface{}, filename
 	return nil
 }
 
+// mergeYAMLOverrides parses content as YAML and deep-merges overrides into it, returning the re-marshalled result
+func mergeYAMLOverrides(label, content string, overrides map[string]interface{}) (string, error) {
+	base := map[string]interface{}{}
+
+	if error := yaml.Unmarshal([]byte(content), &base); error != nil {
+		return "", fmt.Errorf("could not parse '%s' for merging overrides (%s)", label, error.Error())
+	}
+
+	merged, error := yaml.Marshal(deepMergeYAML(base, overrides))
+	if error != nil {
+		return "", fmt.Errorf("could not marshal merged '%s' (%s)", label, error.Error())
+	}
+
+	return string(merged), nil
+}
+
+// deepMergeYAML merges override into base and returns base. Values in override win; when both the base and the
+// override value at a key are maps, they are merged recursively instead of override replacing the whole branch
+func deepMergeYAML(base, override map[string]interface{}) map[string]interface{} {
+	for key, overrideValue := range override {
+		baseValue, exists := base[key]
+		if !exists {
+			base[key] = overrideValue
+
+			continue
+		}
+
+		baseMap, baseIsMap := asStringMap(baseValue)
+		overrideMap, overrideIsMap := asStringMap(overrideValue)
+
+		if baseIsMap && overrideIsMap {
+			base[key] = deepMergeYAML(baseMap, overrideMap)
+		} else {
+			base[key] = overrideValue
+		}
+	}
+
+	return base
+}
+
+// asStringMap normalizes either a map[string]interface{} or the map[interface{}]interface{} yaml.v2 produces for
+// nested maps into a map[string]interface{}
+func asStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return typed, true
+
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(typed))
+
+		for key, value := range typed {
+			result[fmt.Sprintf("%v", key)] = value
+		}
+
+		return result, true
+
+	default:
+		return nil, false
+	}
+}
+
+// RecordPlannedContentChange compares newContent, the content that would be written to filename, against what is
+// already there, without writing anything. Callers that build their file content themselves instead of going
+// through ApplyTemplateAndSaveMerged (e.g. generateEncryptionFile) use this directly to still show up in `plan`
+func RecordPlannedContentChange(filename, newContent string) error {
+	newHash := GetSHA256([]byte(newContent))
+
+	if !FileExists(filename) {
+		RecordPlannedFileChange(filename, "create", "", newHash)
+
+		return nil
+	}
+
+	oldContent, error := ioutil.ReadFile(filename)
+	if error != nil {
+		return error
+	}
+
+	oldHash := GetSHA256(oldContent)
+
+	action := "update"
+
+	if oldHash == newHash {
+		action = "unchanged"
+	}
+
+	RecordPlannedFileChange(filename, action, oldHash, newHash)
+
+	return nil
+}
+
+// VerifyChecksum compares the SHA256 of filename against expectedHash, returning an error naming the file and
+// both hashes on mismatch
+func VerifyChecksum(filename, expectedHash string) error {
+	content, error := ioutil.ReadFile(filename)
+	if error != nil {
+		return error
+	}
+
+	actualHash := GetSHA256(content)
+
+	if actualHash != expectedHash {
+		return fmt.Errorf("Checksum mismatch for '%s' (expected '%s', got '%s')", filename, expectedHash, actualHash)
+	}
+
+	return nil
+}
+
 func GetBase64OfPEM(filename string) (string, error) {
 	content, error := ioutil.ReadFile(filename)
 
@@ -240,11 +413,62 @@ func GenerateCephKey() string {
 	return base64.StdEncoding.EncodeToString(buffer)
 }
 
+func GenerateRandomPassword() string {
+	size := 16
+	buffer := make([]byte, size)
+
+	rand.Read(buffer)
+
+	return base64.RawURLEncoding.EncodeToString(buffer)
+}
+
+// osReleaseFamilies maps the /etc/os-release ID field to the short OS family name used throughout this project,
+// covering distros the vendored goslib does not recognize on its own. CentOS 8 is not listed here since its
+// ID is "centos", same as 7.x, and is therefore already picked up by goslib
+var osReleaseFamilies = map[string]string{
+	"rocky":     OS_ROCKY,
+	"almalinux": OS_ALMA,
+}
+
+// readOSRelease parses /etc/os-release into a key/value map, returning false if the file could not be read
+func readOSRelease() (map[string]string, bool) {
+	content, error := ioutil.ReadFile("/etc/os-release")
+	if error != nil {
+		return nil, false
+	}
+
+	osRelease := map[string]string{}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		pair := strings.SplitN(line, "=", 2)
+
+		if len(pair) != 2 {
+			continue
+		}
+
+		osRelease[pair[0]] = strings.Trim(pair[1], "\"")
+	}
+
+	return osRelease, true
+}
+
 func GetOSName() string {
+	if osRelease, ok := readOSRelease(); ok {
+		if family, ok := osReleaseFamilies[strings.ToLower(osRelease["ID"])]; ok {
+			return family
+		}
+	}
+
 	return strings.ToLower(oslib.GetDist().Display)
 }
 
 func GetOSRelease() string {
+	if osRelease, ok := readOSRelease(); ok {
+		if _, ok := osReleaseFamilies[strings.ToLower(osRelease["ID"])]; ok {
+			return osRelease["VERSION_ID"]
+		}
+	}
+
 	return oslib.GetDist().Release
 }
 
@@ -252,13 +476,29 @@ func GetOSNameAndRelease() string {
 	return fmt.Sprintf("%s/%s", GetOSName(), GetOSRelease())
 }
 
+// GetOSMajorRelease returns just the major version component of GetOSRelease, e.g. "8" for "8.3", so OS
+// entries like "centos/8" match every minor release of that major version
+func GetOSMajorRelease() string {
+	return strings.SplitN(GetOSRelease(), ".", 2)[0]
+}
+
+// GetOSNameAndMajorRelease returns the family and major version, e.g. "centos/8", used to match an OS family
+// regardless of minor version
+func GetOSNameAndMajorRelease() string {
+	return fmt.Sprintf("%s/%s", GetOSName(), GetOSMajorRelease())
+}
+
 func HasOS(os []string) bool {
 	if len(os) == 0 {
 		return true
 	}
 
+	name := GetOSName()
+	nameAndRelease := GetOSNameAndRelease()
+	nameAndMajorRelease := GetOSNameAndMajorRelease()
+
 	for _, entry := range os {
-		if entry == GetOSName() || entry == GetOSNameAndRelease() {
+		if entry == name || entry == nameAndRelease || entry == nameAndMajorRelease {
 			return true
 		}
 	}